@@ -3,23 +3,23 @@ package main
 import (
 	"authentication/config"
 	"authentication/internal/routes"
-	"log"
+	"authentication/internal/utils/logger"
 )
 
 func main() {
 	serverConfig, err := config.NewServerConfig()
 	if err != nil {
-		log.Fatalf("❌ Failed to initialize server: %v", err)
+		logger.Fatal().Err(err).Msg("❌ Failed to initialize server")
 	}
 
 	defer func() {
 		sqlDB, _ := serverConfig.DB.DB()
 		sqlDB.Close()
-		log.Println("✅ Database connection closed")
+		logger.Info().Msg("✅ Database connection closed")
 	}()
 
 	if err := serverConfig.Start(); err != nil {
-		log.Fatalf("❌ Error starting server: %v", err)
+		logger.Fatal().Err(err).Msg("❌ Error starting server")
 	}
 
 	engine := serverConfig.Gin
@@ -29,10 +29,20 @@ func main() {
 	routes.AuthRoutes(engine, serverConfig.Middleware, serverConfig.Controller.AuthController)
 	routes.RoleRoutes(engine, serverConfig.Middleware, serverConfig.Controller.RoleController)
 	routes.UserRoutes(engine, serverConfig.Middleware, serverConfig.Controller.UserController)
+	routes.TwoFactorRoutes(engine, serverConfig.Middleware, serverConfig.Controller.TwoFactorController)
+	routes.WebauthnRoutes(engine, serverConfig.Middleware, serverConfig.Controller.WebauthnController)
+	routes.OauthRoutes(engine, serverConfig.Middleware, serverConfig.Controller.OauthController)
+	routes.WellKnownRoutes(engine, serverConfig.Middleware, serverConfig.Controller.WellKnownController)
+	routes.SessionRoutes(engine, serverConfig.Middleware, serverConfig.Controller.SessionController)
+	routes.InternalTokenRoutes(engine, serverConfig.Middleware, serverConfig.Controller.InternalTokenController)
+	routes.ServiceAccountRoutes(engine, serverConfig.Middleware, serverConfig.Controller.ServiceAccountController)
+	routes.ApiKeyRoutes(engine, serverConfig.Middleware, serverConfig.Controller.ApiKeyController)
+	routes.IntrospectionRoutes(engine, serverConfig.Middleware, serverConfig.Controller.IntrospectionController)
+	routes.ImpersonationRoutes(engine, serverConfig.Middleware, serverConfig.Controller.ImpersonationController)
 	//routes.FamilyRoutes(engine, serverConfig.Middleware, serverConfig.Controller.FamilyController)
 
 	// Run server
-	log.Println("Starting server on :8080")
+	logger.Info().Msg("Starting server on :8080")
 	err = engine.Run(":8080")
 	if err != nil {
 		return
@@ -1,25 +1,30 @@
 package config
 
 import (
+	"authentication/internal/middleware"
+	"authentication/internal/utils"
+	"authentication/internal/utils/logger"
 	"context"
 	"fmt"
 	"github.com/gin-gonic/gin"
-	"log"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/kelseyhightower/envconfig"
 	"github.com/redis/go-redis/v9"
-	"github.com/sirupsen/logrus"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	gormlogger "gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
 )
 
 // Config holds application-wide configurations
 type Config struct {
 	AppPort    string `envconfig:"APP_PORT" default:"8080"`
+	LogLevel   string `envconfig:"LOG_LEVEL" default:"info"`
 	JWTSecret  string `envconfig:"JWT_SECRET" default:"a1b2c3d4e5f6g7h8i9j0k1l2m3n4o5p6q7r8s9t0u1v2w3x4y5z6"`
 	AesEncrypt string `envconfig:"AES_ENCRYPT" default:"E8gWmPq4R7YTXA9LJ0b3VNdT1KoU6C8h"`
 	AesFixedIV string `envconfig:"AES_FIXED_IV" default:"D4f9s8X7a2K6mL1Q"`
@@ -36,21 +41,74 @@ type Config struct {
 	DBSSLMode  string `envconfig:"DB_SSLMODE" default:"disable"`
 	CdnUrl     string `envconfig:"CDN_URL"  default:"http://localhost:8181"`
 	NatsUrl    string `envconfig:"NATS_URL" default:"nats://localhost:4222"`
+	TotpIssuer string `envconfig:"TOTP_ISSUER" default:"Authentication Service"`
+
+	WebauthnRPID      string   `envconfig:"WEBAUTHN_RP_ID" default:"localhost"`
+	WebauthnRPName    string   `envconfig:"WEBAUTHN_RP_NAME" default:"Authentication Service"`
+	WebauthnRPOrigins []string `envconfig:"WEBAUTHN_RP_ORIGINS" default:"http://localhost:8080"`
+
+	JWTAlgorithm           string `envconfig:"JWT_ALGORITHM" default:"HS256"`
+	JWTPrivateKey          string `envconfig:"JWT_PRIVATE_KEY" default:""`
+	JWTPrivateKeyFile      string `envconfig:"JWT_PRIVATE_KEY_FILE" default:""`
+	JWTIssuer              string `envconfig:"JWT_ISSUER" default:"http://localhost:8080"`
+	JWTKeyRotationDays     int    `envconfig:"JWT_KEY_ROTATION_DAYS" default:"30"`
+	JWTKeyPropagationHours int    `envconfig:"JWT_KEY_PROPAGATION_HOURS" default:"24"`
+
+	InternalTokenTTL           time.Duration `envconfig:"INTERNAL_TOKEN_TTL" default:"2160h"`
+	InternalTokenRotationGrace time.Duration `envconfig:"INTERNAL_TOKEN_ROTATION_GRACE" default:"1h"`
+	InternalTokenAudience      string        `envconfig:"INTERNAL_TOKEN_AUDIENCE" default:"auth-service"`
+
+	ServiceAccountTokenTTL            time.Duration `envconfig:"SERVICE_ACCOUNT_TOKEN_TTL" default:"1h"`
+	ServiceAccountMaxTokenTTL         time.Duration `envconfig:"SERVICE_ACCOUNT_MAX_TOKEN_TTL" default:"24h"`
+	ServiceAccountSecretRotationGrace time.Duration `envconfig:"SERVICE_ACCOUNT_SECRET_ROTATION_GRACE" default:"1h"`
+
+	ApiKeyMaxPerUser int `envconfig:"API_KEY_MAX_PER_USER" default:"10"`
+
+	ImpersonationTokenTTL time.Duration `envconfig:"IMPERSONATION_TOKEN_TTL" default:"15m"`
+
+	TokenHashKey   string            `envconfig:"TOKEN_HASH_KEY" default:""`
+	BlindIndexKey  string            `envconfig:"BLIND_INDEX_KEY" default:""`
+	AesEncryptKeys map[string]string `envconfig:"AES_ENCRYPT_KEYS" default:""`
+
+	PinMaxAttempts   int             `envconfig:"PIN_MAX_ATTEMPTS" default:"5"`
+	PinLockDurations []time.Duration `envconfig:"PIN_LOCK_DURATIONS" default:"15m,1h,24h"`
+
+	LoginMaxAttempts      int           `envconfig:"LOGIN_MAX_ATTEMPTS" default:"5"`
+	LoginMaxAttemptsPerIP int           `envconfig:"LOGIN_MAX_ATTEMPTS_PER_IP" default:"20"`
+	LoginAttemptWindow    time.Duration `envconfig:"LOGIN_ATTEMPT_WINDOW" default:"15m"`
+	LoginLockoutDuration  time.Duration `envconfig:"LOGIN_LOCKOUT_DURATION" default:"15m"`
+
+	RateLimitEnabled bool              `envconfig:"RATE_LIMIT_ENABLED" default:"true"`
+	RateLimitRules   map[string]string `envconfig:"RATE_LIMIT_RULES" default:"sensitive:5/15m/ip,public:60/1m/ip,authenticated:300/1m/client_id"`
+
+	PasswordPolicies         map[string]string `envconfig:"PASSWORD_POLICIES" default:"default:8/lower+upper+digit,admin:12/lower+upper+digit+symbol,super admin:12/lower+upper+digit+symbol"`
+	PasswordBreachedListFile string            `envconfig:"PASSWORD_BREACHED_LIST_FILE" default:""`
+	PasswordHistorySize      int               `envconfig:"PASSWORD_HISTORY_SIZE" default:"5"`
+
+	PasswordHashAlgorithm string `envconfig:"PASSWORD_HASH_ALGORITHM" default:"argon2id"`
+	PasswordBcryptCost    int    `envconfig:"PASSWORD_BCRYPT_COST" default:"12"`
+	PasswordArgon2Time    uint32 `envconfig:"PASSWORD_ARGON2_TIME" default:"2"`
+	PasswordArgon2Memory  uint32 `envconfig:"PASSWORD_ARGON2_MEMORY" default:"19456"`
+	PasswordArgon2Threads uint8  `envconfig:"PASSWORD_ARGON2_THREADS" default:"1"`
 }
 
 // LoadConfig loads environment variables into the Config struct
 func LoadConfig() *Config {
 	var cfg Config
 	if err := envconfig.Process("", &cfg); err != nil {
-		log.Fatalf("❌ Failed to load config: %v", err)
+		logger.Fatal().Msgf("❌ Failed to load config: %v", err)
+	}
+
+	if err := logger.Init(cfg.LogLevel); err != nil {
+		logger.Fatal().Err(err).Msg("❌ Invalid LOG_LEVEL")
 	}
 
-	logrus.WithFields(logrus.Fields{
-		"AppPort":   cfg.AppPort,
-		"DBHost":    cfg.DBHost,
-		"DBName":    cfg.DBName,
-		"RedisHost": cfg.RedisHost,
-	}).Info("✅ Configuration loaded successfully")
+	logger.Info().
+		Str("app_port", cfg.AppPort).
+		Str("db_host", cfg.DBHost).
+		Str("db_name", cfg.DBName).
+		Str("redis_host", cfg.RedisHost).
+		Msg("✅ Configuration loaded successfully")
 
 	return &cfg
 }
@@ -60,17 +118,17 @@ func InitGin() *gin.Engine {
 	// Set Gin mode based on environment
 	if ginMode := gin.Mode(); ginMode != gin.ReleaseMode {
 		gin.SetMode(gin.ReleaseMode)
-		logrus.Warn("⚠ Running in DEBUG mode. Use `GIN_MODE=release` in production.")
+		logger.Warn().Msg("⚠ Running in DEBUG mode. Use `GIN_MODE=release` in production.")
 	} else {
-		logrus.Info("✅ Running in RELEASE mode.")
+		logger.Info().Msg("✅ Running in RELEASE mode.")
 	}
 
 	// Create a new Gin router
 	engine := gin.New()
 
 	// Middleware
-	engine.Use(gin.Recovery()) // Handles panics and prevents crashes
-	engine.Use(gin.Logger())   // Logs HTTP requests
+	engine.Use(gin.Recovery())                                    // Handles panics and prevents crashes
+	engine.Use(middleware.NewRequestLoggerMiddleware().Handler()) // Logs HTTP requests with a request ID
 
 	// Security Headers (Prevents Clickjacking & XSS Attacks)
 	engine.Use(func(c *gin.Context) {
@@ -80,7 +138,7 @@ func InitGin() *gin.Engine {
 		c.Next()
 	})
 
-	logrus.Info("🚀 Gin HTTP server initialized successfully")
+	logger.Info().Msg("🚀 Gin HTTP server initialized successfully")
 	return engine
 }
 
@@ -97,26 +155,23 @@ func InitDatabase(cfg *Config) *gorm.DB {
 		maxRetries := 5
 		for i := 1; i <= maxRetries; i++ {
 			db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-				Logger:         logger.Default.LogMode(logger.Silent),
+				Logger:         gormlogger.Default.LogMode(gormlogger.Silent),
 				NamingStrategy: schemaNamingStrategy(cfg.DBSchema),
 			})
 			if err == nil {
 				break
 			}
 
-			logrus.WithFields(logrus.Fields{
-				"attempt": i,
-				"error":   err.Error(),
-			}).Warn("⏳ Retrying database connection...")
+			logger.Warn().Int("attempt", i).Err(err).Msg("⏳ Retrying database connection...")
 
 			time.Sleep(2 * time.Second)
 		}
 
 		if err != nil {
-			logrus.WithError(err).Fatal("❌ Failed to connect to PostgreSQL after retries")
+			logger.Fatal().Err(err).Msg("❌ Failed to connect to PostgreSQL after retries")
 		}
 
-		logrus.Info("✅ Connected to PostgreSQL")
+		logger.Info().Msg("✅ Connected to PostgreSQL")
 	})
 	return db
 }
@@ -125,14 +180,14 @@ func InitDatabase(cfg *Config) *gorm.DB {
 func CloseDatabase(db *gorm.DB) {
 	sqlDB, err := db.DB()
 	if err != nil {
-		logrus.WithError(err).Error("Failed to retrieve database instance")
+		logger.Error().Err(err).Msg("Failed to retrieve database instance")
 		return
 	}
 
 	if err := sqlDB.Close(); err != nil {
-		logrus.WithError(err).Error("Error closing database connection")
+		logger.Error().Err(err).Msg("Error closing database connection")
 	} else {
-		logrus.Info("✅ Database connection closed")
+		logger.Info().Msg("✅ Database connection closed")
 	}
 }
 
@@ -152,28 +207,167 @@ func InitRedis(cfg *Config) *redis.Client {
 
 		_, err := rdb.Ping(ctx).Result()
 		if err == nil {
-			logrus.Info("✅ Connected to Redis")
+			logger.Info().Msg("✅ Connected to Redis")
 			return rdb
 		}
 
-		logrus.WithFields(logrus.Fields{
-			"attempt": i,
-			"error":   err.Error(),
-		}).Warn("⏳ Retrying Redis connection...")
+		logger.Warn().Int("attempt", i).Err(err).Msg("⏳ Retrying Redis connection...")
 
 		time.Sleep(2 * time.Second)
 	}
 
-	logrus.Fatal("❌ Failed to connect to Redis after retries")
+	logger.Fatal().Msg("❌ Failed to connect to Redis after retries")
 	return nil
 }
 
+// InitSigningKey loads the operator-supplied JWT private key, if any, to seed the signing keyring
+func InitSigningKey(cfg *Config) *utils.SigningKey {
+	if cfg.JWTAlgorithm == utils.AlgorithmHS256 {
+		logger.Warn().Msg("⚠ Signing JWTs with HS256 (legacy mode). Set JWT_ALGORITHM to RS256 or EdDSA to publish public keys.")
+		return nil
+	}
+
+	key, err := utils.LoadSigningKey(cfg.JWTAlgorithm, cfg.JWTPrivateKey, cfg.JWTPrivateKeyFile)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("❌ Failed to load JWT signing key")
+	}
+
+	if key != nil {
+		logger.Info().Str("alg", key.Algorithm).Str("kid", key.KeyID).Msg("✅ JWT private key loaded")
+	}
+	return key
+}
+
+// InitSigningKeyRetireGrace returns how long a superseded signing key keeps verifying. It must outlive
+// every token the key may have signed, and internal tokens live far longer than user access tokens.
+func InitSigningKeyRetireGrace(cfg *Config) time.Duration {
+	grace := utils.SigningKeyRetireGrace * time.Hour
+	for _, ttl := range []time.Duration{cfg.InternalTokenTTL, cfg.ServiceAccountMaxTokenTTL, cfg.ImpersonationTokenTTL} {
+		if ttl+time.Hour > grace {
+			grace = ttl + time.Hour
+		}
+	}
+	return grace
+}
+
+// InitTokenHashKey returns the HMAC key used to hash stored session and refresh tokens. It must be a
+// key of its own, a leaked JWT_SECRET must not also give away the stored token hashes.
+func InitTokenHashKey(cfg *Config) string {
+	if cfg.TokenHashKey == "" {
+		logger.Fatal().Msg("❌ TOKEN_HASH_KEY is not set")
+	}
+	if cfg.TokenHashKey == cfg.JWTSecret {
+		logger.Fatal().Msg("❌ TOKEN_HASH_KEY must differ from JWT_SECRET")
+	}
+	return cfg.TokenHashKey
+}
+
+// InitRateLimitRules parses the configured rate limit rules, each written as <limit>/<window>[/<key by>]
+func InitRateLimitRules(cfg *Config) map[string]utils.RateLimitRule {
+	rules := make(map[string]utils.RateLimitRule, len(cfg.RateLimitRules))
+	for name, value := range cfg.RateLimitRules {
+		rule, err := utils.ParseRateLimitRule(value)
+		if err != nil {
+			logger.Fatal().Msgf("❌ Invalid rate limit rule %q: %v", name, err)
+		}
+		rules[name] = rule
+	}
+	return rules
+}
+
+// InitPasswordValidator builds the per-role password policies, each written as <min length>[/<class>+<class>...],
+// together with the breached password list when one is configured
+func InitPasswordValidator(cfg *Config) utils.PasswordValidator {
+	var breached *utils.BloomFilter
+	if cfg.PasswordBreachedListFile != "" {
+		filter, err := utils.LoadBloomFilter(cfg.PasswordBreachedListFile, 0.001)
+		if err != nil {
+			logger.Fatal().Msgf("❌ Failed to load breached password list: %v", err)
+		}
+		breached = filter
+	} else {
+		logger.Warn().Msg("⚠ PASSWORD_BREACHED_LIST_FILE is not set, passwords are not checked against breached passwords.")
+	}
+
+	policies := make(map[string]utils.PasswordPolicy, len(cfg.PasswordPolicies))
+	for role, value := range cfg.PasswordPolicies {
+		policy, err := utils.ParsePasswordPolicy(value, breached)
+		if err != nil {
+			logger.Fatal().Msgf("❌ Invalid password policy for %q: %v", role, err)
+		}
+		policies[strings.ToLower(strings.TrimSpace(role))] = policy
+	}
+	if _, ok := policies[utils.DefaultPasswordPolicy]; !ok {
+		logger.Fatal().Msgf("❌ PASSWORD_POLICIES must define a %q policy", utils.DefaultPasswordPolicy)
+	}
+	return utils.NewPasswordValidator(policies)
+}
+
+// InitPasswordHashing sets the algorithm and cost new password and PIN hashes are created with
+func InitPasswordHashing(cfg *Config) {
+	if err := utils.SetPasswordHashParams(utils.PasswordHashParams{
+		Algorithm:     cfg.PasswordHashAlgorithm,
+		BcryptCost:    cfg.PasswordBcryptCost,
+		Argon2Time:    cfg.PasswordArgon2Time,
+		Argon2Memory:  cfg.PasswordArgon2Memory,
+		Argon2Threads: cfg.PasswordArgon2Threads,
+	}); err != nil {
+		logger.Fatal().Msgf("❌ Invalid password hashing configuration: %v", err)
+	}
+}
+
+// InitEncryptionKeys returns the AES keyring by version, configured as <version>:<key>,... AES_ENCRYPT
+// is key version 1 unless AES_ENCRYPT_KEYS sets it, so rotating means adding a higher version.
+func InitEncryptionKeys(cfg *Config) map[int]string {
+	keys := map[int]string{1: cfg.AesEncrypt}
+	for name, key := range cfg.AesEncryptKeys {
+		version, err := strconv.Atoi(strings.TrimPrefix(name, "v"))
+		if err != nil || version <= 0 {
+			logger.Fatal().Msgf("❌ Invalid encryption key version %q: must be a positive number", name)
+		}
+		if key == "" {
+			logger.Fatal().Msgf("❌ Encryption key version %d is empty", version)
+		}
+		keys[version] = key
+	}
+	return keys
+}
+
+// InitBlindIndexKey returns the key for the lookup hashes of encrypted columns. It must not be one of
+// the encryption keys, or the blind indexes would be keyed with the key that hides the values.
+func InitBlindIndexKey(cfg *Config, encryptionKeys map[int]string) string {
+	if cfg.BlindIndexKey == "" {
+		logger.Fatal().Msg("❌ BLIND_INDEX_KEY is not set")
+	}
+	for _, key := range encryptionKeys {
+		if cfg.BlindIndexKey == key {
+			logger.Fatal().Msg("❌ BLIND_INDEX_KEY must differ from the encryption keys")
+		}
+	}
+	return cfg.BlindIndexKey
+}
+
+// InitWebAuthn initializes the WebAuthn relying party used for passkey registration and login
+func InitWebAuthn(cfg *Config) *webauthn.WebAuthn {
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.WebauthnRPID,
+		RPDisplayName: cfg.WebauthnRPName,
+		RPOrigins:     cfg.WebauthnRPOrigins,
+	})
+	if err != nil {
+		logger.Fatal().Err(err).Msg("❌ Failed to initialize WebAuthn relying party")
+	}
+
+	logger.Info().Msg("✅ WebAuthn relying party initialized")
+	return webAuthn
+}
+
 // CloseRedis closes the Redis connection properly
 func CloseRedis(rdb *redis.Client) {
 	if err := rdb.Close(); err != nil {
-		logrus.WithError(err).Error("Error closing Redis connection")
+		logger.Error().Err(err).Msg("Error closing Redis connection")
 	} else {
-		logrus.Info("✅ Redis connection closed")
+		logger.Info().Msg("✅ Redis connection closed")
 	}
 }
 
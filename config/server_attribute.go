@@ -9,15 +9,18 @@ import (
 	controllercron "authentication/internal/utils/cron/controller"
 	repositorycron "authentication/internal/utils/cron/repository"
 	"authentication/internal/utils/cron/service"
+	"authentication/internal/utils/logger"
 	nt "authentication/internal/utils/nats"
-	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 )
 
 func NewServerConfig() (*ServerConfig, error) {
 	cfg := LoadConfig()
+	InitPasswordHashing(cfg)
 	redisClient := InitRedis(cfg)
 	redisService := utils.NewRedisService(*redisClient)
 	db := InitDatabase(cfg)
@@ -29,7 +32,7 @@ func NewServerConfig() (*ServerConfig, error) {
 
 	go func() {
 		<-quit
-		log.Println("🛑 Shutting down gracefully...")
+		logger.Info().Msg("🛑 Shutting down gracefully...")
 
 		// Close database and Redis before exiting
 		CloseDatabase(db)
@@ -39,16 +42,20 @@ func NewServerConfig() (*ServerConfig, error) {
 	}()
 
 	server := &ServerConfig{
-		Gin:        engine,
-		Config:     cfg,
-		DB:         db,
-		Redis:      redisService,
-		JWTService: utils.NewJWTService(cfg.JWTSecret),
+		Gin:               engine,
+		Config:            cfg,
+		DB:                db,
+		Redis:             redisService,
+		TokenRevocation:   utils.NewTokenRevocation(redisService),
+		SessionActivity:   utils.NewSessionActivity(redisService),
+		PasswordValidator: InitPasswordValidator(cfg),
+		WebAuthn:          InitWebAuthn(cfg),
 	}
 
 	server.initNats()
 	server.initAesEncrypt()
 	server.initRepository()
+	server.initJWT()
 	server.initTransactional()
 	server.initServices()
 	server.initController()
@@ -60,18 +67,49 @@ func NewServerConfig() (*ServerConfig, error) {
 // initRepository initializes database access objects (Repository)
 func (s *ServerConfig) initRepository() {
 	s.Repository = Repository{
-		AuthRepository:         repository.NewAuthRepository(*s.DB),
-		UserRepository:         repository.NewUserRepository(*s.DB),
-		UserKeyRepository:      repository.NewUserKeyRepository(*s.DB),
-		UserSettingRepository:  repository.NewUserSettingRepository(*s.DB),
-		ResourceRepository:     repository.NewResourceRepository(*s.DB),
-		RoleRepository:         repository.NewRoleRepository(*s.DB),
-		UserRoleRepository:     repository.NewUserRoleRepository(*s.DB),
-		UserSessionRepository:  repository.NewUserSessionRepository(*s.DB),
-		UserResourceRepository: repository.NewUserResourceRepository(*s.DB),
+		AuthRepository:               repository.NewAuthRepository(*s.DB),
+		UserRepository:               repository.NewUserRepository(*s.DB),
+		UserKeyRepository:            repository.NewUserKeyRepository(*s.DB),
+		UserSettingRepository:        repository.NewUserSettingRepository(*s.DB),
+		ResourceRepository:           repository.NewResourceRepository(*s.DB),
+		RoleRepository:               repository.NewRoleRepository(*s.DB),
+		UserRoleRepository:           repository.NewUserRoleRepository(*s.DB),
+		UserSessionRepository:        repository.NewUserSessionRepository(*s.DB),
+		UserResourceRepository:       repository.NewUserResourceRepository(*s.DB),
+		UserTwoFactorRepository:      repository.NewUserTwoFactorRepository(*s.DB),
+		WebauthnCredentialRepository: repository.NewWebauthnCredentialRepository(*s.DB),
+		OauthClientRepository:        repository.NewOauthClientRepository(*s.DB),
+		SigningKeyRepository:         repository.NewSigningKeyRepository(*s.DB),
+		RefreshTokenRepository:       repository.NewRefreshTokenRepository(*s.DB),
+		PasswordHistoryRepository:    repository.NewPasswordHistoryRepository(*s.DB),
+		InternalTokenRepository:      repository.NewInternalTokenRepository(*s.DB),
+		ServiceAccountRepository:     repository.NewServiceAccountRepository(*s.DB),
+		ApiKeyRepository:             repository.NewApiKeyRepository(*s.DB),
+		ImpersonationAuditRepository: repository.NewImpersonationAuditRepository(*s.DB),
 	}
 }
 
+// initJWT initializes the signing keyring and the JWT service that depends on it
+func (s *ServerConfig) initJWT() {
+	s.Services.SigningKeyService = services.NewSigningKeyService(s.Repository.SigningKeyRepository,
+		s.Encryption.EncryptionService,
+		s.Config.JWTAlgorithm,
+		time.Duration(s.Config.JWTKeyRotationDays)*24*time.Hour,
+		time.Duration(s.Config.JWTKeyPropagationHours)*time.Hour,
+		InitSigningKeyRetireGrace(s.Config))
+
+	if s.Config.JWTAlgorithm == utils.AlgorithmHS256 {
+		InitSigningKey(s.Config)
+		s.JWTService = utils.NewJWTService(s.Config.JWTSecret, nil, s.Config.JWTIssuer)
+		return
+	}
+
+	if err := s.Services.SigningKeyService.EnsureSigningKey(InitSigningKey(s.Config)); err != nil {
+		logger.Fatal().Err(err).Msg("❌ Failed to initialize JWT signing keyring")
+	}
+	s.JWTService = utils.NewJWTService(s.Config.JWTSecret, s.Services.SigningKeyService, s.Config.JWTIssuer)
+}
+
 // initTransactional initializes transactional repository
 func (s *ServerConfig) initTransactional() {
 	s.Transactional = Transactional{
@@ -81,6 +119,10 @@ func (s *ServerConfig) initTransactional() {
 
 // initServices initializes the application services
 func (s *ServerConfig) initServices() {
+	twoFactorService := services.NewTwoFactorService(s.Repository.UserRepository, s.Repository.UserTwoFactorRepository, s.Encryption.EncryptionService, s.Redis, s.Config.TotpIssuer)
+	refreshTokenService := services.NewRefreshTokenService(s.Repository.RefreshTokenRepository, s.Repository.UserSessionRepository, s.Repository.UserRepository, s.Redis, s.Nats.NatsService, s.Encryption.TokenHasher, s.TokenRevocation)
+	apiKeyService := services.NewApiKeyService(s.Repository.ApiKeyRepository, s.Repository.UserRepository, s.Repository.ResourceRepository, s.Encryption.TokenHasher, s.Config.ApiKeyMaxPerUser)
+	loginAttemptService := services.NewLoginAttemptService(s.Redis, s.Nats.NatsService, s.Config.LoginMaxAttempts, s.Config.LoginMaxAttemptsPerIP, s.Config.LoginAttemptWindow, s.Config.LoginLockoutDuration)
 	s.Services = Services{
 		AuthService: services.NewAuthService(s.Repository.AuthRepository,
 			s.Repository.ResourceRepository,
@@ -95,49 +137,91 @@ func (s *ServerConfig) initServices() {
 			s.Redis,
 			s.JWTService,
 			s.Encryption.EncryptionService,
-			s.Nats.NatsService),
-		UserService:        services.NewUserService(s.Repository.UserRepository, s.Repository.UserKeyRepository, s.Repository.UserSettingRepository, s.Redis, s.JWTService, s.Encryption.EncryptionService),
-		RoleService:        services.NewRoleService(s.Repository.RoleRepository, s.Repository.UserRepository),
-		UserSessionService: services.NewUsersSessionService(s.Repository.UserSessionRepository, s.Repository.UserRepository, s.JWTService, s.Redis),
+			s.Nats.NatsService,
+			twoFactorService,
+			refreshTokenService,
+			s.Encryption.TokenHasher,
+			s.TokenRevocation,
+			utils.NewPinLockPolicy(s.Config.PinMaxAttempts, s.Config.PinLockDurations),
+			loginAttemptService,
+			s.PasswordValidator,
+			s.Repository.PasswordHistoryRepository,
+			s.Config.PasswordHistorySize,
+			apiKeyService),
+		UserService:         services.NewUserService(s.Repository.UserRepository, s.Repository.UserKeyRepository, s.Repository.UserSettingRepository, s.Redis, s.JWTService, s.Encryption.EncryptionService),
+		RoleService:         services.NewRoleService(s.Repository.RoleRepository, s.Repository.UserRepository),
+		UserSessionService:  services.NewUsersSessionService(s.Repository.UserSessionRepository, s.Repository.UserRepository, s.JWTService, s.Redis, refreshTokenService, s.Encryption.TokenHasher, s.TokenRevocation, s.SessionActivity, apiKeyService),
+		TwoFactorService:    twoFactorService,
+		SigningKeyService:   s.Services.SigningKeyService,
+		RefreshTokenService: refreshTokenService,
+		LoginAttemptService: loginAttemptService,
 	}
-	s.Services.ResourceService = services.NewResourceService(s.Repository.ResourceRepository, s.Repository.UserResourceRepository, s.Repository.RoleRepository, s.Repository.UserRepository, s.Nats.NatsService, s.Services.AuthService)
+	s.Services.ResourceService = services.NewResourceService(s.Repository.ResourceRepository, s.Repository.UserResourceRepository, s.Repository.RoleRepository, s.Repository.UserRepository, s.Nats.NatsService, s.Services.AuthService, s.Encryption.EncryptionService)
+	s.Services.WebauthnService = services.NewWebauthnService(s.Repository.UserRepository, s.Repository.WebauthnCredentialRepository, s.Redis, s.Services.AuthService, s.WebAuthn)
+	s.Services.ApiKeyService = apiKeyService
+	s.Services.ImpersonationService = services.NewImpersonationService(s.Repository.ImpersonationAuditRepository, s.Repository.UserRepository, s.Repository.RoleRepository, s.Repository.ResourceRepository, s.JWTService, s.Config.ImpersonationTokenTTL)
+	s.Services.IntrospectionService = services.NewIntrospectionService(s.JWTService, s.TokenRevocation, s.Repository.UserSessionRepository, s.Repository.RoleRepository, s.Services.ApiKeyService)
+	s.Services.ServiceAccountService = services.NewServiceAccountService(s.Repository.ServiceAccountRepository, s.Repository.ResourceRepository, s.JWTService, s.Encryption.EncryptionService, s.Config.ServiceAccountTokenTTL, s.Config.ServiceAccountMaxTokenTTL, s.Config.ServiceAccountSecretRotationGrace, s.Config.InternalTokenAudience)
+	s.Services.OauthService = services.NewOauthService(s.Repository.OauthClientRepository, s.Repository.UserRepository, s.Repository.ResourceRepository, s.Repository.RoleRepository, s.Repository.UserSessionRepository, s.Services.UserSessionService, s.Services.RefreshTokenService, s.Services.ServiceAccountService, s.Services.LoginAttemptService, s.Services.TwoFactorService, s.Redis, s.JWTService, s.Encryption.TokenHasher, s.TokenRevocation, s.Encryption.EncryptionService)
+	s.Services.InternalTokenService = services.NewInternalTokenService(s.Repository.InternalTokenRepository, s.Repository.ResourceRepository, s.JWTService, s.Encryption.TokenHasher, s.Config.InternalTokenTTL, s.Config.InternalTokenRotationGrace, s.Config.InternalTokenAudience)
+
+	// rewrite session tokens stored before hashing was introduced
+	s.Services.UserSessionService.HashLegacyTokens()
+	// lookups by email and phone number go through the blind indexes, fill them in before serving
+	s.Services.AuthService.BackfillBlindIndexes()
+	// move personal data to AES-GCM under the newest encryption key
+	go s.Services.AuthService.ReencryptPII()
 
 }
 
 // Start initializes everything and returns an error if something fails
 func (s *ServerConfig) Start() error {
-	log.Println("✅ Server configuration initialized successfully!")
+	logger.Info().Msg("✅ Server configuration initialized successfully!")
 	return nil
 }
 
 func (s *ServerConfig) initController() {
 	s.Controller = Controller{
-		AuthController:     controller.NewAuthController(s.Services.AuthService, s.Services.UserSessionService, s.JWTService),
-		UserController:     controller.NewUserController(s.Services.UserService, s.JWTService, s.Config.CdnUrl),
-		ResourceController: controller.NewResourceController(s.Services.ResourceService, s.JWTService),
-		RoleController:     controller.NewRoleController(s.Services.RoleService, s.JWTService),
+		AuthController:           controller.NewAuthController(s.Services.AuthService, s.Services.UserSessionService, s.JWTService),
+		UserController:           controller.NewUserController(s.Services.UserService, s.JWTService, s.Config.CdnUrl),
+		ResourceController:       controller.NewResourceController(s.Services.ResourceService, s.JWTService),
+		RoleController:           controller.NewRoleController(s.Services.RoleService, s.JWTService),
+		TwoFactorController:      controller.NewTwoFactorController(s.Services.TwoFactorService),
+		WebauthnController:       controller.NewWebauthnController(s.Services.WebauthnService, s.Services.UserSessionService),
+		OauthController:          controller.NewOauthController(s.Services.OauthService, strings.HasPrefix(s.Config.JWTIssuer, "https://")),
+		WellKnownController:      controller.NewWellKnownController(s.JWTService, s.Config.JWTIssuer),
+		SessionController:        controller.NewSessionController(s.Services.UserSessionService),
+		InternalTokenController:  controller.NewInternalTokenController(s.Services.InternalTokenService),
+		ServiceAccountController: controller.NewServiceAccountController(s.Services.ServiceAccountService),
+		ApiKeyController:         controller.NewApiKeyController(s.Services.ApiKeyService),
+		IntrospectionController:  controller.NewIntrospectionController(s.Services.IntrospectionService),
+		ImpersonationController:  controller.NewImpersonationController(s.Services.ImpersonationService),
 	}
 }
 
 func (s *ServerConfig) initMiddleware() {
 	s.Middleware = Middleware{
-		AuthMiddleware:  middleware.NewAuthMiddleware(s.JWTService),
-		AdminMiddleware: middleware.NewAdminMiddleware(s.JWTService),
+		AuthMiddleware:      middleware.NewAuthMiddleware(s.JWTService, s.TokenRevocation, s.SessionActivity, s.Services.ApiKeyService, s.Services.ImpersonationService),
+		AdminMiddleware:     middleware.NewAdminMiddleware(s.JWTService, s.TokenRevocation, s.SessionActivity),
+		RateLimitMiddleware: middleware.NewRateLimitMiddleware(s.Redis, InitRateLimitRules(s.Config), s.Config.RateLimitEnabled),
+		InternalMiddleware:  middleware.NewInternalMiddleware(s.Services.InternalTokenService, s.Services.ServiceAccountService),
 	}
 }
 
 func (s *ServerConfig) initCron() {
 	s.Cron = Cron{
 		CronRepository: repositorycron.NewCronRepository(*s.DB),
-		CronService:    service.NewCronService(*s.DB, repositorycron.NewCronRepository(*s.DB), s.Services.UserSessionService, s.Services.AuthService),
-		CronController: controllercron.NewCronJobController(service.NewCronService(*s.DB, repositorycron.NewCronRepository(*s.DB), s.Services.UserSessionService, s.Services.AuthService)),
+		CronService:    service.NewCronService(*s.DB, repositorycron.NewCronRepository(*s.DB), s.Services.UserSessionService, s.Services.AuthService, s.Services.SigningKeyService, s.JWTService),
+		CronController: controllercron.NewCronJobController(service.NewCronService(*s.DB, repositorycron.NewCronRepository(*s.DB), s.Services.UserSessionService, s.Services.AuthService, s.Services.SigningKeyService, s.JWTService)),
 	}
 	s.Cron.CronService.Start()
 }
 
 func (s *ServerConfig) initAesEncrypt() {
+	encryptionKeys := InitEncryptionKeys(s.Config)
 	s.Encryption = Encryption{
-		EncryptionService: utils.NewEncryption(s.Config.AesEncrypt, s.Config.AesFixedIV),
+		EncryptionService: utils.NewEncryption(encryptionKeys, s.Config.AesFixedIV, InitBlindIndexKey(s.Config, encryptionKeys)),
+		TokenHasher:       utils.NewTokenHasher(InitTokenHashKey(s.Config)),
 	}
 }
 
@@ -11,58 +11,96 @@ import (
 	servicescron "authentication/internal/utils/cron/service"
 	nt "authentication/internal/utils/nats"
 	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"gorm.io/gorm"
 )
 
 // ServerConfig holds all initialized components
 type ServerConfig struct {
-	Gin           *gin.Engine
-	Config        *Config
-	DB            *gorm.DB
-	Redis         utils.RedisService
-	JWTService    utils.JWTService
-	Controller    Controller
-	Services      Services
-	Repository    Repository
-	Transactional Transactional
-	Middleware    Middleware
-	Cron          Cron
-	Encryption    Encryption
-	Nats          Nats
+	Gin               *gin.Engine
+	Config            *Config
+	DB                *gorm.DB
+	Redis             utils.RedisService
+	JWTService        utils.JWTService
+	TokenRevocation   utils.TokenRevocation
+	SessionActivity   utils.SessionActivity
+	PasswordValidator utils.PasswordValidator
+	WebAuthn          *webauthn.WebAuthn
+	Controller        Controller
+	Services          Services
+	Repository        Repository
+	Transactional     Transactional
+	Middleware        Middleware
+	Cron              Cron
+	Encryption        Encryption
+	Nats              Nats
 }
 
 // Services holds all service dependencies
 type Services struct {
-	AuthService        services.AuthService
-	UserService        services.UserService
-	UserSessionService services.UsersSessionService
-	ResourceService    services.ResourceService
-	RoleService        services.RoleService
+	AuthService           services.AuthService
+	UserService           services.UserService
+	UserSessionService    services.UsersSessionService
+	ResourceService       services.ResourceService
+	RoleService           services.RoleService
+	TwoFactorService      services.TwoFactorService
+	WebauthnService       services.WebauthnService
+	OauthService          services.OauthService
+	SigningKeyService     services.SigningKeyService
+	RefreshTokenService   services.RefreshTokenService
+	LoginAttemptService   services.LoginAttemptService
+	InternalTokenService  services.InternalTokenService
+	ServiceAccountService services.ServiceAccountService
+	ApiKeyService         services.ApiKeyService
+	IntrospectionService  services.IntrospectionService
+	ImpersonationService  services.ImpersonationService
 }
 
 // Repository contains repository (database access objects)
 type Repository struct {
-	AuthRepository         repository.AuthRepository
-	UserRepository         repository.UserRepository
-	UserKeyRepository      repository.UserKeyRepository
-	UserSettingRepository  repository.UserSettingRepository
-	ResourceRepository     repository.ResourceRepository
-	UserResourceRepository repository.UserResourceRepository
-	RoleRepository         repository.RoleRepository
-	UserRoleRepository     repository.UserRoleRepository
-	UserSessionRepository  repository.UserSessionRepository
+	AuthRepository               repository.AuthRepository
+	UserRepository               repository.UserRepository
+	UserKeyRepository            repository.UserKeyRepository
+	UserSettingRepository        repository.UserSettingRepository
+	ResourceRepository           repository.ResourceRepository
+	UserResourceRepository       repository.UserResourceRepository
+	RoleRepository               repository.RoleRepository
+	UserRoleRepository           repository.UserRoleRepository
+	UserSessionRepository        repository.UserSessionRepository
+	UserTwoFactorRepository      repository.UserTwoFactorRepository
+	WebauthnCredentialRepository repository.WebauthnCredentialRepository
+	OauthClientRepository        repository.OauthClientRepository
+	SigningKeyRepository         repository.SigningKeyRepository
+	RefreshTokenRepository       repository.RefreshTokenRepository
+	PasswordHistoryRepository    repository.PasswordHistoryRepository
+	InternalTokenRepository      repository.InternalTokenRepository
+	ServiceAccountRepository     repository.ServiceAccountRepository
+	ApiKeyRepository             repository.ApiKeyRepository
+	ImpersonationAuditRepository repository.ImpersonationAuditRepository
 }
 
 type Controller struct {
-	AuthController     controller.AuthController
-	UserController     controller.UserController
-	ResourceController controller.ResourceController
-	RoleController     controller.RoleController
+	AuthController           controller.AuthController
+	UserController           controller.UserController
+	ResourceController       controller.ResourceController
+	RoleController           controller.RoleController
+	TwoFactorController      controller.TwoFactorController
+	WebauthnController       controller.WebauthnController
+	OauthController          controller.OauthController
+	WellKnownController      controller.WellKnownController
+	SessionController        controller.SessionController
+	InternalTokenController  controller.InternalTokenController
+	ServiceAccountController controller.ServiceAccountController
+	ApiKeyController         controller.ApiKeyController
+	IntrospectionController  controller.IntrospectionController
+	ImpersonationController  controller.ImpersonationController
 }
 
 type Middleware struct {
-	AuthMiddleware  middleware.AuthMiddleware
-	AdminMiddleware middleware.AdminMiddleware
+	AuthMiddleware      middleware.AuthMiddleware
+	AdminMiddleware     middleware.AdminMiddleware
+	RateLimitMiddleware middleware.RateLimitMiddleware
+	InternalMiddleware  middleware.InternalMiddleware
 }
 
 type Transactional struct {
@@ -77,6 +115,7 @@ type Cron struct {
 
 type Encryption struct {
 	EncryptionService utils.Encryption
+	TokenHasher       utils.TokenHasher
 }
 
 type Nats struct {
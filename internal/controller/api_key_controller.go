@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/services"
+	"authentication/internal/utils"
+	"authentication/package/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ApiKeyController interface {
+	CreateApiKey(ctx *gin.Context)
+	GetApiKeys(ctx *gin.Context)
+	RevokeApiKey(ctx *gin.Context)
+	GetUserApiKeys(ctx *gin.Context)
+	RevokeUserApiKey(ctx *gin.Context)
+}
+
+type apiKeyController struct {
+	ApiKeyService services.ApiKeyService
+}
+
+func NewApiKeyController(apiKeyService services.ApiKeyService) ApiKeyController {
+	return apiKeyController{ApiKeyService: apiKeyService}
+}
+
+func (h apiKeyController) CreateApiKey(ctx *gin.Context) {
+	var req in.ApiKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Invalid request", nil, err.Error())
+		return
+	}
+
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	// a leaked key must not be able to mint longer-lived keys for itself
+	if token.ApiKeyID != 0 {
+		response.SendResponse(ctx, http.StatusForbidden, "API keys cannot be created with an API key", nil, nil)
+		return
+	}
+
+	apiKey, err := h.ApiKeyService.CreateApiKey(&req, token.ClientID)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusCreated, "API key created successfully, store the key now as it will not be shown again", apiKey, nil)
+}
+
+func (h apiKeyController) GetApiKeys(ctx *gin.Context) {
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	apiKeys, err := h.ApiKeyService.GetApiKeys(token.ClientID)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "Success", apiKeys, nil)
+}
+
+func (h apiKeyController) RevokeApiKey(ctx *gin.Context) {
+	id, err := utils.ConvertToUint(ctx.Param("id"))
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "API key ID must be a number", nil, err)
+		return
+	}
+
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := h.ApiKeyService.RevokeApiKey(id, token.ClientID); err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "API key revoked successfully", nil, nil)
+}
+
+func (h apiKeyController) GetUserApiKeys(ctx *gin.Context) {
+	userID, err := utils.ConvertToUint(ctx.Param("id"))
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "User ID must be a number", nil, err)
+		return
+	}
+
+	apiKeys, err := h.ApiKeyService.GetUserApiKeys(userID)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusInternalServerError, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "Success", apiKeys, nil)
+}
+
+func (h apiKeyController) RevokeUserApiKey(ctx *gin.Context) {
+	id, err := utils.ConvertToUint(ctx.Param("id"))
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "API key ID must be a number", nil, err)
+		return
+	}
+
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := h.ApiKeyService.RevokeUserApiKey(id, token.ClientID); err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "API key revoked successfully", nil, nil)
+}
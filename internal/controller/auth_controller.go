@@ -6,7 +6,9 @@ import (
 	"authentication/internal/services"
 	"authentication/internal/utils"
 	"authentication/package/response"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,6 +18,7 @@ type AuthController interface {
 	RegisterDeviceToken(c *gin.Context)
 	Login(c *gin.Context)
 	LoginPhoneNumber(c *gin.Context)
+	VerifyTwoFactorLogin(c *gin.Context)
 	ResetPassword(c *gin.Context)
 	ChangeDeviceID(c *gin.Context)
 	VerifyDeviceID(c *gin.Context)
@@ -23,10 +26,10 @@ type AuthController interface {
 	ChangePinCode(c *gin.Context)
 	ForgetPinCode(c *gin.Context)
 	RefreshToken(c *gin.Context)
-	RegisterInternalToken(c *gin.Context)
 	UpdateRole(ctx *gin.Context)
 	GetListUser(ctx *gin.Context)
 	GetUserByID(ctx *gin.Context)
+	UnlockPinCode(ctx *gin.Context)
 	ForgotPassword(ctx *gin.Context)
 	ChangePassword(ctx *gin.Context)
 	GenerateCredentialKey(ctx *gin.Context)
@@ -49,6 +52,23 @@ func handleErrorResponse(c *gin.Context, status int, message string, err interfa
 	return
 }
 
+// pinErrorStatus answers a locked PIN with 423 so clients can tell it apart from a wrong one
+func pinErrorStatus(err error) int {
+	if errors.Is(err, utils.ErrPinLocked) {
+		return http.StatusLocked
+	}
+	return http.StatusBadRequest
+}
+
+// passwordPolicyErrors returns the rules a rejected password broke, nil for any other error
+func passwordPolicyErrors(err error) interface{} {
+	var policyErr *utils.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		return policyErr.Errors
+	}
+	return nil
+}
+
 // Helper for centralized success response
 func handleSuccessResponse(c *gin.Context, status int, message string, data interface{}) {
 	response.SendResponse(c, status, message, data, nil)
@@ -70,12 +90,12 @@ func (h authController) Register(c *gin.Context) {
 
 	user, err := h.AuthService.Register(&req, deviceID)
 	if err != nil {
-		handleErrorResponse(c, http.StatusBadRequest, err.Error(), nil)
+		handleErrorResponse(c, http.StatusBadRequest, err.Error(), passwordPolicyErrors(err))
 		return
 	}
 
 	errSession := h.UserSession.AddUserSession(user.UserID, user.Token,
-		user.RefreshToken, c.ClientIP(), "WEB")
+		user.RefreshToken, c.ClientIP(), c.Request.UserAgent())
 
 	if errSession != nil {
 		handleErrorResponse(c, http.StatusInternalServerError, "Failed to create user session", err)
@@ -123,14 +143,25 @@ func (h authController) Login(c *gin.Context) {
 		return
 	}
 
-	user, err := h.AuthService.Login(&req, deviceID)
+	user, err := h.AuthService.Login(&req, deviceID, c.ClientIP())
 	if err != nil {
+		var throttleErr *utils.ThrottleError
+		if errors.As(err, &throttleErr) {
+			c.Header("Retry-After", strconv.Itoa(throttleErr.RetryAfterSeconds()))
+			handleErrorResponse(c, http.StatusTooManyRequests, err.Error(), nil)
+			return
+		}
 		handleErrorResponse(c, http.StatusBadRequest, err.Error(), nil)
 		return
 	}
 
+	if challenge, ok := user.(out.TwoFactorChallengeResponse); ok {
+		handleSuccessResponse(c, http.StatusAccepted, "Two-factor authentication required", challenge)
+		return
+	}
+
 	errSession := h.UserSession.AddUserSession(user.(out.LoginResponse).UserID, user.(out.LoginResponse).Token,
-		user.(out.LoginResponse).RefreshToken, c.ClientIP(), deviceID)
+		user.(out.LoginResponse).RefreshToken, c.ClientIP(), c.Request.UserAgent())
 
 	if errSession != nil {
 		handleErrorResponse(c, http.StatusInternalServerError, "Failed to create user session", err)
@@ -156,12 +187,54 @@ func (h authController) LoginPhoneNumber(c *gin.Context) {
 
 	user, errs := h.AuthService.LoginPhoneNumber(&req, deviceID)
 	if errs != nil {
-		handleErrorResponse(c, http.StatusBadRequest, errs.Error(), nil)
+		handleErrorResponse(c, pinErrorStatus(errs), errs.Error(), nil)
+		return
+	}
+
+	if challenge, ok := user.(out.TwoFactorChallengeResponse); ok {
+		handleSuccessResponse(c, http.StatusAccepted, "Two-factor authentication required", challenge)
+		return
+	}
+
+	err := h.UserSession.AddUserSession(user.(out.LoginResponse).UserID, user.(out.LoginResponse).Token,
+		user.(out.LoginResponse).RefreshToken, c.ClientIP(), c.Request.UserAgent())
+
+	if err != nil {
+		handleErrorResponse(c, http.StatusInternalServerError, "Failed to create user session", err)
+		return
+	}
+
+	handleSuccessResponse(c, http.StatusOK, "Login successful", user)
+}
+
+func (h authController) VerifyTwoFactorLogin(c *gin.Context) {
+	var req in.TwoFactorLoginRequest
+	var deviceID = c.GetHeader("Device-Type")
+
+	if deviceID != "WEB" && deviceID != "MOBILE" {
+		handleErrorResponse(c, http.StatusBadRequest, "Invalid or missing Device-Type", nil)
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleErrorResponse(c, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	user, errs := h.AuthService.VerifyTwoFactorLogin(&req, deviceID)
+	if errs != nil {
+		var throttleErr *utils.ThrottleError
+		if errors.As(errs, &throttleErr) {
+			c.Header("Retry-After", strconv.Itoa(throttleErr.RetryAfterSeconds()))
+			handleErrorResponse(c, http.StatusTooManyRequests, errs.Error(), nil)
+			return
+		}
+		handleErrorResponse(c, http.StatusUnauthorized, errs.Error(), nil)
 		return
 	}
 
 	err := h.UserSession.AddUserSession(user.(out.LoginResponse).UserID, user.(out.LoginResponse).Token,
-		user.(out.LoginResponse).RefreshToken, c.ClientIP(), deviceID)
+		user.(out.LoginResponse).RefreshToken, c.ClientIP(), c.Request.UserAgent())
 
 	if err != nil {
 		handleErrorResponse(c, http.StatusInternalServerError, "Failed to create user session", err)
@@ -184,7 +257,7 @@ func (h authController) ResetPassword(c *gin.Context) {
 
 	errs := h.AuthService.ResetPassword(&req)
 	if errs != nil {
-		handleErrorResponse(c, http.StatusBadRequest, errs.Error(), nil)
+		handleErrorResponse(c, http.StatusBadRequest, errs.Error(), passwordPolicyErrors(errs))
 		return
 	}
 
@@ -224,7 +297,7 @@ func (h authController) VerifyDeviceID(c *gin.Context) {
 
 	data, errs := h.AuthService.VerifyDeviceID(&req)
 	if errs != nil {
-		handleErrorResponse(c, http.StatusBadRequest, errs.Error(), nil)
+		handleErrorResponse(c, pinErrorStatus(errs), errs.Error(), nil)
 		return
 	}
 
@@ -249,7 +322,7 @@ func (h authController) VerifyPinCode(c *gin.Context) {
 
 	clientID, err := h.AuthService.VerifyPinCode(&req, token.ClientID)
 	if err != nil {
-		handleErrorResponse(c, http.StatusBadRequest, err.Error(), nil)
+		handleErrorResponse(c, pinErrorStatus(err), err.Error(), nil)
 		return
 	}
 
@@ -284,8 +357,9 @@ func (h authController) ChangePinCode(c *gin.Context) {
 
 func (h authController) ForgetPinCode(c *gin.Context) {
 	var req struct {
-		Email   string `json:"email" binding:"required"`
-		PinCode string `json:"pin_code" binding:"required"`
+		Email    string `json:"email" binding:"required"`
+		Password string `json:"password" binding:"required"`
+		PinCode  string `json:"pin_code" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -324,7 +398,7 @@ func (h authController) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	newToken, errs := h.AuthService.RefreshToken(&req, token.ClientID)
+	newToken, errs := h.AuthService.RefreshToken(&req, token.ClientID, c.ClientIP())
 	if errs != nil {
 		handleErrorResponse(c, http.StatusBadRequest, errs.Error(), nil)
 		return
@@ -333,35 +407,38 @@ func (h authController) RefreshToken(c *gin.Context) {
 	handleSuccessResponse(c, http.StatusOK, "Token refreshed successfully", newToken)
 }
 
-func (h authController) RegisterInternalToken(c *gin.Context) {
+func (h authController) UpdateRole(ctx *gin.Context) {
 	var req struct {
-		ResourceName string `json:"resource_name" binding:"required"`
+		RoleID uint `json:"role_id" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		handleErrorResponse(c, http.StatusBadRequest, "Invalid request", err)
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.SendResponse(ctx, 400, "Invalid request", nil, err)
 		return
 	}
 
-	token, errs := h.AuthService.RegisterInternalToken(&req)
-	if errs != nil {
-		response.SendResponse(c, http.StatusBadRequest, errs.Error(), nil, errs)
+	userID, err := utils.ConvertToUint(ctx.Param("id"))
+	if err != nil {
+		response.SendResponse(ctx, 400, "User ID must be a number", nil, err)
 		return
 	}
 
-	handleSuccessResponse(c, http.StatusOK, "Internal token registered successfully", token)
-}
-
-func (h authController) UpdateRole(ctx *gin.Context) {
-	var req struct {
-		RoleID uint `json:"role_id" binding:"required"`
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
 	}
 
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		response.SendResponse(ctx, 400, "Invalid request", nil, err)
+	errs := h.AuthService.UpdateRole(userID, req.RoleID, token.ClientID)
+	if errs != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, errs.Error(), nil, errs)
 		return
 	}
 
+	response.SendResponse(ctx, 200, "Role updated successfully", nil, nil)
+}
+
+func (h authController) UnlockPinCode(ctx *gin.Context) {
 	userID, err := utils.ConvertToUint(ctx.Param("id"))
 	if err != nil {
 		response.SendResponse(ctx, 400, "User ID must be a number", nil, err)
@@ -374,13 +451,12 @@ func (h authController) UpdateRole(ctx *gin.Context) {
 		return
 	}
 
-	errs := h.AuthService.UpdateRole(userID, req.RoleID, token.ClientID)
-	if errs != nil {
+	if errs := h.AuthService.UnlockPinCode(userID, token.ClientID); errs != nil {
 		response.SendResponse(ctx, http.StatusBadRequest, errs.Error(), nil, errs)
 		return
 	}
 
-	response.SendResponse(ctx, 200, "Role updated successfully", nil, nil)
+	response.SendResponse(ctx, 200, "Pin code unlocked successfully", nil, nil)
 }
 
 func (h authController) GetListUser(ctx *gin.Context) {
@@ -459,6 +535,10 @@ func (h authController) ChangePassword(ctx *gin.Context) {
 
 	errs := h.AuthService.ChangePassword(&req, token.ClientID)
 	if errs != nil {
+		if fieldErrors := passwordPolicyErrors(errs); fieldErrors != nil {
+			response.SendResponse(ctx, http.StatusBadRequest, errs.Error(), nil, fieldErrors)
+			return
+		}
 		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, errs)
 		return
 	}
@@ -487,7 +567,7 @@ func (h authController) Logout(ctx *gin.Context) {
 		return
 	}
 
-	err := h.UserSession.LogoutSession(token.UserID)
+	err := h.UserSession.LogoutSession(token.UserID, token.AccessUUID)
 	if err != nil {
 		response.SendResponse(ctx, 400, "User Session", nil, err.Error())
 		return
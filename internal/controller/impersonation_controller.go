@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/services"
+	"authentication/internal/utils"
+	"authentication/package/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ImpersonationController interface {
+	TokenExchange(ctx *gin.Context)
+	GetAudits(ctx *gin.Context)
+}
+
+type impersonationController struct {
+	ImpersonationService services.ImpersonationService
+}
+
+func NewImpersonationController(impersonationService services.ImpersonationService) ImpersonationController {
+	return impersonationController{ImpersonationService: impersonationService}
+}
+
+func (h impersonationController) TokenExchange(ctx *gin.Context) {
+	var req in.TokenExchangeRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		sendOauthError(ctx, utils.NewOAuthError(utils.OAuthErrInvalidRequest, err.Error()))
+		return
+	}
+
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	exchanged, err := h.ImpersonationService.Exchange(&req, token.ClientID, ctx.ClientIP(), ctx.Request.UserAgent())
+	if err != nil {
+		sendOauthError(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+	ctx.JSON(http.StatusOK, exchanged)
+}
+
+func (h impersonationController) GetAudits(ctx *gin.Context) {
+	var filter in.ImpersonationAuditFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Invalid request", nil, err.Error())
+		return
+	}
+
+	pageIndex, pageSize, err := utils.GetPageIndexPageSize(ctx)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Invalid page index or page size", nil, err.Error())
+		return
+	}
+
+	audits, total, err := h.ImpersonationService.GetAudits(filter, pageIndex, pageSize)
+	if err != nil {
+		response.SendResponseList(ctx, http.StatusInternalServerError, "Failed to get impersonation audits", response.PagedData{
+			Total:     total,
+			PageIndex: pageIndex,
+			PageSize:  pageSize,
+			Items:     nil,
+		}, err.Error())
+		return
+	}
+
+	response.SendResponseList(ctx, http.StatusOK, "Impersonation audits retrieved successfully", response.PagedData{
+		Total:     total,
+		PageIndex: pageIndex,
+		PageSize:  pageSize,
+		Items:     audits,
+	}, nil)
+}
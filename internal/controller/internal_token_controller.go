@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"authentication/internal/services"
+	"authentication/internal/utils"
+	"authentication/package/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type InternalTokenController interface {
+	CreateToken(ctx *gin.Context)
+	GetTokens(ctx *gin.Context)
+	RotateToken(ctx *gin.Context)
+	RevokeToken(ctx *gin.Context)
+}
+
+type internalTokenController struct {
+	InternalTokenService services.InternalTokenService
+}
+
+func NewInternalTokenController(internalTokenService services.InternalTokenService) InternalTokenController {
+	return internalTokenController{InternalTokenService: internalTokenService}
+}
+
+func (h internalTokenController) CreateToken(ctx *gin.Context) {
+	resourceID, err := utils.ConvertToUint(ctx.Param("id"))
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Resource ID must be a number", nil, err)
+		return
+	}
+
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	internalToken, err := h.InternalTokenService.CreateToken(resourceID, token.ClientID)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusCreated, "Internal token created successfully, store the token now as it will not be shown again", internalToken, nil)
+}
+
+func (h internalTokenController) GetTokens(ctx *gin.Context) {
+	resourceID, err := utils.ConvertToUint(ctx.Param("id"))
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Resource ID must be a number", nil, err)
+		return
+	}
+
+	tokens, err := h.InternalTokenService.GetTokens(resourceID)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "Success", tokens, nil)
+}
+
+func (h internalTokenController) RotateToken(ctx *gin.Context) {
+	id, err := utils.ConvertToUint(ctx.Param("id"))
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Internal token ID must be a number", nil, err)
+		return
+	}
+
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	internalToken, err := h.InternalTokenService.RotateToken(id, token.ClientID)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusCreated, "Internal token rotated successfully, store the token now as it will not be shown again", internalToken, nil)
+}
+
+func (h internalTokenController) RevokeToken(ctx *gin.Context) {
+	id, err := utils.ConvertToUint(ctx.Param("id"))
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Internal token ID must be a number", nil, err)
+		return
+	}
+
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := h.InternalTokenService.RevokeToken(id, token.ClientID); err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "Internal token revoked successfully", nil, nil)
+}
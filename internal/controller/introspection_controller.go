@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/services"
+	"authentication/internal/utils"
+	"authentication/package/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type IntrospectionController interface {
+	Introspect(ctx *gin.Context)
+}
+
+type introspectionController struct {
+	IntrospectionService services.IntrospectionService
+}
+
+func NewIntrospectionController(introspectionService services.IntrospectionService) IntrospectionController {
+	return introspectionController{IntrospectionService: introspectionService}
+}
+
+func (h introspectionController) Introspect(ctx *gin.Context) {
+	var req in.IntrospectionRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		sendOauthError(ctx, utils.NewOAuthError(utils.OAuthErrInvalidRequest, err.Error()))
+		return
+	}
+	if req.Token == "" {
+		sendOauthError(ctx, utils.NewOAuthError(utils.OAuthErrInvalidRequest, "token is required"))
+		return
+	}
+
+	introspection, err := h.IntrospectionService.Introspect(&req)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusServiceUnavailable, "Unable to verify token", nil, err.Error())
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+	ctx.JSON(http.StatusOK, introspection)
+}
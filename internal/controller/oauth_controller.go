@@ -0,0 +1,264 @@
+package controller
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/dto/out"
+	"authentication/internal/models"
+	"authentication/internal/services"
+	"authentication/internal/utils"
+	"authentication/package/response"
+	"crypto/subtle"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/url"
+)
+
+type OauthController interface {
+	Authorize(ctx *gin.Context)
+	Consent(ctx *gin.Context)
+	Login(ctx *gin.Context)
+	Token(ctx *gin.Context)
+	CreateClient(ctx *gin.Context)
+	GetClients(ctx *gin.Context)
+	DeleteClient(ctx *gin.Context)
+}
+
+type oauthController struct {
+	OauthService services.OauthService
+	SecureCookie bool
+}
+
+func NewOauthController(oauthService services.OauthService, secureCookie bool) OauthController {
+	return oauthController{OauthService: oauthService, SecureCookie: secureCookie}
+}
+
+// Authorize is opened by the browser the client redirected. Without a browser session it shows the
+// login form, otherwise it asks the user to approve the client.
+func (h oauthController) Authorize(ctx *gin.Context) {
+	var req in.OauthAuthorizeRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		sendOauthError(ctx, utils.NewOAuthError(utils.OAuthErrInvalidRequest, err.Error()))
+		return
+	}
+
+	consent, redirect, err := h.OauthService.PrepareAuthorize(&req)
+	if err != nil {
+		sendOauthError(ctx, err)
+		return
+	}
+	if redirect != "" {
+		ctx.Redirect(http.StatusFound, redirect)
+		return
+	}
+
+	session := h.browserSession(ctx)
+	if session == nil {
+		utils.RenderOauthLoginPage(ctx, http.StatusOK, utils.OauthLoginData{ReturnTo: ctx.Request.URL.RequestURI()})
+		return
+	}
+
+	utils.RenderOauthConsentPage(ctx, utils.OauthConsentData{
+		ClientName:  consent.ClientName,
+		RedirectURI: consent.RedirectURI,
+		Scopes:      consent.Scopes,
+		CSRFToken:   session.CSRFToken,
+		Params:      authorizeParams(ctx.Request.URL.Query()),
+	})
+}
+
+// Consent receives the user's decision from the consent page and redirects back to the client
+func (h oauthController) Consent(ctx *gin.Context) {
+	var req in.OauthConsentRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		sendOauthError(ctx, utils.NewOAuthError(utils.OAuthErrInvalidRequest, err.Error()))
+		return
+	}
+
+	session := h.browserSession(ctx)
+	if session == nil {
+		returnTo := url.URL{Path: utils.OauthAuthorizePath, RawQuery: authorizeValues(ctx.Request.PostForm).Encode()}
+		utils.RenderOauthLoginPage(ctx, http.StatusUnauthorized, utils.OauthLoginData{
+			ReturnTo: returnTo.RequestURI(),
+			Error:    "Your session has expired, please sign in again",
+		})
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(req.CSRFToken), []byte(session.CSRFToken)) != 1 {
+		sendOauthError(ctx, utils.NewOAuthError(utils.OAuthErrInvalidRequest, "invalid CSRF token"))
+		return
+	}
+
+	redirect, err := h.OauthService.Authorize(&req.OauthAuthorizeRequest, session.ClientID, req.Decision == utils.OauthConsentApprove)
+	if err != nil {
+		sendOauthError(ctx, err)
+		return
+	}
+
+	ctx.Redirect(http.StatusSeeOther, redirect)
+}
+
+// Login signs the user in from the authorization page and returns to the pending authorization request
+func (h oauthController) Login(ctx *gin.Context) {
+	var req in.OauthLoginRequest
+	if err := ctx.ShouldBind(&req); err != nil || !utils.IsOauthAuthorizeURI(req.ReturnTo) {
+		response.SendResponse(ctx, http.StatusBadRequest, "Invalid request", nil, "username, password and a valid return_to are required")
+		return
+	}
+
+	sessionID, err := h.OauthService.Login(&req, ctx.ClientIP())
+	if err != nil {
+		utils.RenderOauthLoginPage(ctx, http.StatusUnauthorized, utils.OauthLoginData{
+			ReturnTo: req.ReturnTo,
+			Username: req.Username,
+			Error:    err.Error(),
+		})
+		return
+	}
+
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(utils.OauthSessionCookie, sessionID, utils.OauthBrowserSessionExpiry*60, "/oauth", "", h.SecureCookie, true)
+	ctx.Redirect(http.StatusSeeOther, req.ReturnTo)
+}
+
+func (h oauthController) Token(ctx *gin.Context) {
+	var req in.OauthTokenRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		sendOauthError(ctx, utils.NewOAuthError(utils.OAuthErrInvalidRequest, err.Error()))
+		return
+	}
+
+	// RFC 6749 section 2.3.1: HTTP Basic credentials are form-encoded before being base64-encoded
+	if username, password, ok := ctx.Request.BasicAuth(); ok {
+		clientID, errID := url.QueryUnescape(username)
+		clientSecret, errSecret := url.QueryUnescape(password)
+		if errID != nil || errSecret != nil {
+			sendOauthError(ctx, utils.NewOAuthError(utils.OAuthErrInvalidClient, "malformed client credentials"))
+			return
+		}
+		req.ClientID = clientID
+		req.ClientSecret = clientSecret
+	}
+
+	token, err := h.OauthService.Token(&req, ctx.ClientIP(), ctx.Request.UserAgent())
+	if err != nil {
+		sendOauthError(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+	ctx.JSON(http.StatusOK, token)
+}
+
+func (h oauthController) CreateClient(ctx *gin.Context) {
+	var req in.OauthClientRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Invalid request", nil, err.Error())
+		return
+	}
+
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	client, err := h.OauthService.CreateClient(&req, token.ClientID)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusCreated, "Client registered successfully, store the client secret now as it will not be shown again", client, nil)
+}
+
+func (h oauthController) GetClients(ctx *gin.Context) {
+	clients, err := h.OauthService.GetClients()
+	if err != nil {
+		response.SendResponse(ctx, http.StatusInternalServerError, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "Success", clients, nil)
+}
+
+func (h oauthController) DeleteClient(ctx *gin.Context) {
+	id, err := utils.ConvertToUint(ctx.Param("id"))
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Client ID must be a number", nil, err)
+		return
+	}
+
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := h.OauthService.DeleteClient(id, token.ClientID); err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "Client deleted successfully", nil, nil)
+}
+
+func (h oauthController) browserSession(ctx *gin.Context) *models.OauthBrowserSession {
+	sessionID, err := ctx.Cookie(utils.OauthSessionCookie)
+	if err != nil {
+		return nil
+	}
+
+	session, err := h.OauthService.GetBrowserSession(sessionID)
+	if err != nil {
+		return nil
+	}
+	return session
+}
+
+// authorizeValues keeps only the authorization request parameters of a query or form
+func authorizeValues(values url.Values) url.Values {
+	kept := url.Values{}
+	for _, name := range []string{"response_type", "client_id", "redirect_uri", "scope", "state", "code_challenge", "code_challenge_method"} {
+		if value := values.Get(name); value != "" {
+			kept.Set(name, value)
+		}
+	}
+	return kept
+}
+
+// authorizeParams flattens the authorization request parameters for the consent form
+func authorizeParams(values url.Values) map[string]string {
+	params := make(map[string]string)
+	for name := range authorizeValues(values) {
+		params[name] = values.Get(name)
+	}
+	return params
+}
+
+// sendOauthError writes an RFC 6749 section 5.2 error body
+func sendOauthError(ctx *gin.Context, err error) {
+	var oauthErr *utils.OAuthError
+	if !errors.As(err, &oauthErr) {
+		oauthErr = utils.NewOAuthError(utils.OAuthErrServerError, err.Error())
+	}
+
+	status := http.StatusBadRequest
+	switch oauthErr.Code {
+	case utils.OAuthErrInvalidClient:
+		status = http.StatusUnauthorized
+		ctx.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	case utils.OAuthErrAccessDenied:
+		status = http.StatusForbidden
+	case utils.OAuthErrServerError:
+		status = http.StatusInternalServerError
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+	ctx.AbortWithStatusJSON(status, out.OauthErrorResponse{
+		Error:            oauthErr.Code,
+		ErrorDescription: oauthErr.Description,
+	})
+}
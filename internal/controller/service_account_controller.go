@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/services"
+	"authentication/internal/utils"
+	"authentication/package/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ServiceAccountController interface {
+	CreateServiceAccount(ctx *gin.Context)
+	GetServiceAccounts(ctx *gin.Context)
+	UpdateServiceAccount(ctx *gin.Context)
+	RotateSecret(ctx *gin.Context)
+	DeleteServiceAccount(ctx *gin.Context)
+}
+
+type serviceAccountController struct {
+	ServiceAccountService services.ServiceAccountService
+}
+
+func NewServiceAccountController(serviceAccountService services.ServiceAccountService) ServiceAccountController {
+	return serviceAccountController{ServiceAccountService: serviceAccountService}
+}
+
+func (h serviceAccountController) CreateServiceAccount(ctx *gin.Context) {
+	var req in.ServiceAccountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Invalid request", nil, err.Error())
+		return
+	}
+
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	account, err := h.ServiceAccountService.CreateServiceAccount(&req, token.ClientID)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusCreated, "Service account created successfully, store the client secret now as it will not be shown again", account, nil)
+}
+
+func (h serviceAccountController) GetServiceAccounts(ctx *gin.Context) {
+	accounts, err := h.ServiceAccountService.GetServiceAccounts()
+	if err != nil {
+		response.SendResponse(ctx, http.StatusInternalServerError, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "Success", accounts, nil)
+}
+
+func (h serviceAccountController) UpdateServiceAccount(ctx *gin.Context) {
+	id, err := utils.ConvertToUint(ctx.Param("id"))
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Service account ID must be a number", nil, err)
+		return
+	}
+
+	var req in.ServiceAccountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Invalid request", nil, err.Error())
+		return
+	}
+
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	account, err := h.ServiceAccountService.UpdateServiceAccount(id, &req, token.ClientID)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "Service account updated successfully", account, nil)
+}
+
+func (h serviceAccountController) RotateSecret(ctx *gin.Context) {
+	id, err := utils.ConvertToUint(ctx.Param("id"))
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Service account ID must be a number", nil, err)
+		return
+	}
+
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	account, err := h.ServiceAccountService.RotateSecret(id, token.ClientID)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "Client secret rotated successfully, store the client secret now as it will not be shown again", account, nil)
+}
+
+func (h serviceAccountController) DeleteServiceAccount(ctx *gin.Context) {
+	id, err := utils.ConvertToUint(ctx.Param("id"))
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Service account ID must be a number", nil, err)
+		return
+	}
+
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := h.ServiceAccountService.DeleteServiceAccount(id, token.ClientID); err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "Service account deleted successfully", nil, nil)
+}
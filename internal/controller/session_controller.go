@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/services"
+	"authentication/internal/utils"
+	"authentication/package/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type SessionController interface {
+	GetSessions(ctx *gin.Context)
+	RevokeSession(ctx *gin.Context)
+	RevokeOtherSessions(ctx *gin.Context)
+	GetAllSessions(ctx *gin.Context)
+	ForceLogout(ctx *gin.Context)
+}
+
+type sessionController struct {
+	UserSession services.UsersSessionService
+}
+
+func NewSessionController(userSession services.UsersSessionService) SessionController {
+	return sessionController{UserSession: userSession}
+}
+
+func (h sessionController) GetSessions(ctx *gin.Context) {
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	sessions, err := h.UserSession.GetUserSessions(token.UserID, token.AccessUUID)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusInternalServerError, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "Success", sessions, nil)
+}
+
+func (h sessionController) RevokeSession(ctx *gin.Context) {
+	id, err := utils.ConvertToUint(ctx.Param("id"))
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Session ID must be a number", nil, err)
+		return
+	}
+
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := h.UserSession.RevokeSession(token.UserID, id); err != nil {
+		response.SendResponse(ctx, http.StatusNotFound, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "Session revoked successfully", nil, nil)
+}
+
+func (h sessionController) RevokeOtherSessions(ctx *gin.Context) {
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	revoked, err := h.UserSession.RevokeOtherSessions(token.UserID, token.AccessUUID)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusInternalServerError, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "Other sessions revoked successfully", gin.H{"revoked": revoked}, nil)
+}
+
+func (h sessionController) GetAllSessions(ctx *gin.Context) {
+	var filter in.AdminSessionFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Invalid request", nil, err.Error())
+		return
+	}
+
+	pageIndex, pageSize, err := utils.GetPageIndexPageSize(ctx)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Invalid page index or page size", nil, err.Error())
+		return
+	}
+
+	sessions, total, err := h.UserSession.GetSessions(filter, pageIndex, pageSize)
+	if err != nil {
+		response.SendResponseList(ctx, http.StatusInternalServerError, "Failed to get list of sessions", response.PagedData{
+			Total:     total,
+			PageIndex: pageIndex,
+			PageSize:  pageSize,
+			Items:     nil,
+		}, err.Error())
+		return
+	}
+
+	response.SendResponseList(ctx, http.StatusOK, "Sessions retrieved successfully", response.PagedData{
+		Total:     total,
+		PageIndex: pageIndex,
+		PageSize:  pageSize,
+		Items:     sessions,
+	}, nil)
+}
+
+func (h sessionController) ForceLogout(ctx *gin.Context) {
+	userID, err := utils.ConvertToUint(ctx.Param("id"))
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "User ID must be a number", nil, err)
+		return
+	}
+
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	terminated, err := h.UserSession.ForceLogout(userID, token.ClientID)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "User logged out from every session", gin.H{"terminated": terminated}, nil)
+}
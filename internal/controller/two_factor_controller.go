@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/services"
+	"authentication/internal/utils"
+	"authentication/package/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type TwoFactorController interface {
+	EnrollTotp(ctx *gin.Context)
+	ConfirmTotp(ctx *gin.Context)
+	DisableTotp(ctx *gin.Context)
+}
+
+type twoFactorController struct {
+	TwoFactorService services.TwoFactorService
+}
+
+func NewTwoFactorController(twoFactorService services.TwoFactorService) TwoFactorController {
+	return twoFactorController{TwoFactorService: twoFactorService}
+}
+
+func (h twoFactorController) EnrollTotp(ctx *gin.Context) {
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	enrollment, err := h.TwoFactorService.EnrollTotp(token.ClientID)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "Scan the QR code and confirm with a code from your authenticator app", enrollment, nil)
+}
+
+func (h twoFactorController) ConfirmTotp(ctx *gin.Context) {
+	var req in.TotpCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Invalid request", nil, err.Error())
+		return
+	}
+
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := h.TwoFactorService.ConfirmTotp(&req, token.ClientID); err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "Two-factor authentication enabled", nil, nil)
+}
+
+func (h twoFactorController) DisableTotp(ctx *gin.Context) {
+	var req in.TotpCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Invalid request", nil, err.Error())
+		return
+	}
+
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := h.TwoFactorService.DisableTotp(&req, token.ClientID); err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "Two-factor authentication disabled", nil, nil)
+}
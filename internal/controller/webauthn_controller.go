@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/dto/out"
+	"authentication/internal/services"
+	"authentication/internal/utils"
+	"authentication/package/response"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+)
+
+type WebauthnController interface {
+	BeginRegistration(ctx *gin.Context)
+	FinishRegistration(ctx *gin.Context)
+	BeginLogin(ctx *gin.Context)
+	FinishLogin(ctx *gin.Context)
+	GetCredentials(ctx *gin.Context)
+	DeleteCredential(ctx *gin.Context)
+}
+
+type webauthnController struct {
+	WebauthnService services.WebauthnService
+	UserSession     services.UsersSessionService
+}
+
+func NewWebauthnController(webauthnService services.WebauthnService, userSession services.UsersSessionService) WebauthnController {
+	return webauthnController{
+		WebauthnService: webauthnService,
+		UserSession:     userSession,
+	}
+}
+
+func (h webauthnController) BeginRegistration(ctx *gin.Context) {
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	options, err := h.WebauthnService.BeginRegistration(token.ClientID)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "Passkey registration started", options, nil)
+}
+
+func (h webauthnController) FinishRegistration(ctx *gin.Context) {
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	body, err := ctx.GetRawData()
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Invalid request", nil, err.Error())
+		return
+	}
+
+	credential, err := h.WebauthnService.FinishRegistration(body, ctx.Query("name"), token.ClientID)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusCreated, "Passkey registered", credential, nil)
+}
+
+func (h webauthnController) BeginLogin(ctx *gin.Context) {
+	var req in.WebauthnLoginBeginRequest
+	// an empty body starts a discoverable (usernameless) login
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.SendResponse(ctx, http.StatusBadRequest, "Invalid request", nil, err.Error())
+		return
+	}
+
+	options, err := h.WebauthnService.BeginLogin(&req)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "Passkey login started", options, nil)
+}
+
+func (h webauthnController) FinishLogin(ctx *gin.Context) {
+	var deviceID = ctx.GetHeader("Device-Type")
+	if deviceID != "WEB" && deviceID != "MOBILE" {
+		handleErrorResponse(ctx, http.StatusBadRequest, "Invalid or missing Device-Type", nil)
+		return
+	}
+
+	sessionID := ctx.Query("session_id")
+	if sessionID == "" {
+		handleErrorResponse(ctx, http.StatusBadRequest, "Session ID is required", nil)
+		return
+	}
+
+	body, err := ctx.GetRawData()
+	if err != nil {
+		handleErrorResponse(ctx, http.StatusBadRequest, "Invalid request", err)
+		return
+	}
+
+	user, errs := h.WebauthnService.FinishLogin(body, sessionID, deviceID)
+	if errs != nil {
+		handleErrorResponse(ctx, http.StatusUnauthorized, errs.Error(), nil)
+		return
+	}
+
+	err = h.UserSession.AddUserSession(user.(out.LoginResponse).UserID, user.(out.LoginResponse).Token,
+		user.(out.LoginResponse).RefreshToken, ctx.ClientIP(), ctx.Request.UserAgent())
+
+	if err != nil {
+		handleErrorResponse(ctx, http.StatusInternalServerError, "Failed to create user session", err)
+		return
+	}
+
+	handleSuccessResponse(ctx, http.StatusOK, "Login successful", user)
+}
+
+func (h webauthnController) GetCredentials(ctx *gin.Context) {
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	credentials, err := h.WebauthnService.GetCredentials(token.ClientID)
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "Success", credentials, nil)
+}
+
+func (h webauthnController) DeleteCredential(ctx *gin.Context) {
+	credentialID, err := utils.ConvertToUint(ctx.Param("id"))
+	if err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, "Passkey ID must be a number", nil, err)
+		return
+	}
+
+	token, exist := utils.ExtractTokenClaims(ctx)
+	if !exist {
+		response.SendResponse(ctx, http.StatusBadRequest, "Error", nil, "Token not found")
+		return
+	}
+
+	if err := h.WebauthnService.DeleteCredential(credentialID, token.ClientID); err != nil {
+		response.SendResponse(ctx, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
+
+	response.SendResponse(ctx, http.StatusOK, "Passkey deleted", nil, nil)
+}
@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"authentication/internal/dto/out"
+	"authentication/internal/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+type WellKnownController interface {
+	JWKS(ctx *gin.Context)
+	OpenIDConfiguration(ctx *gin.Context)
+}
+
+type wellKnownController struct {
+	JWTService utils.JWTService
+	Issuer     string
+}
+
+func NewWellKnownController(jwtService utils.JWTService, issuer string) WellKnownController {
+	return wellKnownController{JWTService: jwtService, Issuer: strings.TrimRight(issuer, "/")}
+}
+
+func (h wellKnownController) JWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, out.JWKSResponse{Keys: h.JWTService.PublicKeys()})
+}
+
+func (h wellKnownController) OpenIDConfiguration(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, out.OpenIDConfigurationResponse{
+		Issuer:                 h.Issuer,
+		AuthorizationEndpoint:  h.Issuer + "/oauth/authorize",
+		TokenEndpoint:          h.Issuer + "/oauth/token",
+		JwksURI:                h.Issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported: []string{"code"},
+		GrantTypesSupported: []string{
+			utils.GrantTypeAuthorizationCode,
+			utils.GrantTypeRefreshToken,
+			utils.GrantTypeClientCredentials,
+		},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{h.JWTService.Algorithm()},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{utils.CodeChallengeMethodS256},
+	})
+}
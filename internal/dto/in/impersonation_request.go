@@ -0,0 +1,20 @@
+package in
+
+import "time"
+
+// TokenExchangeRequest is an RFC 8693 token exchange request; Reason is recorded in the audit trail
+type TokenExchangeRequest struct {
+	GrantType          string `form:"grant_type" json:"grant_type"`
+	SubjectToken       string `form:"subject_token" json:"subject_token"`
+	SubjectTokenType   string `form:"subject_token_type" json:"subject_token_type"`
+	RequestedTokenType string `form:"requested_token_type" json:"requested_token_type"`
+	Reason             string `form:"reason" json:"reason"`
+}
+
+type ImpersonationAuditFilter struct {
+	ActorUserID   uint       `form:"actor_user_id"`
+	SubjectUserID uint       `form:"subject_user_id"`
+	AccessUUID    string     `form:"access_uuid"`
+	From          *time.Time `form:"from" time_format:"2006-01-02"`
+	To            *time.Time `form:"to" time_format:"2006-01-02"`
+}
@@ -0,0 +1,6 @@
+package in
+
+type IntrospectionRequest struct {
+	Token         string `form:"token" json:"token"`
+	TokenTypeHint string `form:"token_type_hint" json:"token_type_hint"`
+}
@@ -0,0 +1,45 @@
+package in
+
+type OauthAuthorizeRequest struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+}
+
+// OauthConsentRequest is the consent form posted back to /oauth/authorize
+type OauthConsentRequest struct {
+	OauthAuthorizeRequest
+	Decision  string `form:"decision"`
+	CSRFToken string `form:"csrf_token"`
+}
+
+// OauthLoginRequest is the login form shown when /oauth/authorize is opened without a browser session
+type OauthLoginRequest struct {
+	Username string `form:"username" binding:"required"`
+	Password string `form:"password" binding:"required"`
+	Code     string `form:"code"`
+	ReturnTo string `form:"return_to" binding:"required"`
+}
+
+type OauthTokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+type OauthClientRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	RedirectURIs []string `json:"redirect_uris"`
+	GrantTypes   []string `json:"grant_types" binding:"required"`
+	Scopes       []string `json:"scopes"`
+	IsPublic     bool     `json:"is_public"`
+}
@@ -0,0 +1,8 @@
+package in
+
+type ServiceAccountRequest struct {
+	Name      string   `json:"name" binding:"required"`
+	Resources []string `json:"resources" binding:"required"`
+	TokenTTL  int64    `json:"token_ttl"`
+	IsActive  *bool    `json:"is_active"`
+}
@@ -0,0 +1,11 @@
+package in
+
+import "time"
+
+type AdminSessionFilter struct {
+	UserID    uint       `form:"user_id"`
+	IsActive  *bool      `form:"is_active"`
+	IPAddress string     `form:"ip_address"`
+	From      *time.Time `form:"from" time_format:"2006-01-02"`
+	To        *time.Time `form:"to" time_format:"2006-01-02"`
+}
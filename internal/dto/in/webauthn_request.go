@@ -0,0 +1,5 @@
+package in
+
+type WebauthnLoginBeginRequest struct {
+	Username string `json:"username"`
+}
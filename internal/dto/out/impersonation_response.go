@@ -0,0 +1,27 @@
+package out
+
+import "time"
+
+// TokenExchangeResponse follows RFC 8693 section 2.2.1
+type TokenExchangeResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+}
+
+type ImpersonationAuditResponse struct {
+	ID            uint      `json:"id"`
+	AccessUUID    string    `json:"access_uuid"`
+	ActorUserID   uint      `json:"actor_user_id"`
+	ActorClientID string    `json:"actor_client_id"`
+	SubjectUserID uint      `json:"subject_user_id"`
+	Action        string    `json:"action"`
+	Method        string    `json:"method,omitempty"`
+	Path          string    `json:"path,omitempty"`
+	Status        int       `json:"status,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+	IPAddress     string    `json:"ip_address"`
+	UserAgent     string    `json:"user_agent"`
+	CreatedAt     time.Time `json:"created_at"`
+}
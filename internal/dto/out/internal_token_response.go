@@ -0,0 +1,14 @@
+package out
+
+import "time"
+
+type InternalTokenResponse struct {
+	ID         uint      `json:"id"`
+	ResourceID uint      `json:"resource_id"`
+	TokenID    string    `json:"token_id"`
+	Token      string    `json:"token,omitempty"`
+	Status     string    `json:"status"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	CreatedBy  string    `json:"created_by"`
+}
@@ -0,0 +1,29 @@
+package out
+
+import "time"
+
+// IntrospectionResponse follows RFC 7662 section 2.2; inactive tokens only carry active=false
+type IntrospectionResponse struct {
+	Active           bool                `json:"active"`
+	TokenType        string              `json:"token_type,omitempty"`
+	Sub              string              `json:"sub,omitempty"`
+	ClientID         string              `json:"client_id,omitempty"`
+	Scope            string              `json:"scope,omitempty"`
+	Resource         []string            `json:"resource,omitempty"`
+	Role             string              `json:"role,omitempty"`
+	RoleID           uint                `json:"role_id,omitempty"`
+	Exp              int64               `json:"exp,omitempty"`
+	Iat              int64               `json:"iat,omitempty"`
+	Jti              string              `json:"jti,omitempty"`
+	SessionStatus    string              `json:"session_status,omitempty"`
+	SessionID        uint                `json:"session_id,omitempty"`
+	SessionExpiresAt *time.Time          `json:"session_expires_at,omitempty"`
+	ApiKeyID         uint                `json:"api_key_id,omitempty"`
+	Act              *IntrospectionActor `json:"act,omitempty"`
+}
+
+// IntrospectionActor is the act claim of an impersonated token
+type IntrospectionActor struct {
+	Sub    string `json:"sub"`
+	UserID uint   `json:"user_id"`
+}
@@ -0,0 +1,15 @@
+package out
+
+import "time"
+
+type ServiceAccountResponse struct {
+	ID                      uint       `json:"id"`
+	ClientID                string     `json:"client_id"`
+	ClientSecret            string     `json:"client_secret,omitempty"`
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at,omitempty"`
+	Name                    string     `json:"name"`
+	Resources               []string   `json:"resources"`
+	TokenTTL                int64      `json:"token_ttl"`
+	IsActive                bool       `json:"is_active"`
+	CreatedAt               time.Time  `json:"created_at"`
+}
@@ -0,0 +1,27 @@
+package out
+
+import "time"
+
+type UserSessionResponse struct {
+	SessionID  uint       `json:"session_id"`
+	IPAddress  string     `json:"ip_address"`
+	UserAgent  string     `json:"user_agent"`
+	LoginTime  time.Time  `json:"login_time"`
+	LastSeenAt *time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	Current    bool       `json:"current"`
+}
+
+type AdminSessionResponse struct {
+	SessionID  uint       `json:"session_id"`
+	UserID     uint       `json:"user_id"`
+	ClientID   string     `json:"client_id"`
+	Username   string     `json:"username"`
+	IPAddress  string     `json:"ip_address"`
+	UserAgent  string     `json:"user_agent"`
+	LoginTime  time.Time  `json:"login_time"`
+	LastSeenAt *time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LogoutTime *time.Time `json:"logout_time"`
+	IsActive   bool       `json:"is_active"`
+}
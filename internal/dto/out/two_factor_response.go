@@ -0,0 +1,13 @@
+package out
+
+type TotpEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeID       string `json:"challenge_id"`
+	Method            string `json:"method"`
+	ExpiresAt         int64  `json:"expires_at"`
+}
@@ -0,0 +1,16 @@
+package out
+
+import "time"
+
+type WebauthnLoginBeginResponse struct {
+	SessionID string      `json:"session_id"`
+	Options   interface{} `json:"options"`
+}
+
+type WebauthnCredentialResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Transports []string   `json:"transports,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -14,13 +14,17 @@ type AdminMiddleware interface {
 
 // adminMiddleware is the struct that implements AdminMiddleware
 type adminMiddleware struct {
-	JWTService utils.JWTService
+	JWTService      utils.JWTService
+	TokenRevocation utils.TokenRevocation
+	SessionActivity utils.SessionActivity
 }
 
 // NewAdminMiddleware initializes authentication middleware
-func NewAdminMiddleware(jwtService utils.JWTService) AdminMiddleware {
+func NewAdminMiddleware(jwtService utils.JWTService, tokenRevocation utils.TokenRevocation, sessionActivity utils.SessionActivity) AdminMiddleware {
 	return adminMiddleware{
-		JWTService: jwtService,
+		JWTService:      jwtService,
+		TokenRevocation: tokenRevocation,
+		SessionActivity: sessionActivity,
 	}
 }
 
@@ -48,6 +52,21 @@ func (a adminMiddleware) Handler() gin.HandlerFunc {
 			return
 		}
 
+		revoked, err := a.TokenRevocation.IsRevoked(tokenClaims)
+		if err != nil {
+			response.SendResponse(c, http.StatusServiceUnavailable, "Unable to verify token", nil, err.Error())
+			c.Abort()
+			return
+		}
+
+		if revoked {
+			response.SendResponse(c, http.StatusUnauthorized, "Unauthorized", nil, "Token has been revoked")
+			c.Abort()
+			return
+		}
+
+		a.SessionActivity.Touch(tokenClaims.AccessUUID, tokenClaims.Exp)
+
 		c.Set("token", tokenClaims)
 
 		c.Next()
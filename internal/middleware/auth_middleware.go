@@ -1,26 +1,40 @@
 package middleware
 
 import (
+	"authentication/internal/services"
 	"authentication/internal/utils"
 	"authentication/package/response"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"strings"
 )
 
 // AuthMiddleware defines the contract for authentication middleware
 type AuthMiddleware interface {
 	Handler() gin.HandlerFunc
+	DenyImpersonation() gin.HandlerFunc
+	DenyApiKey() gin.HandlerFunc
+	DenyDelegated() gin.HandlerFunc
 }
 
 // authMiddleware is the struct that implements AuthMiddleware
 type authMiddleware struct {
-	JWTService utils.JWTService
+	JWTService      utils.JWTService
+	TokenRevocation utils.TokenRevocation
+	SessionActivity utils.SessionActivity
+	ApiKeyService   services.ApiKeyService
+	Impersonation   services.ImpersonationService
 }
 
 // NewAuthMiddleware initializes authentication middleware
-func NewAuthMiddleware(jwtService utils.JWTService) AuthMiddleware {
+func NewAuthMiddleware(jwtService utils.JWTService, tokenRevocation utils.TokenRevocation, sessionActivity utils.SessionActivity, apiKeyService services.ApiKeyService, impersonation services.ImpersonationService) AuthMiddleware {
 	return authMiddleware{
-		JWTService: jwtService,
+		JWTService:      jwtService,
+		TokenRevocation: tokenRevocation,
+		SessionActivity: sessionActivity,
+		ApiKeyService:   apiKeyService,
+		Impersonation:   impersonation,
 	}
 }
 
@@ -33,6 +47,11 @@ func (a authMiddleware) Handler() gin.HandlerFunc {
 			return
 		}
 
+		if strings.HasPrefix(token, utils.ApiKeyScheme) {
+			a.handleApiKey(c, strings.TrimPrefix(token, utils.ApiKeyScheme))
+			return
+		}
+
 		_, err := a.JWTService.ValidateToken(token)
 		if err != nil {
 			response.SendResponse(c, http.StatusUnauthorized, "Invalid token", nil, err.Error())
@@ -59,18 +78,141 @@ func (a authMiddleware) Handler() gin.HandlerFunc {
 			return
 		}
 
+		// a token delegated to an OAuth client only reaches the resources its grant names
+		if tokenClaims.AuthorizedParty != "" {
+			tokenClaims.Resource = grantedResources(tokenClaims.Resource, tokenClaims.Scope)
+		}
+
 		if !hasAuthResource(tokenClaims.Resource) {
 			response.SendResponse(c, http.StatusUnauthorized, "Unauthorized", nil, "You do not have access to this resource")
 			c.Abort()
 			return
 		}
 
+		revoked, err := a.TokenRevocation.IsRevoked(tokenClaims)
+		if err != nil {
+			response.SendResponse(c, http.StatusServiceUnavailable, "Unable to verify token", nil, err.Error())
+			c.Abort()
+			return
+		}
+
+		if revoked {
+			response.SendResponse(c, http.StatusUnauthorized, "Unauthorized", nil, "Token has been revoked")
+			c.Abort()
+			return
+		}
+
 		c.Set("token", tokenClaims)
 
+		// impersonated tokens have no session of their own; every write they make is audited instead
+		if tokenClaims.Actor != nil {
+			c.Next()
+
+			if utils.IsWriteMethod(c.Request.Method) {
+				a.Impersonation.RecordWrite(tokenClaims, c.Request.Method, c.Request.URL.Path, c.Writer.Status(), c.ClientIP(), c.Request.UserAgent())
+			}
+			return
+		}
+
+		a.SessionActivity.Touch(tokenClaims.AccessUUID, tokenClaims.Exp)
+
+		c.Next()
+	}
+}
+
+// DenyImpersonation rejects impersonated tokens on routes only the user themselves may use, e.g.
+// changing credentials. It must run after Handler.
+func (a authMiddleware) DenyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, impersonated := utils.ExtractActor(c); impersonated {
+			response.SendResponse(c, http.StatusForbidden, "Forbidden", nil, "This action is not allowed while impersonating a user")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// DenyApiKey rejects requests authenticated with an API key. Keys carry no session, so routes that
+// change credentials or end sessions need a token from an interactive login.
+func (a authMiddleware) DenyApiKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tokenClaims, exist := utils.ExtractTokenClaims(c); exist && tokenClaims.ApiKeyID != 0 {
+			response.SendResponse(c, http.StatusForbidden, "Forbidden", nil, "This action is not allowed with an API key")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// DenyDelegated rejects tokens a user granted to an OAuth client. A grant covers the scope the user
+// consented to, never managing the user's credentials, keys or sessions. It must run after Handler.
+func (a authMiddleware) DenyDelegated() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tokenClaims, exist := utils.ExtractTokenClaims(c); exist && tokenClaims.AuthorizedParty != "" {
+			response.SendResponse(c, http.StatusForbidden, "Forbidden", nil, "This action is not allowed with a token issued to an OAuth client")
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	}
 }
 
+// handleApiKey authenticates an "Authorization: ApiKey ..." request and stores the same claims a JWT would
+func (a authMiddleware) handleApiKey(c *gin.Context, key string) {
+	tokenClaims, err := a.ApiKeyService.Authenticate(key)
+	if errors.Is(err, utils.ErrApiKeyInvalid) || errors.Is(err, utils.ErrApiKeyRevoked) {
+		response.SendResponse(c, http.StatusUnauthorized, "Invalid API key", nil, err.Error())
+		c.Abort()
+		return
+	}
+	if err != nil {
+		response.SendResponse(c, http.StatusServiceUnavailable, "Unable to verify API key", nil, err.Error())
+		c.Abort()
+		return
+	}
+
+	if !hasAuthResource(tokenClaims.Resource) {
+		response.SendResponse(c, http.StatusUnauthorized, "Unauthorized", nil, "You do not have access to this resource")
+		c.Abort()
+		return
+	}
+
+	// the claims carry the key's creation time, so a sign-out-everywhere cutoff covers older keys too
+	revoked, err := a.TokenRevocation.IsRevoked(tokenClaims)
+	if err != nil {
+		response.SendResponse(c, http.StatusServiceUnavailable, "Unable to verify API key", nil, err.Error())
+		c.Abort()
+		return
+	}
+
+	if revoked {
+		response.SendResponse(c, http.StatusUnauthorized, "Invalid API key", nil, utils.ErrApiKeyRevoked.Error())
+		c.Abort()
+		return
+	}
+
+	c.Set("token", tokenClaims)
+
+	c.Next()
+}
+
+// grantedResources keeps the resources named in a space-delimited scope
+func grantedResources(resources []string, scope string) []string {
+	granted := strings.Fields(scope)
+	var kept []string
+	for _, res := range resources {
+		if utils.ContainsString(granted, res) {
+			kept = append(kept, res)
+		}
+	}
+	return kept
+}
+
 func hasAuthResource(t []string) bool {
 	for _, res := range t {
 		if res == "auth" {
@@ -0,0 +1,309 @@
+package middleware
+
+import (
+	"authentication/internal/services"
+	"authentication/internal/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// stubJWT treats the bearer value as a key into a table of already verified claims
+type stubJWT struct {
+	utils.JWTService
+	claims map[string]utils.TokenClaims
+}
+
+func (j stubJWT) ValidateToken(token string) (*jwt.MapClaims, error) {
+	if _, ok := j.claims[token]; !ok {
+		return nil, errors.New("token is malformed")
+	}
+	return &jwt.MapClaims{}, nil
+}
+
+func (j stubJWT) ExtractClaims(token string) (*utils.TokenClaims, error) {
+	claims := j.claims[token]
+	return &claims, nil
+}
+
+// denyList revokes tokens by access UUID, or fails every check when err is set
+type denyList struct {
+	utils.TokenRevocation
+	revoked map[string]bool
+	err     error
+}
+
+func (d denyList) IsRevoked(claims *utils.TokenClaims) (bool, error) {
+	return d.revoked[claims.AccessUUID], d.err
+}
+
+// idleSessions ignores session activity
+type idleSessions struct {
+	utils.SessionActivity
+}
+
+func (idleSessions) Touch(string, int64) {}
+
+// apiKeyTable authenticates the keys it holds, reports revoked keys and every other key as invalid
+type apiKeyTable struct {
+	services.ApiKeyService
+	keys    map[string]utils.TokenClaims
+	revoked map[string]bool
+	err     error
+}
+
+func (a apiKeyTable) Authenticate(key string) (*utils.TokenClaims, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+	if a.revoked[key] {
+		return nil, utils.ErrApiKeyRevoked
+	}
+	claims, ok := a.keys[key]
+	if !ok {
+		return nil, utils.ErrApiKeyInvalid
+	}
+	return &claims, nil
+}
+
+func apiKeyClaims(id uint, resource ...string) utils.TokenClaims {
+	return utils.TokenClaims{
+		Authorized: true,
+		UserID:     7,
+		ClientID:   "client-7",
+		Resource:   resource,
+		ApiKeyID:   id,
+	}
+}
+
+// auditLog records the impersonated writes the middleware reports
+type auditLog struct {
+	services.ImpersonationService
+	writes *[]string
+}
+
+func (a auditLog) RecordWrite(_ *utils.TokenClaims, method, path string, status int, _, _ string) {
+	*a.writes = append(*a.writes, fmt.Sprintf("%s %s %d", method, path, status))
+}
+
+func loginClaims(accessUUID string, resource ...string) utils.TokenClaims {
+	return utils.TokenClaims{
+		Authorized: true,
+		AccessUUID: accessUUID,
+		UserID:     7,
+		ClientID:   "client-7",
+		Resource:   resource,
+		Exp:        time.Now().Add(time.Hour).Unix(),
+		IssuedAt:   time.Now().Unix(),
+	}
+}
+
+// serveAuth sends one request with the Authorization header through the handlers
+func serveAuth(authorization string, handlers ...gin.HandlerFunc) int {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/v1/change-pin", append(handlers, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})...)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/change-pin", nil)
+	req.Header.Set("Authorization", authorization)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	expired := loginClaims("expired", "auth")
+	expired.Exp = time.Now().Add(-time.Minute).Unix()
+	unauthorized := loginClaims("unauthorized", "auth")
+	unauthorized.Authorized = false
+
+	jwtService := stubJWT{claims: map[string]utils.TokenClaims{
+		"valid":        loginClaims("valid", "auth"),
+		"revoked":      loginClaims("revoked", "auth"),
+		"expired":      expired,
+		"unauthorized": unauthorized,
+		"other":        loginClaims("other", "billing"),
+	}}
+
+	tests := []struct {
+		name          string
+		authorization string
+		revocation    denyList
+		wantStatus    int
+	}{
+		{"valid token", "valid", denyList{}, http.StatusOK},
+		{"missing token", "", denyList{}, http.StatusUnauthorized},
+		{"malformed token", "garbage", denyList{}, http.StatusUnauthorized},
+		{"revoked token", "revoked", denyList{revoked: map[string]bool{"revoked": true}}, http.StatusUnauthorized},
+		{"expired token", "expired", denyList{}, http.StatusUnauthorized},
+		{"unauthorized token", "unauthorized", denyList{}, http.StatusUnauthorized},
+		{"token without the auth resource", "other", denyList{}, http.StatusUnauthorized},
+		{"revocation list unavailable", "valid", denyList{err: errors.New("connection refused")}, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := NewAuthMiddleware(jwtService, tt.revocation, idleSessions{}, nil, nil)
+			if status := serveAuth(tt.authorization, auth.Handler()); status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareApiKey(t *testing.T) {
+	keys := map[string]utils.TokenClaims{
+		"key-auth":    apiKeyClaims(1, "auth", "billing"),
+		"key-billing": apiKeyClaims(2, "billing"),
+		"key-revoked": apiKeyClaims(3, "auth"),
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		apiKeys       apiKeyTable
+		wantStatus    int
+	}{
+		{"key scoped to auth", utils.ApiKeyScheme + "key-auth", apiKeyTable{keys: keys}, http.StatusOK},
+		{"key scoped to other resources", utils.ApiKeyScheme + "key-billing", apiKeyTable{keys: keys}, http.StatusUnauthorized},
+		{"revoked key", utils.ApiKeyScheme + "key-revoked", apiKeyTable{keys: keys, revoked: map[string]bool{"key-revoked": true}}, http.StatusUnauthorized},
+		{"unknown key", utils.ApiKeyScheme + "key-unknown", apiKeyTable{keys: keys}, http.StatusUnauthorized},
+		{"key sent as a bearer token", "key-auth", apiKeyTable{keys: keys}, http.StatusUnauthorized},
+		{"key store unavailable", utils.ApiKeyScheme + "key-auth", apiKeyTable{err: errors.New("connection refused")}, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := NewAuthMiddleware(stubJWT{}, denyList{}, idleSessions{}, tt.apiKeys, nil)
+			if status := serveAuth(tt.authorization, auth.Handler()); status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareImpersonation(t *testing.T) {
+	impersonated := loginClaims("impersonated", "auth")
+	impersonated.Actor = &utils.Actor{Sub: "super-admin-1", UserID: 1}
+	jwtService := stubJWT{claims: map[string]utils.TokenClaims{
+		"login":        loginClaims("login", "auth"),
+		"impersonated": impersonated,
+	}}
+
+	tests := []struct {
+		name          string
+		authorization string
+		guards        bool
+		wantStatus    int
+		wantWrites    []string
+	}{
+		{"write by the user", "login", false, http.StatusOK, nil},
+		{"write while impersonating", "impersonated", false, http.StatusOK, []string{"POST /v1/change-pin 200"}},
+		{"credential route while impersonating", "impersonated", true, http.StatusForbidden, []string{"POST /v1/change-pin 403"}},
+		{"credential route for the user", "login", true, http.StatusOK, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var writes []string
+			auth := NewAuthMiddleware(jwtService, denyList{}, idleSessions{}, nil, auditLog{writes: &writes})
+			handlers := []gin.HandlerFunc{auth.Handler()}
+			if tt.guards {
+				handlers = append(handlers, auth.DenyImpersonation())
+			}
+
+			if status := serveAuth(tt.authorization, handlers...); status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if fmt.Sprint(writes) != fmt.Sprint(tt.wantWrites) {
+				t.Errorf("audited writes = %v, want %v", writes, tt.wantWrites)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareDelegatedScope(t *testing.T) {
+	delegated := func(scope string) utils.TokenClaims {
+		claims := loginClaims("delegated", "auth", "profile", "billing")
+		claims.AuthorizedParty = "oauth-client"
+		claims.Scope = scope
+		return claims
+	}
+
+	tests := []struct {
+		name          string
+		claims        utils.TokenClaims
+		wantStatus    int
+		wantResources []string
+	}{
+		{"login token keeps its resources", loginClaims("login", "auth", "billing"), http.StatusOK, []string{"auth", "billing"}},
+		{"delegated token is narrowed to its scope", delegated("auth profile"), http.StatusOK, []string{"auth", "profile"}},
+		{"scope naming resources the token lacks", delegated("auth admin"), http.StatusOK, []string{"auth"}},
+		{"scope without auth", delegated("profile billing"), http.StatusUnauthorized, nil},
+		{"delegated token without a scope", delegated(""), http.StatusUnauthorized, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := NewAuthMiddleware(stubJWT{claims: map[string]utils.TokenClaims{"bearer": tt.claims}}, denyList{}, idleSessions{}, nil, nil)
+			var resources []string
+			seeResources := func(c *gin.Context) {
+				claims, _ := utils.ExtractTokenClaims(c)
+				resources = claims.Resource
+			}
+
+			if status := serveAuth("bearer", auth.Handler(), seeResources); status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if !reflect.DeepEqual(resources, tt.wantResources) {
+				t.Errorf("resources = %v, want %v", resources, tt.wantResources)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareCredentialGuards(t *testing.T) {
+	delegated := loginClaims("delegated", "auth")
+	delegated.AuthorizedParty = "oauth-client"
+	delegated.Scope = "auth"
+	impersonated := loginClaims("impersonated", "auth")
+	impersonated.Actor = &utils.Actor{Sub: "super-admin-1", UserID: 1}
+
+	jwtService := stubJWT{claims: map[string]utils.TokenClaims{
+		"login":        loginClaims("login", "auth"),
+		"delegated":    delegated,
+		"impersonated": impersonated,
+	}}
+	apiKeys := apiKeyTable{keys: map[string]utils.TokenClaims{"key-1": apiKeyClaims(1, "auth")}}
+	var writes []string
+	auth := NewAuthMiddleware(jwtService, denyList{}, idleSessions{}, apiKeys, auditLog{writes: &writes})
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+	}{
+		{"interactive login", "login", http.StatusOK},
+		{"token issued to an OAuth client", "delegated", http.StatusForbidden},
+		{"API key", utils.ApiKeyScheme + "key-1", http.StatusForbidden},
+		{"impersonated token", "impersonated", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := serveAuth(tt.authorization, auth.Handler(), auth.DenyApiKey(), auth.DenyDelegated(), auth.DenyImpersonation())
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
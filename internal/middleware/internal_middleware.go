@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"authentication/internal/services"
+	"authentication/internal/utils"
+	"authentication/package/response"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+// InternalMiddleware guards routes only other services may call
+type InternalMiddleware interface {
+	Handler() gin.HandlerFunc
+}
+
+// internalMiddleware is the struct that implements InternalMiddleware
+type internalMiddleware struct {
+	InternalTokenService  services.InternalTokenService
+	ServiceAccountService services.ServiceAccountService
+}
+
+// NewInternalMiddleware initializes internal service middleware
+func NewInternalMiddleware(internalTokenService services.InternalTokenService, serviceAccountService services.ServiceAccountService) InternalMiddleware {
+	return internalMiddleware{
+		InternalTokenService:  internalTokenService,
+		ServiceAccountService: serviceAccountService,
+	}
+}
+
+// Handler returns a middleware function validating the calling service's internal or service account token
+func (m internalMiddleware) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" {
+			response.SendResponse(c, http.StatusUnauthorized, "Missing token", nil, "Authorization header is required")
+			c.Abort()
+			return
+		}
+
+		claims, err := m.InternalTokenService.ValidateToken(token)
+		if errors.Is(err, utils.ErrInternalTokenInvalid) {
+			claims, err = m.ServiceAccountService.ValidateToken(token)
+		}
+		if errors.Is(err, utils.ErrInternalTokenInvalid) || errors.Is(err, utils.ErrInternalTokenRevoked) {
+			response.SendResponse(c, http.StatusUnauthorized, "Invalid token", nil, err.Error())
+			c.Abort()
+			return
+		}
+		if err != nil {
+			response.SendResponse(c, http.StatusServiceUnavailable, "Unable to verify token", nil, err.Error())
+			c.Abort()
+			return
+		}
+
+		c.Set(utils.InternalToken, claims)
+
+		c.Next()
+	}
+}
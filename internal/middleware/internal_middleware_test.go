@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"authentication/internal/models"
+	"authentication/internal/repository"
+	"authentication/internal/services"
+	"authentication/internal/utils"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// internalTokenTable keeps internal token records by token ID
+type internalTokenTable struct {
+	repository.InternalTokenRepository
+	tokens map[string]*models.InternalToken
+	err    error
+}
+
+func (r *internalTokenTable) AddInternalToken(token *models.InternalToken) error {
+	token.ID = uint(len(r.tokens) + 1)
+	r.tokens[token.TokenID] = token
+	return nil
+}
+
+func (r *internalTokenTable) GetInternalTokenByTokenID(tokenID string) (*models.InternalToken, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	token, ok := r.tokens[tokenID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return token, nil
+}
+
+// resourceTable serves a single resource
+type resourceTable struct {
+	repository.ResourceRepository
+}
+
+func (resourceTable) GetResourceByID(id uint) (*models.Resource, error) {
+	return &models.Resource{ResourceID: id, Name: "billing"}, nil
+}
+
+// serviceAccountTokens accepts only the listed service account tokens
+type serviceAccountTokens struct {
+	services.ServiceAccountService
+	tokens map[string]bool
+}
+
+func (s serviceAccountTokens) ValidateToken(token string) (*utils.InternalClaims, error) {
+	if !s.tokens[token] {
+		return nil, utils.ErrInternalTokenInvalid
+	}
+	return &utils.InternalClaims{Service: "billing-job"}, nil
+}
+
+func TestInternalMiddleware(t *testing.T) {
+	jwtService := utils.NewJWTService("jwt-secret", nil, "authentication")
+	hasher := utils.NewTokenHasher("token-hash-key")
+	tokens := &internalTokenTable{tokens: map[string]*models.InternalToken{}}
+	service := services.NewInternalTokenService(tokens, resourceTable{}, jwtService, hasher, time.Hour, time.Minute, "authentication-service")
+	otherAudience := services.NewInternalTokenService(tokens, resourceTable{}, jwtService, hasher, time.Hour, time.Minute, "billing-service")
+
+	issue := func(service services.InternalTokenService) string {
+		issued, err := service.CreateToken(1, "admin-1")
+		if err != nil {
+			t.Fatalf("CreateToken() error = %v", err)
+		}
+		return issued.Token
+	}
+	valid := issue(service)
+	wrongAudience := issue(otherAudience)
+	revoked := issue(service)
+	expired := issue(service)
+	forgotten := issue(service)
+	record := func(token string) *models.InternalToken {
+		for _, record := range tokens.tokens {
+			if record.Token == hasher.Hash(token) {
+				return record
+			}
+		}
+		t.Fatalf("no record stored for %q", token)
+		return nil
+	}
+	record(revoked).Expired = true
+	record(expired).ExpiresAt = time.Now().Add(-time.Second)
+	delete(tokens.tokens, record(forgotten).TokenID)
+	accounts := serviceAccountTokens{tokens: map[string]bool{"service-account-token": true}}
+	userToken, err := jwtService.GenerateToken(models.Users{UserID: 7, ClientID: "client-7"}, []string{"auth"}, "User")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		storeErr      error
+		wantStatus    int
+	}{
+		{"valid token", "Bearer " + valid, nil, http.StatusOK},
+		{"missing token", "", nil, http.StatusUnauthorized},
+		{"token for another audience", "Bearer " + wrongAudience, nil, http.StatusUnauthorized},
+		{"revoked token", "Bearer " + revoked, nil, http.StatusUnauthorized},
+		{"expired record", "Bearer " + expired, nil, http.StatusUnauthorized},
+		{"token without a record", "Bearer " + forgotten, nil, http.StatusUnauthorized},
+		{"service account token", "Bearer service-account-token", nil, http.StatusOK},
+		{"user access token", "Bearer " + userToken.AccessToken, nil, http.StatusUnauthorized},
+		{"token store unavailable", "Bearer " + valid, errors.New("connection refused"), http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens.err = tt.storeErr
+			if status := serveAuth(tt.authorization, NewInternalMiddleware(service, accounts).Handler()); status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"authentication/internal/utils"
+	"authentication/internal/utils/logger"
+	"authentication/package/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// RateLimitMiddleware defines the contract for rate limiting middleware
+type RateLimitMiddleware interface {
+	Handler(rule string) gin.HandlerFunc
+}
+
+// rateLimitMiddleware is the struct that implements RateLimitMiddleware
+type rateLimitMiddleware struct {
+	RedisService utils.RedisService
+	Rules        map[string]utils.RateLimitRule
+	Enabled      bool
+}
+
+// NewRateLimitMiddleware initializes rate limiting middleware with the configured rules
+func NewRateLimitMiddleware(redis utils.RedisService, rules map[string]utils.RateLimitRule, enabled bool) RateLimitMiddleware {
+	return rateLimitMiddleware{
+		RedisService: redis,
+		Rules:        rules,
+		Enabled:      enabled,
+	}
+}
+
+// Handler limits the route with the named rule. Every route keeps its own count, so a rule
+// shared by several routes is applied to each one separately. When Redis is unavailable
+// requests are let through.
+func (m rateLimitMiddleware) Handler(name string) gin.HandlerFunc {
+	rule, ok := m.Rules[name]
+	if !ok {
+		logger.Warn().Str("rule", name).Msg("Rate limit rule is not configured, route is not limited")
+	}
+
+	return func(c *gin.Context) {
+		if !m.Enabled || !ok {
+			c.Next()
+			return
+		}
+
+		key := name + ":" + c.FullPath()
+		switch rule.KeyBy {
+		case utils.RateLimitByClientID:
+			if token, exist := utils.ExtractTokenClaims(c); exist {
+				key += ":" + token.ClientID
+			} else {
+				key += ":" + c.ClientIP()
+			}
+		case utils.RateLimitByIP:
+			key += ":" + c.ClientIP()
+		}
+
+		retryAfter, err := m.RedisService.SlidingWindow(utils.RateLimit, key, rule.Limit, rule.Window)
+		if err != nil {
+			logger.Ctx(c.Request.Context()).Error().Err(err).Msg("Error checking rate limit")
+			c.Next()
+			return
+		}
+
+		if retryAfter > 0 {
+			throttleErr := utils.NewThrottleError(retryAfter)
+			c.Header("Retry-After", strconv.Itoa(throttleErr.RetryAfterSeconds()))
+			response.SendResponse(c, http.StatusTooManyRequests, "Too many requests", nil, throttleErr.Error())
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
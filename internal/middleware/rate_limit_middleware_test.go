@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"authentication/internal/utils"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// slidingWindowRedis answers SlidingWindow from a queue and records the keys it was asked about
+type slidingWindowRedis struct {
+	utils.RedisService
+	retryAfter []time.Duration
+	err        error
+	keys       []string
+}
+
+func (r *slidingWindowRedis) SlidingWindow(key, clientID string, limit int, window time.Duration) (time.Duration, error) {
+	r.keys = append(r.keys, key+":"+clientID)
+	if r.err != nil {
+		return 0, r.err
+	}
+	retryAfter := r.retryAfter[0]
+	r.retryAfter = r.retryAfter[1:]
+	return retryAfter, nil
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rules := map[string]utils.RateLimitRule{
+		utils.RateLimitSensitive:     {Limit: 5, Window: time.Minute, KeyBy: utils.RateLimitByIP},
+		utils.RateLimitAuthenticated: {Limit: 5, Window: time.Minute, KeyBy: utils.RateLimitByClientID},
+		utils.RateLimitPublic:        {Limit: 5, Window: time.Minute, KeyBy: utils.RateLimitByRoute},
+	}
+
+	tests := []struct {
+		name           string
+		rule           string
+		enabled        bool
+		claims         *utils.TokenClaims
+		retryAfter     time.Duration
+		redisErr       error
+		wantStatus     int
+		wantRetryAfter string
+		wantKey        string
+	}{
+		{"allowed", utils.RateLimitSensitive, true, nil, 0, nil, http.StatusOK, "", "rate_limit:sensitive:/login:192.0.2.1"},
+		{"limited", utils.RateLimitSensitive, true, nil, 1500 * time.Millisecond, nil, http.StatusTooManyRequests, "2", "rate_limit:sensitive:/login:192.0.2.1"},
+		{"keyed by client ID", utils.RateLimitAuthenticated, true, &utils.TokenClaims{ClientID: "client-1"}, 0, nil, http.StatusOK, "", "rate_limit:authenticated:/login:client-1"},
+		{"client ID falls back to IP", utils.RateLimitAuthenticated, true, nil, 0, nil, http.StatusOK, "", "rate_limit:authenticated:/login:192.0.2.1"},
+		{"keyed by route", utils.RateLimitPublic, true, nil, 0, nil, http.StatusOK, "", "rate_limit:public:/login"},
+		{"Redis unavailable lets requests through", utils.RateLimitSensitive, true, nil, 0, errors.New("connection refused"), http.StatusOK, "", "rate_limit:sensitive:/login:192.0.2.1"},
+		{"disabled", utils.RateLimitSensitive, false, nil, 0, nil, http.StatusOK, "", ""},
+		{"unknown rule", "missing", true, nil, 0, nil, http.StatusOK, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redis := &slidingWindowRedis{retryAfter: []time.Duration{tt.retryAfter}, err: tt.redisErr}
+			limiter := NewRateLimitMiddleware(redis, rules, tt.enabled)
+
+			router := gin.New()
+			router.POST("/login", func(c *gin.Context) {
+				if tt.claims != nil {
+					c.Set("token", tt.claims)
+				}
+				c.Next()
+			}, limiter.Handler(tt.rule), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/login", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+
+			var gotKey string
+			if len(redis.keys) > 0 {
+				gotKey = redis.keys[0]
+			}
+			if gotKey != tt.wantKey {
+				t.Errorf("rate limit key = %q, want %q", gotKey, tt.wantKey)
+			}
+		})
+	}
+}
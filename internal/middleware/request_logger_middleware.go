@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"authentication/internal/utils"
+	"authentication/internal/utils/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// RequestLoggerMiddleware defines the contract for request logging middleware
+type RequestLoggerMiddleware interface {
+	Handler() gin.HandlerFunc
+}
+
+// requestLoggerMiddleware is the struct that implements RequestLoggerMiddleware
+type requestLoggerMiddleware struct{}
+
+// NewRequestLoggerMiddleware initializes request logging middleware
+func NewRequestLoggerMiddleware() RequestLoggerMiddleware {
+	return requestLoggerMiddleware{}
+}
+
+// Handler gives every request an ID, taken from the X-Request-ID header when the caller sent a
+// usable one, returns it in the response and logs the request once it is served. The request
+// context carries a logger tagged with the ID. Query strings are never logged, they can hold
+// codes and tokens.
+func (m requestLoggerMiddleware) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(utils.RequestIDHeader)
+		if len(requestID) > utils.RequestIDMaxLength || !requestIDPattern.MatchString(requestID) {
+			requestID = uuid.New().String()
+		}
+		c.Set(utils.RequestID, requestID)
+		c.Header(utils.RequestIDHeader, requestID)
+
+		requestLogger := logger.With().Str(logger.RequestIDField, requestID).Logger()
+		c.Request = c.Request.WithContext(requestLogger.WithContext(c.Request.Context()))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		event := requestLogger.Info()
+		if status >= http.StatusInternalServerError {
+			event = requestLogger.Error()
+		} else if status >= http.StatusBadRequest {
+			event = requestLogger.Warn()
+		}
+		if token, exist := utils.ExtractTokenClaims(c); exist {
+			event = event.Str(utils.ClientID, token.ClientID)
+			if token.Actor != nil {
+				event = event.Str("actor", token.Actor.Sub)
+			}
+		}
+		if claims, exist := utils.ExtractInternalClaims(c); exist {
+			event = event.Str("service", claims.Service)
+		}
+		if len(c.Errors) > 0 {
+			event = event.Str("errors", c.Errors.String())
+		}
+
+		event.Str("method", c.Request.Method).
+			Str("path", c.Request.URL.Path).
+			Str("route", c.FullPath()).
+			Int("status", status).
+			Dur("latency", time.Since(start)).
+			Str("client_ip", c.ClientIP()).
+			Str("user_agent", c.Request.UserAgent()).
+			Int("size", c.Writer.Size()).
+			Msg("Request served")
+	}
+}
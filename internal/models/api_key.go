@@ -0,0 +1,25 @@
+package models
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// ApiKey is a long-lived personal key. Prefix identifies the key, KeyHash is the hash of the
+// whole key and Scopes are resource names.
+type ApiKey struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	UserID     uint           `gorm:"not null" json:"user_id"`
+	Name       string         `gorm:"not null" json:"name"`
+	Prefix     string         `gorm:"unique;not null" json:"prefix"`
+	KeyHash    string         `gorm:"not null" json:"-"`
+	Scopes     pq.StringArray `gorm:"type:text[]" json:"scopes"`
+	ExpiresAt  *time.Time     `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time     `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time     `json:"revoked_at,omitempty"`
+	RevokedBy  string         `json:"revoked_by,omitempty"`
+	CreatedAt  time.Time      `gorm:"autoCreateTime" json:"created_at,omitempty"`
+	CreatedBy  string         `json:"created_by,omitempty"`
+	UpdatedAt  time.Time      `gorm:"autoUpdateTime" json:"updated_at,omitempty"`
+	UpdatedBy  string         `json:"updated_by,omitempty"`
+}
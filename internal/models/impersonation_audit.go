@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ImpersonationAudit records a token exchange by an admin and every write made with the issued token
+type ImpersonationAudit struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	AccessUUID    string    `gorm:"column:access_uuid;not null;index" json:"access_uuid"`
+	ActorUserID   uint      `gorm:"not null;index" json:"actor_user_id"`
+	ActorClientID string    `gorm:"not null" json:"actor_client_id"`
+	SubjectUserID uint      `gorm:"not null;index" json:"subject_user_id"`
+	Action        string    `gorm:"not null" json:"action"`
+	Method        string    `json:"method,omitempty"`
+	Path          string    `json:"path,omitempty"`
+	Status        int       `json:"status,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+	IPAddress     string    `json:"ip_address"`
+	UserAgent     string    `gorm:"type:text" json:"user_agent"`
+	CreatedAt     time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
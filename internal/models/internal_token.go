@@ -5,11 +5,15 @@ import (
 	"time"
 )
 
+// InternalToken is a long-lived token a resource's service calls us with. Only the hash of the
+// token is stored, Expired marks a revoked token.
 type InternalToken struct {
 	ID         uint           `gorm:"primaryKey" json:"id,omitempty"`
 	ResourceID uint           `gorm:"not null" json:"resource_id,omitempty"`
-	Token      string         `gorm:"not null" json:"token,omitempty"`
+	TokenID    string         `gorm:"unique" json:"token_id,omitempty"`
+	Token      string         `gorm:"not null" json:"-"`
 	Expired    bool           `gorm:"not null" json:"expired,omitempty"`
+	ExpiresAt  time.Time      `json:"expires_at,omitempty"`
 	CreatedAt  time.Time      `gorm:"autoCreateTime" json:"created_at,omitempty"`
 	CreatedBy  string         `json:"created_by,omitempty"`
 	UpdatedAt  time.Time      `gorm:"autoUpdateTime" json:"updated_at,omitempty"`
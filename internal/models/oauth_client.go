@@ -0,0 +1,50 @@
+package models
+
+import (
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"time"
+)
+
+type OauthClient struct {
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	ClientID     string         `gorm:"unique;not null" json:"client_id"`
+	ClientSecret *string        `json:"-"`
+	Name         string         `gorm:"not null" json:"name"`
+	RedirectURIs pq.StringArray `gorm:"column:redirect_uris;type:text[]" json:"redirect_uris"`
+	GrantTypes   pq.StringArray `gorm:"type:text[]" json:"grant_types"`
+	Scopes       pq.StringArray `gorm:"type:text[]" json:"scopes"`
+	IsPublic     bool           `gorm:"default:false" json:"is_public"`
+	IsActive     bool           `gorm:"default:true" json:"is_active"`
+	CreatedAt    time.Time      `gorm:"autoCreateTime" json:"created_at,omitempty"`
+	CreatedBy    string         `json:"created_by,omitempty"`
+	UpdatedAt    time.Time      `gorm:"autoUpdateTime" json:"updated_at,omitempty"`
+	UpdatedBy    string         `json:"updated_by,omitempty"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	DeletedBy    string         `json:"deleted_by,omitempty"`
+}
+
+// OauthAuthorizationCode is kept in Redis between /oauth/authorize and /oauth/token
+type OauthAuthorizationCode struct {
+	ClientID            string `json:"client_id"`
+	UserID              uint   `json:"user_id"`
+	RedirectURI         string `json:"redirect_uri"` // as sent to /oauth/authorize, empty when the registered default was used
+	Scope               string `json:"scope"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+// OauthRefreshGrant binds a refresh token issued at /oauth/token to its client
+type OauthRefreshGrant struct {
+	ClientID string `json:"client_id"`
+	UserID   uint   `json:"user_id"`
+	Scope    string `json:"scope"`
+}
+
+// OauthBrowserSession is the login behind the oauth_session cookie, used while a user authorizes clients
+type OauthBrowserSession struct {
+	UserID    uint   `json:"user_id"`
+	ClientID  string `json:"client_id"`
+	CSRFToken string `json:"csrf_token"`
+	IssuedAt  int64  `json:"issued_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordHistory keeps the hash of every password a user has set, so none of the recent ones
+// can be set again
+type PasswordHistory struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	Password  string    `gorm:"not null" json:"-"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken is one link in a refresh token family. Every refresh retires the presented
+// token and issues its successor in the same family.
+type RefreshToken struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Token         string     `gorm:"unique;not null" json:"-"` // HMAC of the token handed to the client
+	TokenHashed   bool       `gorm:"default:false" json:"-"`
+	FamilyID      string     `gorm:"not null;index" json:"family_id"`
+	ParentID      *uint      `json:"parent_id,omitempty"`
+	UserID        uint       `gorm:"not null;index" json:"user_id"`
+	Status        string     `gorm:"not null" json:"status"`
+	ExpiresAt     time.Time  `gorm:"not null" json:"expires_at"`
+	RotatedAt     *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason string     `json:"revoked_reason,omitempty"`
+	IPAddress     string     `json:"ip_address"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at,omitempty"`
+	CreatedBy     string     `json:"created_by,omitempty"`
+	UpdatedAt     time.Time  `gorm:"autoUpdateTime" json:"updated_at,omitempty"`
+	UpdatedBy     string     `json:"updated_by,omitempty"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+type SecurityEvent struct {
+	EventType  string            `json:"event_type"`
+	UserID     uint              `json:"user_id"`
+	ClientID   string            `json:"client_id"`
+	IPAddress  string            `json:"ip_address"`
+	Details    map[string]string `json:"details,omitempty"`
+	OccurredAt time.Time         `json:"occurred_at"`
+}
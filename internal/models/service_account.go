@@ -0,0 +1,27 @@
+package models
+
+import (
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ServiceAccount is a machine identity for backend jobs. Resources are the resource names its
+// tokens may be scoped to, TokenTTL is in seconds.
+type ServiceAccount struct {
+	ID                      uint           `gorm:"primaryKey" json:"id"`
+	ClientID                string         `gorm:"unique;not null" json:"client_id"`
+	ClientSecret            string         `gorm:"not null" json:"-"`
+	PreviousClientSecret    *string        `json:"-"`
+	PreviousSecretExpiresAt *time.Time     `json:"previous_secret_expires_at,omitempty"`
+	Name                    string         `gorm:"not null" json:"name"`
+	Resources               pq.StringArray `gorm:"type:text[]" json:"resources"`
+	TokenTTL                int64          `gorm:"column:token_ttl;not null" json:"token_ttl"`
+	IsActive                bool           `gorm:"default:true" json:"is_active"`
+	CreatedAt               time.Time      `gorm:"autoCreateTime" json:"created_at,omitempty"`
+	CreatedBy               string         `json:"created_by,omitempty"`
+	UpdatedAt               time.Time      `gorm:"autoUpdateTime" json:"updated_at,omitempty"`
+	UpdatedBy               string         `json:"updated_by,omitempty"`
+	DeletedAt               gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	DeletedBy               string         `json:"deleted_by,omitempty"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+type SigningKey struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	KeyID       string     `gorm:"column:kid;unique;not null" json:"kid"`
+	Algorithm   string     `gorm:"not null" json:"algorithm"`
+	PrivateKey  string     `gorm:"not null" json:"-"` // encrypted PKCS#8 PEM
+	Status      string     `gorm:"not null;index" json:"status"`
+	ActivatedAt *time.Time `json:"activated_at,omitempty"`
+	RetiredAt   *time.Time `json:"retired_at,omitempty"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at,omitempty"`
+	CreatedBy   string     `json:"created_by,omitempty"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime" json:"updated_at,omitempty"`
+	UpdatedBy   string     `json:"updated_by,omitempty"`
+}
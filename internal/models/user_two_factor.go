@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+type UserTwoFactor struct {
+	UserID       uint       `gorm:"primaryKey" json:"user_id"`
+	TotpSecret   string     `gorm:"not null" json:"-"`
+	IsEnabled    bool       `gorm:"default:false" json:"is_enabled"`
+	ConfirmedAt  *time.Time `json:"confirmed_at,omitempty"`
+	LastUsedStep int64      `gorm:"default:0" json:"-"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"created_at,omitempty"`
+	CreatedBy    string     `json:"created_by,omitempty"`
+	UpdatedAt    time.Time  `gorm:"autoUpdateTime" json:"updated_at,omitempty"`
+	UpdatedBy    string     `json:"updated_by,omitempty"`
+}
+
+type TwoFactorChallenge struct {
+	ChallengeID string `json:"challenge_id"`
+	ClientID    string `json:"client_id"`
+	Method      string `json:"method"`
+	DeviceType  string `json:"device_type"`
+	DeviceID    string `json:"device_id,omitempty"`
+	Attempts    int    `json:"attempts"`
+}
@@ -6,28 +6,32 @@ import (
 )
 
 type Users struct {
-	UserID         uint           `gorm:"primaryKey" json:"user_id,omitempty"`
-	ClientID       string         `gorm:"unique;not null" json:"client_id,omitempty"`
-	Username       string         `gorm:"unique;not null" json:"username,omitempty"`
-	Email          string         `gorm:"unique;not null" json:"email,omitempty"`
-	Password       string         `gorm:"not null" json:"-"`
-	PinCode        *string        `gorm:"not null" json:"-"`
-	PinAttempts    int            `gorm:"default:0" json:"-"`
-	PinLastUpdated time.Time      `json:"-"`
-	FirstName      string         `json:"first_name,omitempty"`
-	LastName       string         `json:"last_name,omitempty"`
-	FullName       string         `json:"full_name,omitempty"`
-	PhoneNumber    string         `gorm:"unique" json:"phone_number,omitempty"`
-	ProfilePicture *string        `json:"profile_picture,omitempty"`
-	RoleID         uint           `gorm:"not null" json:"role_id,omitempty"`
-	DeviceID       *string        `json:"device_id,omitempty"`
-	DeviceToken    *string        `json:"device_token,omitempty"`
-	CreatedAt      time.Time      `gorm:"autoCreateTime" json:"created_at,omitempty"`
-	CreatedBy      string         `json:"created_by,omitempty"`
-	UpdatedAt      time.Time      `gorm:"autoUpdateTime" json:"updated_at,omitempty"`
-	UpdatedBy      string         `json:"updated_by,omitempty"`
-	DeletedAt      gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
-	DeletedBy      string         `json:"deleted_by,omitempty"`
+	UserID           uint           `gorm:"primaryKey" json:"user_id,omitempty"`
+	ClientID         string         `gorm:"unique;not null" json:"client_id,omitempty"`
+	Username         string         `gorm:"unique;not null" json:"username,omitempty"`
+	Email            string         `gorm:"not null" json:"email,omitempty"`
+	EmailIndex       *string        `gorm:"unique" json:"-"`
+	Password         string         `gorm:"not null" json:"-"`
+	PinCode          *string        `gorm:"not null" json:"-"`
+	PinAttempts      int            `gorm:"default:0" json:"-"`
+	PinLastUpdated   time.Time      `json:"-"`
+	PinLockCount     int            `gorm:"default:0" json:"-"`
+	LockedUntil      *time.Time     `json:"-"`
+	FirstName        string         `json:"first_name,omitempty"`
+	LastName         string         `json:"last_name,omitempty"`
+	FullName         string         `json:"full_name,omitempty"`
+	PhoneNumber      string         `json:"phone_number,omitempty"`
+	PhoneNumberIndex *string        `gorm:"unique" json:"-"`
+	ProfilePicture   *string        `json:"profile_picture,omitempty"`
+	RoleID           uint           `gorm:"not null" json:"role_id,omitempty"`
+	DeviceID         *string        `json:"device_id,omitempty"`
+	DeviceToken      *string        `json:"device_token,omitempty"`
+	CreatedAt        time.Time      `gorm:"autoCreateTime" json:"created_at,omitempty"`
+	CreatedBy        string         `json:"created_by,omitempty"`
+	UpdatedAt        time.Time      `gorm:"autoUpdateTime" json:"updated_at,omitempty"`
+	UpdatedBy        string         `json:"updated_by,omitempty"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	DeletedBy        string         `json:"deleted_by,omitempty"`
 }
 
 type TokenDetails struct {
@@ -10,12 +10,15 @@ type UserSession struct {
 	UserID        uint           `gorm:"not null;index" json:"user_id"` // Foreign key reference to users table
 	SessionToken  string         `gorm:"unique;not null" json:"session_token"`
 	RefreshToken  string         `gorm:"unique" json:"refresh_token"`
+	AccessUUID    string         `gorm:"column:access_uuid;index" json:"access_uuid"`
 	IPAddress     string         `json:"ip_address"`
 	UserAgent     string         `gorm:"type:text" json:"user_agent"`
 	LoginTime     time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"login_time"`
+	LastSeenAt    *time.Time     `json:"last_seen_at"`
 	ExpiresAt     time.Time      `gorm:"not null" json:"expires_at"`
 	LogoutTime    *time.Time     `gorm:"null" json:"logout_time"` // Nullable field
 	IsActive      bool           `gorm:"default:true" json:"is_active"`
+	TokensHashed  bool           `gorm:"default:false" json:"-"` // false for rows written before tokens were stored as HMACs
 	CreatedAt     time.Time      `gorm:"autoCreateTime" json:"created_at,omitempty"`
 	CreatedBy     string         `json:"created_by,omitempty"`
 	UpdatedAt     time.Time      `gorm:"autoUpdateTime" json:"updated_at,omitempty"`
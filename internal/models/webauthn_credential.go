@@ -0,0 +1,27 @@
+package models
+
+import (
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"time"
+)
+
+type WebauthnCredential struct {
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	UserID          uint           `gorm:"not null;index" json:"user_id"`
+	CredentialID    string         `gorm:"unique;not null" json:"credential_id"`
+	PublicKey       []byte         `gorm:"not null" json:"-"`
+	AttestationType string         `json:"attestation_type,omitempty"`
+	Transports      pq.StringArray `gorm:"type:text[]" json:"transports,omitempty"`
+	AAGUID          []byte         `gorm:"column:aaguid" json:"-"`
+	SignCount       int64          `gorm:"default:0" json:"-"`
+	Flags           int            `gorm:"default:0" json:"-"`
+	Name            string         `json:"name,omitempty"`
+	LastUsedAt      *time.Time     `json:"last_used_at,omitempty"`
+	CreatedAt       time.Time      `gorm:"autoCreateTime" json:"created_at,omitempty"`
+	CreatedBy       string         `json:"created_by,omitempty"`
+	UpdatedAt       time.Time      `gorm:"autoUpdateTime" json:"updated_at,omitempty"`
+	UpdatedBy       string         `json:"updated_by,omitempty"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	DeletedBy       string         `json:"deleted_by,omitempty"`
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"authentication/internal/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+type ApiKeyRepository interface {
+	AddApiKey(apiKey *models.ApiKey) error
+	GetApiKeyByID(id uint) (*models.ApiKey, error)
+	GetApiKeyByPrefix(prefix string) (*models.ApiKey, error)
+	GetApiKeysByUserID(userID uint) (*[]models.ApiKey, error)
+	CountActiveApiKeysByUserID(userID uint) (int64, error)
+	UpdateApiKey(apiKey *models.ApiKey) error
+	UpdateLastUsedAt(id uint, lastUsedAt time.Time) error
+	RevokeApiKeysByUserID(userID uint, revokedBy string, revokedAt time.Time) error
+}
+
+type apiKeyRepository struct {
+	db gorm.DB
+}
+
+func NewApiKeyRepository(db gorm.DB) ApiKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r apiKeyRepository) AddApiKey(apiKey *models.ApiKey) error {
+	if err := r.db.Create(apiKey).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r apiKeyRepository) GetApiKeyByID(id uint) (*models.ApiKey, error) {
+	var apiKey models.ApiKey
+	if err := r.db.First(&apiKey, id).Error; err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+func (r apiKeyRepository) GetApiKeyByPrefix(prefix string) (*models.ApiKey, error) {
+	var apiKey models.ApiKey
+	if err := r.db.Where("prefix = ?", prefix).First(&apiKey).Error; err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+func (r apiKeyRepository) GetApiKeysByUserID(userID uint) (*[]models.ApiKey, error) {
+	var apiKeys []models.ApiKey
+	if err := r.db.Where("user_id = ?", userID).Order("id ASC").Find(&apiKeys).Error; err != nil {
+		return nil, err
+	}
+	return &apiKeys, nil
+}
+
+func (r apiKeyRepository) CountActiveApiKeysByUserID(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.ApiKey{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now()).
+		Count(&count).Error
+	return count, err
+}
+
+func (r apiKeyRepository) UpdateApiKey(apiKey *models.ApiKey) error {
+	if err := r.db.Save(apiKey).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r apiKeyRepository) UpdateLastUsedAt(id uint, lastUsedAt time.Time) error {
+	return r.db.Model(&models.ApiKey{}).Where("id = ?", id).UpdateColumn("last_used_at", lastUsedAt).Error
+}
+
+// RevokeApiKeysByUserID revokes every key of the user that is not revoked yet
+func (r apiKeyRepository) RevokeApiKeysByUserID(userID uint, revokedBy string, revokedAt time.Time) error {
+	return r.db.Model(&models.ApiKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{"revoked_at": revokedAt, "revoked_by": revokedBy, "updated_by": revokedBy}).Error
+}
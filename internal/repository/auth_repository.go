@@ -7,7 +7,6 @@ import (
 
 type AuthRepository interface {
 	UpdatePinCode(user *models.Users) error
-	CreateInternalToken(resourceID uint, token string) error
 }
 
 type authRepository struct {
@@ -21,13 +20,3 @@ func NewAuthRepository(db gorm.DB) AuthRepository {
 func (r authRepository) UpdatePinCode(user *models.Users) error {
 	return r.db.Save(user).Error
 }
-
-func (r authRepository) CreateInternalToken(resourceID uint, token string) error {
-	if err := r.db.Create(&models.InternalToken{
-		ResourceID: resourceID,
-		Token:      token,
-	}).Error; err != nil {
-		return err
-	}
-	return nil
-}
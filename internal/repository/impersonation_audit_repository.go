@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/models"
+	"gorm.io/gorm"
+)
+
+type ImpersonationAuditRepository interface {
+	AddImpersonationAudit(audit *models.ImpersonationAudit) error
+	GetImpersonationAuditsByFilter(filter in.ImpersonationAuditFilter, index, size int) (*[]models.ImpersonationAudit, int64, error)
+}
+
+type impersonationAuditRepository struct {
+	db gorm.DB
+}
+
+func NewImpersonationAuditRepository(db gorm.DB) ImpersonationAuditRepository {
+	return &impersonationAuditRepository{db: db}
+}
+
+func (r impersonationAuditRepository) AddImpersonationAudit(audit *models.ImpersonationAudit) error {
+	if err := r.db.Create(audit).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r impersonationAuditRepository) GetImpersonationAuditsByFilter(filter in.ImpersonationAuditFilter, index, size int) (*[]models.ImpersonationAudit, int64, error) {
+	query := r.db.Model(&models.ImpersonationAudit{})
+	if filter.ActorUserID != 0 {
+		query = query.Where("actor_user_id = ?", filter.ActorUserID)
+	}
+	if filter.SubjectUserID != 0 {
+		query = query.Where("subject_user_id = ?", filter.SubjectUserID)
+	}
+	if filter.AccessUUID != "" {
+		query = query.Where("access_uuid = ?", filter.AccessUUID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		// the end date is inclusive
+		query = query.Where("created_at < ?", filter.To.AddDate(0, 0, 1))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var audits []models.ImpersonationAudit
+	err := query.Order("created_at DESC").
+		Limit(size).Offset((index - 1) * size).
+		Find(&audits).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return &audits, total, nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"authentication/internal/models"
+	"gorm.io/gorm"
+)
+
+type InternalTokenRepository interface {
+	AddInternalToken(token *models.InternalToken) error
+	GetInternalTokenByID(id uint) (*models.InternalToken, error)
+	GetInternalTokenByTokenID(tokenID string) (*models.InternalToken, error)
+	GetInternalTokensByResourceID(resourceID uint) (*[]models.InternalToken, error)
+	UpdateInternalToken(token *models.InternalToken) error
+}
+
+type internalTokenRepository struct {
+	db gorm.DB
+}
+
+func NewInternalTokenRepository(db gorm.DB) InternalTokenRepository {
+	return &internalTokenRepository{db: db}
+}
+
+func (r internalTokenRepository) AddInternalToken(token *models.InternalToken) error {
+	if err := r.db.Create(token).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r internalTokenRepository) GetInternalTokenByID(id uint) (*models.InternalToken, error) {
+	var token models.InternalToken
+	if err := r.db.First(&token, id).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r internalTokenRepository) GetInternalTokenByTokenID(tokenID string) (*models.InternalToken, error) {
+	var token models.InternalToken
+	if err := r.db.Where("token_id = ?", tokenID).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r internalTokenRepository) GetInternalTokensByResourceID(resourceID uint) (*[]models.InternalToken, error) {
+	var tokens []models.InternalToken
+	if err := r.db.Where("resource_id = ?", resourceID).Order("id ASC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return &tokens, nil
+}
+
+func (r internalTokenRepository) UpdateInternalToken(token *models.InternalToken) error {
+	if err := r.db.Save(token).Error; err != nil {
+		return err
+	}
+	return nil
+}
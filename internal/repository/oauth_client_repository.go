@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"authentication/internal/models"
+	"gorm.io/gorm"
+)
+
+type OauthClientRepository interface {
+	AddClient(client *models.OauthClient) error
+	GetClientByID(id uint) (*models.OauthClient, error)
+	GetClientByClientID(clientID string) (*models.OauthClient, error)
+	GetClients() (*[]models.OauthClient, error)
+	UpdateClient(client *models.OauthClient) error
+	DeleteClient(client *models.OauthClient) error
+}
+
+type oauthClientRepository struct {
+	db gorm.DB
+}
+
+func NewOauthClientRepository(db gorm.DB) OauthClientRepository {
+	return &oauthClientRepository{db: db}
+}
+
+func (r oauthClientRepository) AddClient(client *models.OauthClient) error {
+	if err := r.db.Create(client).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r oauthClientRepository) GetClientByID(id uint) (*models.OauthClient, error) {
+	var client models.OauthClient
+	if err := r.db.First(&client, id).Error; err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (r oauthClientRepository) GetClientByClientID(clientID string) (*models.OauthClient, error) {
+	var client models.OauthClient
+	if err := r.db.Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (r oauthClientRepository) GetClients() (*[]models.OauthClient, error) {
+	var clients []models.OauthClient
+	if err := r.db.Order("id ASC").Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	return &clients, nil
+}
+
+func (r oauthClientRepository) UpdateClient(client *models.OauthClient) error {
+	if err := r.db.Save(client).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r oauthClientRepository) DeleteClient(client *models.OauthClient) error {
+	if err := r.db.Model(&client).
+		Update("deleted_by", client.DeletedBy).
+		Delete(&client).Error; err != nil {
+		return err
+	}
+	return nil
+}
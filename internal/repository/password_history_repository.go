@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"authentication/internal/models"
+	"authentication/internal/utils"
+	"gorm.io/gorm"
+)
+
+type PasswordHistoryRepository interface {
+	AddPasswordHistory(history *models.PasswordHistory) error
+	GetPasswordHistory(userID uint, limit int) (*[]models.PasswordHistory, error)
+	PrunePasswordHistory(userID uint, keep int) error
+}
+
+type passwordHistoryRepository struct {
+	db gorm.DB
+}
+
+func NewPasswordHistoryRepository(db gorm.DB) PasswordHistoryRepository {
+	return &passwordHistoryRepository{db: db}
+}
+
+func (r passwordHistoryRepository) AddPasswordHistory(history *models.PasswordHistory) error {
+	if err := r.db.Table(utils.TablePasswordHistoryName).Create(history).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetPasswordHistory returns the user's most recent passwords, newest first
+func (r passwordHistoryRepository) GetPasswordHistory(userID uint, limit int) (*[]models.PasswordHistory, error) {
+	var history []models.PasswordHistory
+	if err := r.db.Table(utils.TablePasswordHistoryName).
+		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&history).Error; err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// PrunePasswordHistory deletes everything but the user's newest passwords
+func (r passwordHistoryRepository) PrunePasswordHistory(userID uint, keep int) error {
+	newest := r.db.Table(utils.TablePasswordHistoryName).
+		Select("id").
+		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Limit(keep)
+
+	return r.db.Table(utils.TablePasswordHistoryName).
+		Where("user_id = ? AND id NOT IN (?)", userID, newest).
+		Delete(&models.PasswordHistory{}).Error
+}
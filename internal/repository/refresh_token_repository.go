@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"authentication/internal/models"
+	"authentication/internal/utils"
+	"gorm.io/gorm"
+	"time"
+)
+
+type RefreshTokenRepository interface {
+	AddRefreshToken(token *models.RefreshToken) error
+	GetRefreshTokenByToken(token string) (*models.RefreshToken, error)
+	GetActiveRefreshTokenByFamilyID(familyID string) (*models.RefreshToken, error)
+	MarkRefreshTokenRotated(id uint, updatedBy string) (bool, error)
+	RevokeRefreshTokenFamily(familyID, reason, updatedBy string) error
+	RevokeRefreshTokensByUserID(userID uint, reason, updatedBy string) error
+	GetRefreshTokensWithRawToken(limit int) (*[]models.RefreshToken, error)
+	UpdateRefreshToken(token *models.RefreshToken) error
+}
+
+type refreshTokenRepository struct {
+	db gorm.DB
+}
+
+func NewRefreshTokenRepository(db gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+func (r refreshTokenRepository) AddRefreshToken(token *models.RefreshToken) error {
+	if err := r.db.Create(token).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r refreshTokenRepository) GetRefreshTokenByToken(token string) (*models.RefreshToken, error) {
+	var refreshToken models.RefreshToken
+	if err := r.db.Where("token = ?", token).First(&refreshToken).Error; err != nil {
+		return nil, err
+	}
+	return &refreshToken, nil
+}
+
+func (r refreshTokenRepository) GetActiveRefreshTokenByFamilyID(familyID string) (*models.RefreshToken, error) {
+	var refreshToken models.RefreshToken
+	if err := r.db.Where("family_id = ? AND status = ?", familyID, utils.RefreshTokenActive).First(&refreshToken).Error; err != nil {
+		return nil, err
+	}
+	return &refreshToken, nil
+}
+
+// MarkRefreshTokenRotated retires an active token. It reports false when the token was no longer
+// active, so two concurrent refreshes with the same token cannot both succeed.
+func (r refreshTokenRepository) MarkRefreshTokenRotated(id uint, updatedBy string) (bool, error) {
+	now := time.Now()
+	result := r.db.Model(&models.RefreshToken{}).
+		Where("id = ? AND status = ?", id, utils.RefreshTokenActive).
+		Updates(map[string]interface{}{"status": utils.RefreshTokenRotated, "rotated_at": now, "updated_by": updatedBy})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r refreshTokenRepository) RevokeRefreshTokenFamily(familyID, reason, updatedBy string) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND status <> ?", familyID, utils.RefreshTokenRevoked).
+		Updates(map[string]interface{}{"status": utils.RefreshTokenRevoked, "revoked_at": time.Now(), "revoked_reason": reason, "updated_by": updatedBy}).Error
+}
+
+func (r refreshTokenRepository) RevokeRefreshTokensByUserID(userID uint, reason, updatedBy string) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND status <> ?", userID, utils.RefreshTokenRevoked).
+		Updates(map[string]interface{}{"status": utils.RefreshTokenRevoked, "revoked_at": time.Now(), "revoked_reason": reason, "updated_by": updatedBy}).Error
+}
+
+func (r refreshTokenRepository) GetRefreshTokensWithRawToken(limit int) (*[]models.RefreshToken, error) {
+	var tokens []models.RefreshToken
+	if err := r.db.Where("token_hashed = ?", false).Limit(limit).Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return &tokens, nil
+}
+
+func (r refreshTokenRepository) UpdateRefreshToken(token *models.RefreshToken) error {
+	if err := r.db.Save(token).Error; err != nil {
+		return err
+	}
+	return nil
+}
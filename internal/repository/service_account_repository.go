@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"authentication/internal/models"
+	"gorm.io/gorm"
+)
+
+type ServiceAccountRepository interface {
+	AddServiceAccount(account *models.ServiceAccount) error
+	GetServiceAccountByID(id uint) (*models.ServiceAccount, error)
+	GetServiceAccountByClientID(clientID string) (*models.ServiceAccount, error)
+	GetServiceAccounts() (*[]models.ServiceAccount, error)
+	UpdateServiceAccount(account *models.ServiceAccount) error
+	DeleteServiceAccount(account *models.ServiceAccount) error
+}
+
+type serviceAccountRepository struct {
+	db gorm.DB
+}
+
+func NewServiceAccountRepository(db gorm.DB) ServiceAccountRepository {
+	return &serviceAccountRepository{db: db}
+}
+
+func (r serviceAccountRepository) AddServiceAccount(account *models.ServiceAccount) error {
+	if err := r.db.Create(account).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r serviceAccountRepository) GetServiceAccountByID(id uint) (*models.ServiceAccount, error) {
+	var account models.ServiceAccount
+	if err := r.db.First(&account, id).Error; err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r serviceAccountRepository) GetServiceAccountByClientID(clientID string) (*models.ServiceAccount, error) {
+	var account models.ServiceAccount
+	if err := r.db.Where("client_id = ?", clientID).First(&account).Error; err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r serviceAccountRepository) GetServiceAccounts() (*[]models.ServiceAccount, error) {
+	var accounts []models.ServiceAccount
+	if err := r.db.Order("id ASC").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	return &accounts, nil
+}
+
+func (r serviceAccountRepository) UpdateServiceAccount(account *models.ServiceAccount) error {
+	if err := r.db.Save(account).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r serviceAccountRepository) DeleteServiceAccount(account *models.ServiceAccount) error {
+	if err := r.db.Model(&account).
+		Update("deleted_by", account.DeletedBy).
+		Delete(&account).Error; err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"authentication/internal/models"
+	"gorm.io/gorm"
+)
+
+type SigningKeyRepository interface {
+	AddSigningKey(key *models.SigningKey) error
+	GetSigningKeysByStatus(statuses ...string) (*[]models.SigningKey, error)
+	GetSigningKeyByKeyID(kid string) (*models.SigningKey, error)
+	UpdateSigningKey(key *models.SigningKey) error
+}
+
+type signingKeyRepository struct {
+	db gorm.DB
+}
+
+func NewSigningKeyRepository(db gorm.DB) SigningKeyRepository {
+	return &signingKeyRepository{db: db}
+}
+
+func (r signingKeyRepository) AddSigningKey(key *models.SigningKey) error {
+	if err := r.db.Create(key).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r signingKeyRepository) GetSigningKeysByStatus(statuses ...string) (*[]models.SigningKey, error) {
+	var keys []models.SigningKey
+	if err := r.db.Where("status IN ?", statuses).Order("created_at ASC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return &keys, nil
+}
+
+func (r signingKeyRepository) GetSigningKeyByKeyID(kid string) (*models.SigningKey, error) {
+	var key models.SigningKey
+	if err := r.db.Where("kid = ?", kid).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r signingKeyRepository) UpdateSigningKey(key *models.SigningKey) error {
+	if err := r.db.Save(key).Error; err != nil {
+		return err
+	}
+	return nil
+}
@@ -7,13 +7,15 @@ import (
 	"errors"
 	"github.com/lib/pq"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 type UserRepository interface {
 	RegisterUser(user **models.Users) error
 	CheckClientID(clientID string) bool
 	GetUserByUsername(username string) (*models.Users, error)
-	GetUserByEmail(email string) (*models.Users, error)
+	GetUserByEmail(emailIndex string) (*models.Users, error)
 	GetUserByID(id uint) (*models.Users, error)
 	UpdateUser(user *models.Users) error
 	DeleteUser(user *models.Users) error
@@ -21,7 +23,7 @@ type UserRepository interface {
 	GetUsers() (*[]models.Users, error)
 	GetUserByRole(role uint) (*[]models.Users, error)
 	GetUserByRolePagination(role uint, index, size int) (*[]out.UserRoleResponse, error)
-	GetUserByPhoneNumber(number string) (*models.Users, error)
+	GetUserByPhoneNumber(phoneNumberIndex string) (*models.Users, error)
 	GetUserByClientID(clientID string) (*models.Users, error)
 	GetUserByPinCodeAndClientID(pinCode, clientID string) (*models.Users, error)
 	GetUserByClientAndRole(clientID, roleID uint) (*[]models.Users, error)
@@ -33,8 +35,16 @@ type UserRepository interface {
 	GetListUserByUserIDResponse(userID uint) (*[]out.UserRoleResourceSettingResponse, error)
 	GetUserByResourceID(resourceID uint) (*[]models.Users, error)
 	ChangePassword(user *models.Users) error
-	UpdatePinAttempts(clientID string) error
+	UpdatePinAttempts(clientID string) (int, error)
 	ResetPinAttempts(user *models.Users) error
+	LockPinCode(userID uint, lockedUntil time.Time) error
+	UnlockPinCode(userID uint, updatedBy string) error
+	UpdatePasswordHash(userID uint, hash string) error
+	UpdatePinHash(userID uint, hash string) error
+	GetUsersToReencrypt(keyPrefix string, limit int) (*[]models.Users, error)
+	UpdateUserPII(user *models.Users) error
+	GetUsersWithoutBlindIndex(afterUserID uint, limit int) (*[]models.Users, error)
+	UpdateBlindIndexes(user *models.Users) error
 	GetAllUsersByResourceId(resources *models.Resource) (*[]models.Users, error)
 	GetUserRedisByClientID(clientID string) (*models.UserRedis, error)
 	SaveUserKey(keys *models.UserKey) error
@@ -75,9 +85,10 @@ func (r userRepository) GetUserByUsername(username string) (*models.Users, error
 	return &user, err
 }
 
-func (r userRepository) GetUserByEmail(email string) (*models.Users, error) {
+// GetUserByEmail looks a user up by the blind index of their email address
+func (r userRepository) GetUserByEmail(emailIndex string) (*models.Users, error) {
 	var user models.Users
-	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := r.db.Where("email_index = ?", emailIndex).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
@@ -123,9 +134,10 @@ func (r userRepository) GetUsers() (*[]models.Users, error) {
 	return &users, nil
 }
 
-func (r userRepository) GetUserByPhoneNumber(number string) (*models.Users, error) {
+// GetUserByPhoneNumber looks a user up by the blind index of their phone number
+func (r userRepository) GetUserByPhoneNumber(phoneNumberIndex string) (*models.Users, error) {
 	var user models.Users
-	if err := r.db.Where("phone_number = ?", number).Find(&user).Error; err != nil {
+	if err := r.db.Where("phone_number_index = ?", phoneNumberIndex).Find(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
@@ -421,14 +433,17 @@ func (r userRepository) ChangePassword(user *models.Users) error {
 	return nil
 }
 
-func (r userRepository) UpdatePinAttempts(clientID string) error {
-	if err := r.db.Model(&models.Users{}).
+// UpdatePinAttempts counts a wrong PIN and returns the attempts made since the last reset
+func (r userRepository) UpdatePinAttempts(clientID string) (int, error) {
+	var user models.Users
+	if err := r.db.Model(&user).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "pin_attempts"}}}).
 		Where("client_id = ?", clientID).
 		Update("pin_attempts", gorm.Expr("pin_attempts + 1")).
 		Error; err != nil {
-		return err
+		return 0, err
 	}
-	return nil
+	return user.PinAttempts, nil
 }
 
 func (r userRepository) ResetPinAttempts(user *models.Users) error {
@@ -440,6 +455,90 @@ func (r userRepository) ResetPinAttempts(user *models.Users) error {
 	return nil
 }
 
+// LockPinCode locks the PIN until the given time and starts a new round of attempts
+func (r userRepository) LockPinCode(userID uint, lockedUntil time.Time) error {
+	return r.db.Model(&models.Users{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]interface{}{
+			"pin_attempts":   0,
+			"pin_lock_count": gorm.Expr("pin_lock_count + 1"),
+			"locked_until":   lockedUntil,
+		}).Error
+}
+
+// UnlockPinCode clears the lock together with its escalation
+func (r userRepository) UnlockPinCode(userID uint, updatedBy string) error {
+	return r.db.Model(&models.Users{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]interface{}{
+			"pin_attempts":   0,
+			"pin_lock_count": 0,
+			"locked_until":   nil,
+			"updated_by":     updatedBy,
+		}).Error
+}
+
+// UpdatePasswordHash replaces the hash of an unchanged password, e.g. when it is upgraded
+func (r userRepository) UpdatePasswordHash(userID uint, hash string) error {
+	return r.db.Model(&models.Users{}).
+		Where("user_id = ?", userID).
+		Update("password", hash).Error
+}
+
+// UpdatePinHash replaces the hash of an unchanged PIN, e.g. when it is upgraded
+func (r userRepository) UpdatePinHash(userID uint, hash string) error {
+	return r.db.Model(&models.Users{}).
+		Where("user_id = ?", userID).
+		Update("pin_code", hash).Error
+}
+
+// GetUsersToReencrypt returns users whose email, phone number, device ID or device token is not
+// encrypted under the key with the given prefix, soft-deleted ones included
+func (r userRepository) GetUsersToReencrypt(keyPrefix string, limit int) (*[]models.Users, error) {
+	var users []models.Users
+	encrypted := keyPrefix + "%"
+	if err := r.db.Unscoped().
+		Where("(email NOT LIKE ? OR email_index IS NULL) OR (phone_number <> '' AND (phone_number NOT LIKE ? OR phone_number_index IS NULL)) OR (device_id <> '' AND device_id NOT LIKE ?) OR (device_token <> '' AND device_token NOT LIKE ?)",
+			encrypted, encrypted, encrypted, encrypted).
+		Order("user_id").
+		Limit(limit).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return &users, nil
+}
+
+// UpdateUserPII stores re-encrypted personal data without touching anything else
+func (r userRepository) UpdateUserPII(user *models.Users) error {
+	return r.db.Unscoped().Model(&models.Users{}).
+		Where("user_id = ?", user.UserID).
+		Select("email", "email_index", "phone_number", "phone_number_index", "device_id", "device_token").
+		Updates(user).Error
+}
+
+// GetUsersWithoutBlindIndex returns users after afterUserID that are missing the email or phone number
+// blind index, soft-deleted ones included
+func (r userRepository) GetUsersWithoutBlindIndex(afterUserID uint, limit int) (*[]models.Users, error) {
+	var users []models.Users
+	if err := r.db.Unscoped().
+		Where("user_id > ?", afterUserID).
+		Where("email_index IS NULL OR (phone_number <> '' AND phone_number_index IS NULL)").
+		Order("user_id").
+		Limit(limit).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return &users, nil
+}
+
+// UpdateBlindIndexes stores the email and phone number blind indexes without touching anything else
+func (r userRepository) UpdateBlindIndexes(user *models.Users) error {
+	return r.db.Unscoped().Model(&models.Users{}).
+		Where("user_id = ?", user.UserID).
+		Select("email_index", "phone_number_index").
+		Updates(user).Error
+}
+
 func (r userRepository) GetAllUsersByResourceId(resources *models.Resource) (*[]models.Users, error) {
 	var users []models.Users
 	err := r.db.Table(utils.TableUsersName).
@@ -1,17 +1,24 @@
 package repository
 
 import (
+	"authentication/internal/dto/in"
 	"authentication/internal/models"
 	"gorm.io/gorm"
 )
 
 type UserSessionRepository interface {
 	GetUserSessionByUserID(userID uint) (*models.UserSession, error)
+	GetActiveUserSessionsByUserID(userID uint) (*[]models.UserSession, error)
+	GetUserSessionByIDAndUserID(userSessionID, userID uint) (*models.UserSession, error)
+	GetUserSessionByAccessUUID(accessUUID string) (*models.UserSession, error)
+	GetUserSessionsByFilter(filter in.AdminSessionFilter, index, size int) (*[]models.UserSession, int64, error)
 	GetUserSession() (*[]models.UserSession, error)
 	GetUserSessionExpired() (*[]models.UserSession, error)
 	AddUserSession(userSession *models.UserSession) error
 	UpdateSession(session *models.UserSession) error
 	GetUserSessionByRefreshTokenAndUserID(userID uint, refreshToken string) (*models.UserSession, error)
+	GetUserSessionsWithRawTokens(limit int) (*[]models.UserSession, error)
+	UpdateSessionTokens(userSessionID uint, sessionToken, refreshToken string) error
 }
 
 type userSessionRepository struct {
@@ -24,7 +31,34 @@ func NewUserSessionRepository(db gorm.DB) UserSessionRepository {
 
 func (r userSessionRepository) GetUserSessionByUserID(userID uint) (*models.UserSession, error) {
 	var userSession *models.UserSession
-	err := r.db.Where("user_id = ?", userID).First(&userSession).Error
+	err := r.db.Where("user_id = ?", userID).Order("login_time DESC").First(&userSession).Error
+	if err != nil {
+		return nil, err
+	}
+	return userSession, nil
+}
+
+func (r userSessionRepository) GetActiveUserSessionsByUserID(userID uint) (*[]models.UserSession, error) {
+	var userSessions *[]models.UserSession
+	err := r.db.Where("user_id = ? AND is_active = ?", userID, true).Order("login_time DESC").Find(&userSessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return userSessions, nil
+}
+
+func (r userSessionRepository) GetUserSessionByIDAndUserID(userSessionID, userID uint) (*models.UserSession, error) {
+	var userSession *models.UserSession
+	err := r.db.Where("user_session_id = ? AND user_id = ?", userSessionID, userID).First(&userSession).Error
+	if err != nil {
+		return nil, err
+	}
+	return userSession, nil
+}
+
+func (r userSessionRepository) GetUserSessionByAccessUUID(accessUUID string) (*models.UserSession, error) {
+	var userSession *models.UserSession
+	err := r.db.Where("access_uuid = ?", accessUUID).First(&userSession).Error
 	if err != nil {
 		return nil, err
 	}
@@ -73,3 +107,58 @@ func (r userSessionRepository) GetUserSessionByRefreshTokenAndUserID(userID uint
 	}
 	return userSession, nil
 }
+
+func (r userSessionRepository) GetUserSessionsWithRawTokens(limit int) (*[]models.UserSession, error) {
+	var userSessions *[]models.UserSession
+	err := r.db.Unscoped().Where("tokens_hashed = ?", false).Limit(limit).Find(&userSessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return userSessions, nil
+}
+
+// UpdateSessionTokens rewrites the stored tokens of a session, including soft-deleted ones
+func (r userSessionRepository) UpdateSessionTokens(userSessionID uint, sessionToken, refreshToken string) error {
+	return r.db.Unscoped().Model(&models.UserSession{}).
+		Where("user_session_id = ?", userSessionID).
+		Updates(map[string]interface{}{
+			"session_token": sessionToken,
+			"refresh_token": refreshToken,
+			"tokens_hashed": true,
+			"updated_by":    "system",
+		}).Error
+}
+
+func (r userSessionRepository) GetUserSessionsByFilter(filter in.AdminSessionFilter, index, size int) (*[]models.UserSession, int64, error) {
+	query := r.db.Model(&models.UserSession{})
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
+	if filter.IPAddress != "" {
+		query = query.Where("ip_address = ?", filter.IPAddress)
+	}
+	if filter.From != nil {
+		query = query.Where("login_time >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		// the end date is inclusive
+		query = query.Where("login_time < ?", filter.To.AddDate(0, 0, 1))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var userSessions []models.UserSession
+	err := query.Order("login_time DESC").
+		Limit(size).Offset((index - 1) * size).
+		Find(&userSessions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return &userSessions, total, nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"authentication/internal/models"
+	"gorm.io/gorm"
+)
+
+type UserTwoFactorRepository interface {
+	GetUserTwoFactorByUserID(userID uint) (*models.UserTwoFactor, error)
+	SaveUserTwoFactor(twoFactor *models.UserTwoFactor) error
+	UpdateLastUsedStep(userID uint, step int64) (bool, error)
+	DeleteUserTwoFactor(userID uint) error
+	GetUserTwoFactorsToReencrypt(keyPrefix string, limit int) (*[]models.UserTwoFactor, error)
+	UpdateTotpSecret(userID uint, secret string) error
+}
+
+type userTwoFactorRepository struct {
+	db gorm.DB
+}
+
+func NewUserTwoFactorRepository(db gorm.DB) UserTwoFactorRepository {
+	return &userTwoFactorRepository{db: db}
+}
+
+func (r userTwoFactorRepository) GetUserTwoFactorByUserID(userID uint) (*models.UserTwoFactor, error) {
+	var twoFactor models.UserTwoFactor
+	if err := r.db.Where("user_id = ?", userID).First(&twoFactor).Error; err != nil {
+		return nil, err
+	}
+	return &twoFactor, nil
+}
+
+func (r userTwoFactorRepository) SaveUserTwoFactor(twoFactor *models.UserTwoFactor) error {
+	if err := r.db.Save(twoFactor).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpdateLastUsedStep only moves the step forward, so a code can be consumed once
+func (r userTwoFactorRepository) UpdateLastUsedStep(userID uint, step int64) (bool, error) {
+	result := r.db.Model(&models.UserTwoFactor{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r userTwoFactorRepository) DeleteUserTwoFactor(userID uint) error {
+	if err := r.db.Where("user_id = ?", userID).Delete(&models.UserTwoFactor{}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetUserTwoFactorsToReencrypt returns enrollments whose TOTP secret is not encrypted under the key
+// with the given prefix
+func (r userTwoFactorRepository) GetUserTwoFactorsToReencrypt(keyPrefix string, limit int) (*[]models.UserTwoFactor, error) {
+	var twoFactors []models.UserTwoFactor
+	if err := r.db.Where("totp_secret NOT LIKE ?", keyPrefix+"%").
+		Order("user_id").
+		Limit(limit).
+		Find(&twoFactors).Error; err != nil {
+		return nil, err
+	}
+	return &twoFactors, nil
+}
+
+// UpdateTotpSecret stores a re-encrypted TOTP secret without touching anything else
+func (r userTwoFactorRepository) UpdateTotpSecret(userID uint, secret string) error {
+	return r.db.Model(&models.UserTwoFactor{}).
+		Where("user_id = ?", userID).
+		Update("totp_secret", secret).Error
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"authentication/internal/models"
+	"gorm.io/gorm"
+)
+
+type WebauthnCredentialRepository interface {
+	AddCredential(credential *models.WebauthnCredential) error
+	GetCredentialsByUserID(userID uint) (*[]models.WebauthnCredential, error)
+	GetCredentialByCredentialID(credentialID string) (*models.WebauthnCredential, error)
+	GetCredentialByIDAndUserID(id, userID uint) (*models.WebauthnCredential, error)
+	UpdateCredential(credential *models.WebauthnCredential) error
+	DeleteCredential(credential *models.WebauthnCredential) error
+}
+
+type webauthnCredentialRepository struct {
+	db gorm.DB
+}
+
+func NewWebauthnCredentialRepository(db gorm.DB) WebauthnCredentialRepository {
+	return &webauthnCredentialRepository{db: db}
+}
+
+func (r webauthnCredentialRepository) AddCredential(credential *models.WebauthnCredential) error {
+	if err := r.db.Create(credential).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r webauthnCredentialRepository) GetCredentialsByUserID(userID uint) (*[]models.WebauthnCredential, error) {
+	var credentials []models.WebauthnCredential
+	if err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&credentials).Error; err != nil {
+		return nil, err
+	}
+	return &credentials, nil
+}
+
+func (r webauthnCredentialRepository) GetCredentialByCredentialID(credentialID string) (*models.WebauthnCredential, error) {
+	var credential models.WebauthnCredential
+	if err := r.db.Where("credential_id = ?", credentialID).First(&credential).Error; err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (r webauthnCredentialRepository) GetCredentialByIDAndUserID(id, userID uint) (*models.WebauthnCredential, error) {
+	var credential models.WebauthnCredential
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&credential).Error; err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+func (r webauthnCredentialRepository) UpdateCredential(credential *models.WebauthnCredential) error {
+	if err := r.db.Save(credential).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r webauthnCredentialRepository) DeleteCredential(credential *models.WebauthnCredential) error {
+	if err := r.db.Model(&credential).
+		Update("deleted_by", credential.DeletedBy).
+		Delete(&credential).Error; err != nil {
+		return err
+	}
+	return nil
+}
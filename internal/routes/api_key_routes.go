@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"authentication/config"
+	"authentication/internal/controller"
+	"github.com/gin-gonic/gin"
+)
+
+func ApiKeyRoutes(r *gin.Engine, middleware config.Middleware, apiKeyController controller.ApiKeyController) {
+	protected := r.Group("/v1/api-keys")
+	protected.Use(middleware.AuthMiddleware.Handler())
+	{
+		protected.POST("", middleware.AuthMiddleware.DenyApiKey(), middleware.AuthMiddleware.DenyDelegated(), middleware.AuthMiddleware.DenyImpersonation(), apiKeyController.CreateApiKey)
+		protected.GET("", apiKeyController.GetApiKeys)
+		protected.DELETE("/:id", apiKeyController.RevokeApiKey)
+	}
+
+	admin := r.Group("/v1/admin")
+	admin.Use(middleware.AdminMiddleware.Handler())
+	{
+		admin.GET("/users/:id/api-keys", apiKeyController.GetUserApiKeys)
+		admin.DELETE("/api-keys/:id", apiKeyController.RevokeUserApiKey)
+	}
+}
@@ -8,29 +8,40 @@ import (
 )
 
 func AuthRoutes(r *gin.Engine, middleware config.Middleware, authController controller.AuthController) {
+	sensitive := middleware.RateLimitMiddleware.Handler(utils.RateLimitSensitive)
+
 	public := r.Group("/v1")
+	public.Use(middleware.RateLimitMiddleware.Handler(utils.RateLimitPublic))
 	{
-		public.POST("/register", authController.Register)
-		public.POST("/login", authController.Login)
-		public.POST("/forgot-password", authController.ForgotPassword)
+		public.POST("/register", sensitive, authController.Register)
+		public.POST("/login", sensitive, authController.Login)
+		public.POST("/forgot-password", sensitive, authController.ForgotPassword)
 		public.POST("/login-phone", authController.LoginPhoneNumber)
+		public.POST("/login/2fa", sensitive, authController.VerifyTwoFactorLogin)
 		public.POST("/reset-password", authController.ResetPassword)
-		public.POST("/change-device", authController.ChangeDeviceID)
+		public.POST("/change-device", sensitive, authController.ChangeDeviceID)
 		public.POST("/verify-device", authController.VerifyDeviceID)
 		public.GET("/reset-redirect", utils.ResetRedirectHandler)
 	}
 
+	// only the user themselves may change their credentials, never an admin impersonating them
+	selfOnly := middleware.AuthMiddleware.DenyImpersonation()
+	// credentials and sessions need a login session, an API key has none
+	noApiKey := middleware.AuthMiddleware.DenyApiKey()
+	// nor may an OAuth client the user granted a scope to manage them
+	noDelegated := middleware.AuthMiddleware.DenyDelegated()
+
 	protected := r.Group("/v1")
-	protected.Use(middleware.AuthMiddleware.Handler())
+	protected.Use(middleware.AuthMiddleware.Handler(), middleware.RateLimitMiddleware.Handler(utils.RateLimitAuthenticated))
 	{
 		protected.POST("/register-device-token", authController.RegisterDeviceToken)
 		protected.GET("/credential-key", authController.GenerateCredentialKey)
 		protected.POST("/verify-pin", authController.VerifyPinCode)
-		protected.POST("/change-password", authController.ChangePassword)
-		protected.POST("/change-pin", authController.ChangePinCode)
-		protected.POST("/forget-pin", authController.ForgetPinCode)
-		protected.GET("/logout", authController.Logout)
-		protected.POST("/refresh-token", authController.RefreshToken)
+		protected.POST("/change-password", noApiKey, noDelegated, selfOnly, authController.ChangePassword)
+		protected.POST("/change-pin", noApiKey, noDelegated, selfOnly, authController.ChangePinCode)
+		protected.POST("/forget-pin", noApiKey, noDelegated, selfOnly, authController.ForgetPinCode)
+		protected.GET("/logout", noApiKey, noDelegated, authController.Logout)
+		protected.POST("/refresh-token", noApiKey, noDelegated, authController.RefreshToken)
 	}
 
 	admin := r.Group("/v1")
@@ -39,5 +50,6 @@ func AuthRoutes(r *gin.Engine, middleware config.Middleware, authController cont
 		admin.GET("/users", authController.GetListUser)
 		admin.GET("/users/:id", authController.GetUserByID)
 		admin.POST("/user/update-role/:id", authController.UpdateRole)
+		admin.POST("/users/:id/unlock-pin", authController.UnlockPinCode)
 	}
 }
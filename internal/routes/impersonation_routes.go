@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"authentication/config"
+	"authentication/internal/controller"
+	"github.com/gin-gonic/gin"
+)
+
+func ImpersonationRoutes(r *gin.Engine, middleware config.Middleware, impersonationController controller.ImpersonationController) {
+	admin := r.Group("/v1/admin")
+	admin.Use(middleware.AdminMiddleware.Handler())
+	{
+		admin.POST("/token-exchange", impersonationController.TokenExchange)
+		admin.GET("/impersonation-audits", impersonationController.GetAudits)
+	}
+}
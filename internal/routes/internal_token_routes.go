@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"authentication/config"
+	"authentication/internal/controller"
+	"github.com/gin-gonic/gin"
+)
+
+func InternalTokenRoutes(r *gin.Engine, middleware config.Middleware, internalTokenController controller.InternalTokenController) {
+	admin := r.Group("/v1")
+	admin.Use(middleware.AdminMiddleware.Handler())
+	{
+		admin.POST("/resources/:id/internal-tokens", internalTokenController.CreateToken)
+		admin.GET("/resources/:id/internal-tokens", internalTokenController.GetTokens)
+		admin.POST("/internal-tokens/:id/rotate", internalTokenController.RotateToken)
+		admin.DELETE("/internal-tokens/:id", internalTokenController.RevokeToken)
+	}
+}
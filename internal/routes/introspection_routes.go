@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"authentication/config"
+	"authentication/internal/controller"
+	"github.com/gin-gonic/gin"
+)
+
+func IntrospectionRoutes(r *gin.Engine, middleware config.Middleware, introspectionController controller.IntrospectionController) {
+	internal := r.Group("/v1")
+	internal.Use(middleware.InternalMiddleware.Handler())
+	{
+		internal.POST("/introspect", introspectionController.Introspect)
+	}
+}
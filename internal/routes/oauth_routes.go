@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"authentication/config"
+	"authentication/internal/controller"
+	"authentication/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func OauthRoutes(r *gin.Engine, middleware config.Middleware, oauthController controller.OauthController) {
+	sensitive := middleware.RateLimitMiddleware.Handler(utils.RateLimitSensitive)
+
+	public := r.Group("/oauth")
+	{
+		public.POST("/token", oauthController.Token)
+	}
+
+	// the browser pages authenticate with the oauth_session cookie set by /oauth/login, not a bearer token
+	browser := r.Group("/oauth")
+	browser.Use(middleware.RateLimitMiddleware.Handler(utils.RateLimitPublic))
+	{
+		browser.GET("/authorize", oauthController.Authorize)
+		browser.POST("/authorize", oauthController.Consent)
+		browser.POST("/login", sensitive, oauthController.Login)
+	}
+
+	admin := r.Group("/v1/oauth")
+	admin.Use(middleware.AdminMiddleware.Handler())
+	{
+		admin.POST("/clients", oauthController.CreateClient)
+		admin.GET("/clients", oauthController.GetClients)
+		admin.DELETE("/clients/:id", oauthController.DeleteClient)
+	}
+}
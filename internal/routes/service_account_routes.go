@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"authentication/config"
+	"authentication/internal/controller"
+	"github.com/gin-gonic/gin"
+)
+
+func ServiceAccountRoutes(r *gin.Engine, middleware config.Middleware, serviceAccountController controller.ServiceAccountController) {
+	admin := r.Group("/v1")
+	admin.Use(middleware.AdminMiddleware.Handler())
+	{
+		admin.POST("/service-accounts", serviceAccountController.CreateServiceAccount)
+		admin.GET("/service-accounts", serviceAccountController.GetServiceAccounts)
+		admin.PUT("/service-accounts/:id", serviceAccountController.UpdateServiceAccount)
+		admin.POST("/service-accounts/:id/rotate-secret", serviceAccountController.RotateSecret)
+		admin.DELETE("/service-accounts/:id", serviceAccountController.DeleteServiceAccount)
+	}
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"authentication/config"
+	"authentication/internal/controller"
+	"github.com/gin-gonic/gin"
+)
+
+func SessionRoutes(r *gin.Engine, middleware config.Middleware, sessionController controller.SessionController) {
+	protected := r.Group("/v1/sessions")
+	protected.Use(middleware.AuthMiddleware.Handler())
+	{
+		protected.GET("", sessionController.GetSessions)
+		protected.DELETE("", middleware.AuthMiddleware.DenyApiKey(), middleware.AuthMiddleware.DenyDelegated(), middleware.AuthMiddleware.DenyImpersonation(), sessionController.RevokeOtherSessions)
+		protected.DELETE("/:id", middleware.AuthMiddleware.DenyApiKey(), middleware.AuthMiddleware.DenyDelegated(), middleware.AuthMiddleware.DenyImpersonation(), sessionController.RevokeSession)
+	}
+
+	admin := r.Group("/v1/admin")
+	admin.Use(middleware.AdminMiddleware.Handler())
+	{
+		admin.GET("/sessions", sessionController.GetAllSessions)
+		admin.POST("/users/:id/logout", sessionController.ForceLogout)
+	}
+}
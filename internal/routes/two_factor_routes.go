@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"authentication/config"
+	"authentication/internal/controller"
+	"github.com/gin-gonic/gin"
+)
+
+func TwoFactorRoutes(r *gin.Engine, middleware config.Middleware, twoFactorController controller.TwoFactorController) {
+	protected := r.Group("/v1/2fa")
+	protected.Use(middleware.AuthMiddleware.Handler(), middleware.AuthMiddleware.DenyApiKey(), middleware.AuthMiddleware.DenyDelegated(), middleware.AuthMiddleware.DenyImpersonation())
+	{
+		protected.POST("/totp/enroll", twoFactorController.EnrollTotp)
+		protected.POST("/totp/confirm", twoFactorController.ConfirmTotp)
+		protected.POST("/totp/disable", twoFactorController.DisableTotp)
+	}
+}
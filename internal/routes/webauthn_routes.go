@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"authentication/config"
+	"authentication/internal/controller"
+	"github.com/gin-gonic/gin"
+)
+
+func WebauthnRoutes(r *gin.Engine, middleware config.Middleware, webauthnController controller.WebauthnController) {
+	public := r.Group("/v1/webauthn")
+	{
+		public.POST("/login/begin", webauthnController.BeginLogin)
+		public.POST("/login/finish", webauthnController.FinishLogin)
+	}
+
+	selfOnly := middleware.AuthMiddleware.DenyImpersonation()
+	noApiKey := middleware.AuthMiddleware.DenyApiKey()
+	noDelegated := middleware.AuthMiddleware.DenyDelegated()
+
+	protected := r.Group("/v1/webauthn")
+	protected.Use(middleware.AuthMiddleware.Handler())
+	{
+		protected.POST("/register/begin", noApiKey, noDelegated, selfOnly, webauthnController.BeginRegistration)
+		protected.POST("/register/finish", noApiKey, noDelegated, selfOnly, webauthnController.FinishRegistration)
+		protected.GET("/credentials", webauthnController.GetCredentials)
+		protected.DELETE("/credentials/:id", noApiKey, noDelegated, selfOnly, webauthnController.DeleteCredential)
+	}
+}
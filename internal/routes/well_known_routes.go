@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"authentication/config"
+	"authentication/internal/controller"
+	"github.com/gin-gonic/gin"
+)
+
+func WellKnownRoutes(r *gin.Engine, middleware config.Middleware, wellKnownController controller.WellKnownController) {
+	public := r.Group("/.well-known")
+	{
+		public.GET("/jwks.json", wellKnownController.JWKS)
+		public.GET("/openid-configuration", wellKnownController.OpenIDConfiguration)
+	}
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/dto/out"
+	"authentication/internal/models"
+	"authentication/internal/repository"
+	"authentication/internal/utils"
+	"authentication/internal/utils/logger"
+	"crypto/subtle"
+	"errors"
+	"gorm.io/gorm"
+	"strconv"
+	"time"
+)
+
+type ApiKeyService interface {
+	CreateApiKey(req *in.ApiKeyRequest, clientID string) (out.ApiKeyResponse, error)
+	GetApiKeys(clientID string) ([]out.ApiKeyResponse, error)
+	RevokeApiKey(id uint, clientID string) error
+	GetUserApiKeys(userID uint) ([]out.ApiKeyResponse, error)
+	RevokeUserApiKey(id uint, clientID string) error
+	RevokeAllUserApiKeys(userID uint, clientID string) error
+	Authenticate(key string) (*utils.TokenClaims, error)
+}
+
+type apiKeyService struct {
+	ApiKeyRepository   repository.ApiKeyRepository
+	UserRepository     repository.UserRepository
+	ResourceRepository repository.ResourceRepository
+	TokenHasher        utils.TokenHasher
+	MaxPerUser         int
+}
+
+func NewApiKeyService(
+	apiKeyRepo repository.ApiKeyRepository,
+	userRepo repository.UserRepository,
+	resourceRepo repository.ResourceRepository,
+	tokenHasher utils.TokenHasher,
+	maxPerUser int,
+) ApiKeyService {
+	return apiKeyService{
+		ApiKeyRepository:   apiKeyRepo,
+		UserRepository:     userRepo,
+		ResourceRepository: resourceRepo,
+		TokenHasher:        tokenHasher,
+		MaxPerUser:         maxPerUser,
+	}
+}
+
+// CreateApiKey issues a key scoped to resources the user already has. The key is only returned here.
+func (s apiKeyService) CreateApiKey(req *in.ApiKeyRequest, clientID string) (out.ApiKeyResponse, error) {
+	user, err := s.UserRepository.GetUserByClientID(clientID)
+	if err != nil {
+		return out.ApiKeyResponse{}, errors.New("user not found")
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return out.ApiKeyResponse{}, errors.New("expires_at must be in the future")
+	}
+
+	if len(req.Scopes) == 0 {
+		return out.ApiKeyResponse{}, errors.New("at least one scope is required")
+	}
+	userResources, err := s.userResourceNames(user.UserID)
+	if err != nil {
+		return out.ApiKeyResponse{}, errors.New("unable to get resource")
+	}
+	for _, scope := range req.Scopes {
+		if !utils.ContainsString(userResources, scope) {
+			return out.ApiKeyResponse{}, errors.New("scope is not one of your resources: " + scope)
+		}
+	}
+
+	count, err := s.ApiKeyRepository.CountActiveApiKeysByUserID(user.UserID)
+	if err != nil {
+		return out.ApiKeyResponse{}, errors.New("unable to get API keys")
+	}
+	if count >= int64(s.MaxPerUser) {
+		return out.ApiKeyResponse{}, errors.New("you can have at most " + strconv.Itoa(s.MaxPerUser) + " active API keys")
+	}
+
+	key, prefix, err := utils.GenerateApiKey()
+	if err != nil {
+		return out.ApiKeyResponse{}, errors.New("unable to generate API key")
+	}
+
+	apiKey := &models.ApiKey{
+		UserID:    user.UserID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   s.TokenHasher.Hash(key),
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: clientID,
+		UpdatedBy: clientID,
+	}
+	if err := s.ApiKeyRepository.AddApiKey(apiKey); err != nil {
+		return out.ApiKeyResponse{}, errors.New("unable to create API key")
+	}
+
+	response := apiKeyResponse(apiKey)
+	response.Key = key
+	return response, nil
+}
+
+func (s apiKeyService) GetApiKeys(clientID string) ([]out.ApiKeyResponse, error) {
+	user, err := s.UserRepository.GetUserByClientID(clientID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	return s.GetUserApiKeys(user.UserID)
+}
+
+func (s apiKeyService) RevokeApiKey(id uint, clientID string) error {
+	user, err := s.UserRepository.GetUserByClientID(clientID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+
+	apiKey, err := s.ApiKeyRepository.GetApiKeyByID(id)
+	if err != nil || apiKey.UserID != user.UserID {
+		return errors.New("API key not found")
+	}
+
+	return s.revoke(apiKey, clientID)
+}
+
+func (s apiKeyService) GetUserApiKeys(userID uint) ([]out.ApiKeyResponse, error) {
+	apiKeys, err := s.ApiKeyRepository.GetApiKeysByUserID(userID)
+	if err != nil {
+		return nil, errors.New("unable to get API keys")
+	}
+
+	responses := make([]out.ApiKeyResponse, 0, len(*apiKeys))
+	for i := range *apiKeys {
+		responses = append(responses, apiKeyResponse(&(*apiKeys)[i]))
+	}
+	return responses, nil
+}
+
+// RevokeUserApiKey lets an admin revoke any user's key
+func (s apiKeyService) RevokeUserApiKey(id uint, clientID string) error {
+	apiKey, err := s.ApiKeyRepository.GetApiKeyByID(id)
+	if err != nil {
+		return errors.New("API key not found")
+	}
+
+	return s.revoke(apiKey, clientID)
+}
+
+// Authenticate resolves a key to the claims a JWT for the same user would carry, limited to the
+// key's scopes. Resources the user has lost since the key was created are dropped.
+func (s apiKeyService) Authenticate(key string) (*utils.TokenClaims, error) {
+	prefix, ok := utils.ParseApiKeyPrefix(key)
+	if !ok {
+		return nil, utils.ErrApiKeyInvalid
+	}
+
+	apiKey, err := s.ApiKeyRepository.GetApiKeyByPrefix(prefix)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrApiKeyInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(s.TokenHasher.Hash(key))) != 1 {
+		return nil, utils.ErrApiKeyInvalid
+	}
+	if apiKeyStatus(apiKey) != utils.ApiKeyStatusActive {
+		return nil, utils.ErrApiKeyRevoked
+	}
+
+	user, err := s.UserRepository.GetUserByID(apiKey.UserID)
+	if err != nil {
+		return nil, utils.ErrApiKeyInvalid
+	}
+
+	userResources, err := s.userResourceNames(user.UserID)
+	if err != nil {
+		return nil, err
+	}
+	var resources []string
+	for _, scope := range apiKey.Scopes {
+		if utils.ContainsString(userResources, scope) {
+			resources = append(resources, scope)
+		}
+	}
+
+	now := time.Now()
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > utils.ApiKeyLastUsedInterval {
+		if err := s.ApiKeyRepository.UpdateLastUsedAt(apiKey.ID, now); err != nil {
+			logger.Error().Err(err).Uint("api_key_id", apiKey.ID).Msg("Error updating API key last used time")
+		}
+	}
+
+	claims := &utils.TokenClaims{
+		Authorized: true,
+		AccessUUID: utils.ApiKeyAccessUUID(apiKey.ID),
+		UserID:     user.UserID,
+		ClientID:   user.ClientID,
+		RoleID:     user.RoleID,
+		Resource:   resources,
+		IssuedAt:   apiKey.CreatedAt.Unix(),
+		ApiKeyID:   apiKey.ID,
+	}
+	// a key without an expiry leaves exp out rather than reporting it as 0
+	if apiKey.ExpiresAt != nil {
+		claims.Exp = apiKey.ExpiresAt.Unix()
+	}
+	return claims, nil
+}
+
+// RevokeAllUserApiKeys revokes every key of the user, for when all of their sessions are ended
+func (s apiKeyService) RevokeAllUserApiKeys(userID uint, clientID string) error {
+	if err := s.ApiKeyRepository.RevokeApiKeysByUserID(userID, clientID, time.Now()); err != nil {
+		return errors.New("unable to revoke API keys")
+	}
+	return nil
+}
+
+func (s apiKeyService) revoke(apiKey *models.ApiKey, clientID string) error {
+	if apiKey.RevokedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	apiKey.RevokedAt = &now
+	apiKey.RevokedBy = clientID
+	apiKey.UpdatedBy = clientID
+	if err := s.ApiKeyRepository.UpdateApiKey(apiKey); err != nil {
+		return errors.New("unable to revoke API key")
+	}
+	return nil
+}
+
+func (s apiKeyService) userResourceNames(userID uint) ([]string, error) {
+	resources, err := s.ResourceRepository.GetResourceByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(*resources))
+	for _, resource := range *resources {
+		names = append(names, resource.Name)
+	}
+	return names, nil
+}
+
+func apiKeyStatus(apiKey *models.ApiKey) string {
+	if apiKey.RevokedAt != nil {
+		return utils.ApiKeyStatusRevoked
+	}
+	if apiKey.ExpiresAt != nil && !time.Now().Before(*apiKey.ExpiresAt) {
+		return utils.ApiKeyStatusExpired
+	}
+	return utils.ApiKeyStatusActive
+}
+
+func apiKeyResponse(apiKey *models.ApiKey) out.ApiKeyResponse {
+	return out.ApiKeyResponse{
+		ID:         apiKey.ID,
+		UserID:     apiKey.UserID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.Scopes,
+		Status:     apiKeyStatus(apiKey),
+		ExpiresAt:  apiKey.ExpiresAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
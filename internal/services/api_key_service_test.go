@@ -0,0 +1,188 @@
+package services
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/models"
+	"authentication/internal/repository"
+	"authentication/internal/utils"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// apiKeyStore keeps API keys in memory by ID
+type apiKeyStore struct {
+	repository.ApiKeyRepository
+	keys map[uint]*models.ApiKey
+}
+
+func (r *apiKeyStore) AddApiKey(apiKey *models.ApiKey) error {
+	apiKey.ID = uint(len(r.keys) + 1)
+	apiKey.CreatedAt = time.Now()
+	r.keys[apiKey.ID] = apiKey
+	return nil
+}
+
+func (r *apiKeyStore) GetApiKeyByID(id uint) (*models.ApiKey, error) {
+	apiKey, ok := r.keys[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *apiKey
+	return &copied, nil
+}
+
+func (r *apiKeyStore) GetApiKeyByPrefix(prefix string) (*models.ApiKey, error) {
+	for _, apiKey := range r.keys {
+		if apiKey.Prefix == prefix {
+			copied := *apiKey
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *apiKeyStore) CountActiveApiKeysByUserID(userID uint) (int64, error) {
+	var count int64
+	for _, apiKey := range r.keys {
+		if apiKey.UserID == userID && apiKey.RevokedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *apiKeyStore) UpdateApiKey(apiKey *models.ApiKey) error {
+	copied := *apiKey
+	r.keys[apiKey.ID] = &copied
+	return nil
+}
+
+func (r *apiKeyStore) UpdateLastUsedAt(id uint, lastUsedAt time.Time) error {
+	r.keys[id].LastUsedAt = &lastUsedAt
+	return nil
+}
+
+func (r *apiKeyStore) RevokeApiKeysByUserID(userID uint, revokedBy string, revokedAt time.Time) error {
+	for _, apiKey := range r.keys {
+		if apiKey.UserID == userID && apiKey.RevokedAt == nil {
+			apiKey.RevokedAt = &revokedAt
+			apiKey.RevokedBy = revokedBy
+		}
+	}
+	return nil
+}
+
+// userResources holds the names of the resources each user has
+type userResources struct {
+	repository.ResourceRepository
+	names map[uint][]string
+}
+
+func (r userResources) GetResourceByUserID(userID uint) (*[]models.Resource, error) {
+	var resources []models.Resource
+	for _, name := range r.names[userID] {
+		resources = append(resources, models.Resource{Name: name})
+	}
+	return &resources, nil
+}
+
+func TestApiKeyAuthenticate(t *testing.T) {
+	resources := userResources{names: map[uint][]string{7: {"auth", "billing", "reports"}}}
+	keys := &apiKeyStore{keys: map[uint]*models.ApiKey{}}
+	service := NewApiKeyService(keys, newUserStore(models.Users{UserID: 7, ClientID: "client-7"}), resources, testTokenHasher, 5)
+
+	create := func(scopes ...string) string {
+		created, err := service.CreateApiKey(&in.ApiKeyRequest{Name: "ci", Scopes: scopes}, "client-7")
+		if err != nil {
+			t.Fatalf("CreateApiKey(%v) error = %v", scopes, err)
+		}
+		return created.Key
+	}
+	scoped := create("auth", "billing")
+	revoked := create("auth")
+	if err := service.RevokeApiKey(2, "client-7"); err != nil {
+		t.Fatalf("RevokeApiKey() error = %v", err)
+	}
+	expired := create("auth")
+	keys.keys[3].ExpiresAt = &time.Time{}
+
+	if _, err := service.CreateApiKey(&in.ApiKeyRequest{Name: "ci", Scopes: []string{"admin"}}, "client-7"); err == nil {
+		t.Error("CreateApiKey() accepted a scope the user does not have")
+	}
+
+	claims, err := service.Authenticate(scoped)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if !reflect.DeepEqual(claims.Resource, []string{"auth", "billing"}) || claims.ClientID != "client-7" {
+		t.Errorf("Authenticate() claims = %+v, want client-7 limited to auth and billing", claims)
+	}
+	if claims.AccessUUID != "apikey:1" || claims.Exp != 0 {
+		t.Errorf("Authenticate() access UUID %q, exp %d, want apikey:1 without an expiry", claims.AccessUUID, claims.Exp)
+	}
+
+	resources.names[7] = []string{"auth", "reports"}
+	claims, err = service.Authenticate(scoped)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if !reflect.DeepEqual(claims.Resource, []string{"auth"}) {
+		t.Errorf("Authenticate() resources = %v, want billing dropped after the user lost it", claims.Resource)
+	}
+
+	wrongSecret := scoped[:len(scoped)-1] + "0"
+	if wrongSecret == scoped {
+		wrongSecret = scoped[:len(scoped)-1] + "1"
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		wantErr error
+	}{
+		{"revoked key", revoked, utils.ErrApiKeyRevoked},
+		{"expired key", expired, utils.ErrApiKeyRevoked},
+		{"wrong secret for a known prefix", wrongSecret, utils.ErrApiKeyInvalid},
+		{"unknown prefix", "ak_0000000000000000_secret", utils.ErrApiKeyInvalid},
+		{"not an API key", "bearer-token", utils.ErrApiKeyInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.Authenticate(tt.key); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRevokeAllUserApiKeys(t *testing.T) {
+	users := newUserStore(models.Users{UserID: 7, ClientID: "client-7"}, models.Users{UserID: 8, ClientID: "client-8"})
+	resources := userResources{names: map[uint][]string{7: {"auth"}, 8: {"auth"}}}
+	service := NewApiKeyService(&apiKeyStore{keys: map[uint]*models.ApiKey{}}, users, resources, testTokenHasher, 5)
+
+	issue := func(clientID string) string {
+		created, err := service.CreateApiKey(&in.ApiKeyRequest{Name: "ci", Scopes: []string{"auth"}}, clientID)
+		if err != nil {
+			t.Fatalf("CreateApiKey() error = %v", err)
+		}
+		return created.Key
+	}
+	first, second, otherUser := issue("client-7"), issue("client-7"), issue("client-8")
+
+	if err := service.RevokeAllUserApiKeys(7, "admin-1"); err != nil {
+		t.Fatalf("RevokeAllUserApiKeys() error = %v", err)
+	}
+	for _, key := range []string{first, second} {
+		if _, err := service.Authenticate(key); !errors.Is(err, utils.ErrApiKeyRevoked) {
+			t.Errorf("Authenticate() error = %v, want the user's keys revoked", err)
+		}
+	}
+	if _, err := service.Authenticate(otherUser); err != nil {
+		t.Errorf("Authenticate() error = %v, want another user's key untouched", err)
+	}
+}
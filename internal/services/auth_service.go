@@ -6,11 +6,12 @@ import (
 	"authentication/internal/models"
 	"authentication/internal/repository"
 	"authentication/internal/utils"
+	"authentication/internal/utils/logger"
 	nt "authentication/internal/utils/nats"
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
-	"log"
-	"regexp"
+	"gorm.io/gorm"
 	"strings"
 	"time"
 )
@@ -20,12 +21,14 @@ type AuthService interface {
 	RegisterDeviceToken(req *struct {
 		DeviceToken string `json:"device_token" binding:"required"`
 	}, clientID string) error
-	Login(req *in.LoginRequest, deviceID string) (interface{}, error)
+	Login(req *in.LoginRequest, deviceID, ipAddress string) (interface{}, error)
 	ReLogin(req struct {
 		UserID       uint   `json:"user_id" binding:"required"`
 		RefreshToken string `json:"refresh_token" binding:"required"`
 	}) (interface{}, error)
 	LoginPhoneNumber(req *in.LoginPhoneNumber, deviceID string) (interface{}, error)
+	VerifyTwoFactorLogin(req *in.TwoFactorLoginRequest, deviceID string) (interface{}, error)
+	CompleteLogin(user *models.Users, deviceID string) (interface{}, error)
 	ChangeDeviceID(s *struct {
 		PhoneNumber string `json:"phone_number" binding:"required"`
 		DeviceID    string `json:"device_id" binding:"required"`
@@ -44,10 +47,7 @@ type AuthService interface {
 	UpdateToken(userID uint, clientID string) (*models.TokenDetails, error)
 	RefreshToken(req *struct {
 		RefreshToken string `json:"refresh_token" binding:"required"`
-	}, id string) (interface{}, error)
-	RegisterInternalToken(req *struct {
-		ResourceName string `json:"resource_name" binding:"required"`
-	}) (interface{}, error)
+	}, id, ipAddress string) (interface{}, error)
 	UpdateRole(userID uint, roleID uint, clientID string) error
 	GetListUser(clientID string) (interface{}, error)
 	ChangePassword(password *struct {
@@ -55,9 +55,13 @@ type AuthService interface {
 		NewPassword string `json:"new_password" binding:"required"`
 	}, clientID string) error
 	ResetPinAttempts()
+	ReencryptPII()
+	BackfillBlindIndexes()
+	UnlockPinCode(userID uint, clientID string) error
 	ForgetPinCode(req *struct {
-		Email   string `json:"email" binding:"required"`
-		PinCode string `json:"pin_code" binding:"required"`
+		Email    string `json:"email" binding:"required"`
+		Password string `json:"password" binding:"required"`
+		PinCode  string `json:"pin_code" binding:"required"`
 	}, clientID string) error
 	GetUserByID(userID uint, clientID string) (interface{}, error)
 	GenerateCredentialKey(clientID string) (interface{}, error)
@@ -86,9 +90,19 @@ type authService struct {
 	JWTService                utils.JWTService
 	Encryption                utils.Encryption
 	NatsService               nt.Service
+	TwoFactorService          TwoFactorService
+	RefreshTokenService       RefreshTokenService
+	TokenHasher               utils.TokenHasher
+	TokenRevocation           utils.TokenRevocation
+	PinLockPolicy             utils.PinLockPolicy
+	LoginAttemptService       LoginAttemptService
+	PasswordValidator         utils.PasswordValidator
+	PasswordHistoryRepository repository.PasswordHistoryRepository
+	PasswordHistorySize       int
+	ApiKeyService             ApiKeyService
 }
 
-func NewAuthService(authRepo repository.AuthRepository, resourceRepo repository.ResourceRepository, roleRepo repository.RoleRepository, roleResourceRepo repository.UserResourceRepository, userRepo repository.UserRepository, userKeyRepo repository.UserKeyRepository, userRoleRepo repository.UserRoleRepository, userSessionRepo repository.UserSessionRepository, userTransactionRepo repository.UserTransactionalRepository, userSetting repository.UserSettingRepository, redis utils.RedisService, jwtService utils.JWTService, Encryption utils.Encryption, service nt.Service) AuthService {
+func NewAuthService(authRepo repository.AuthRepository, resourceRepo repository.ResourceRepository, roleRepo repository.RoleRepository, roleResourceRepo repository.UserResourceRepository, userRepo repository.UserRepository, userKeyRepo repository.UserKeyRepository, userRoleRepo repository.UserRoleRepository, userSessionRepo repository.UserSessionRepository, userTransactionRepo repository.UserTransactionalRepository, userSetting repository.UserSettingRepository, redis utils.RedisService, jwtService utils.JWTService, Encryption utils.Encryption, service nt.Service, twoFactorService TwoFactorService, refreshTokenService RefreshTokenService, tokenHasher utils.TokenHasher, tokenRevocation utils.TokenRevocation, pinLockPolicy utils.PinLockPolicy, loginAttemptService LoginAttemptService, passwordValidator utils.PasswordValidator, passwordHistoryRepo repository.PasswordHistoryRepository, passwordHistorySize int, apiKeyService ApiKeyService) AuthService {
 	return authService{
 		AuthRepository:            authRepo,
 		ResourceRepository:        resourceRepo,
@@ -104,6 +118,16 @@ func NewAuthService(authRepo repository.AuthRepository, resourceRepo repository.
 		JWTService:                jwtService,
 		Encryption:                Encryption,
 		NatsService:               service,
+		TwoFactorService:          twoFactorService,
+		RefreshTokenService:       refreshTokenService,
+		TokenHasher:               tokenHasher,
+		TokenRevocation:           tokenRevocation,
+		PinLockPolicy:             pinLockPolicy,
+		LoginAttemptService:       loginAttemptService,
+		PasswordValidator:         passwordValidator,
+		PasswordHistoryRepository: passwordHistoryRepo,
+		PasswordHistorySize:       passwordHistorySize,
+		ApiKeyService:             apiKeyService,
 	}
 }
 
@@ -131,6 +155,18 @@ func (s authService) Register(req *in.RegisterRequest, deviceID string) (out.Reg
 		return out.RegisterResponse{}, errors.New("phone Number is invalid")
 	}
 
+	role, err := s.RoleRepository.GetRoleByName("User")
+	if err != nil {
+		return out.RegisterResponse{}, errors.New("unable to get role")
+	}
+
+	if err := s.PasswordValidator.Validate(role.Name, "password", req.Password, utils.PasswordSubject{
+		Username: req.Username,
+		Email:    req.Email,
+	}); err != nil {
+		return out.RegisterResponse{}, err
+	}
+
 	hashedPassword, err := s.Encryption.HashPassword(req.Password)
 	if err != nil {
 		return out.RegisterResponse{}, errors.New("invalid Password")
@@ -138,14 +174,8 @@ func (s authService) Register(req *in.RegisterRequest, deviceID string) (out.Reg
 
 	var hashedPin *string
 	if req.PinCode != nil && *req.PinCode != "" {
-		if len(*req.PinCode) < 6 {
-			return out.RegisterResponse{}, errors.New("pin Code must be 6 digits")
-		}
-		if len(*req.PinCode) > 6 {
-			return out.RegisterResponse{}, errors.New("pin Code must be 6 digits")
-		}
-		if !regexp.MustCompile(`^\d+$`).MatchString(*req.PinCode) {
-			return out.RegisterResponse{}, errors.New("pin Code must be numeric")
+		if err := utils.ValidatePinCode(*req.PinCode); err != nil {
+			return out.RegisterResponse{}, err
 		}
 		hashedPin, err = s.Encryption.HashPassword(*req.PinCode)
 	}
@@ -165,11 +195,7 @@ func (s authService) Register(req *in.RegisterRequest, deviceID string) (out.Reg
 	if err != nil {
 		return out.RegisterResponse{}, errors.New("phone Number is invalid")
 	}
-
-	role, err := s.RoleRepository.GetRoleByName("User")
-	if err != nil {
-		return out.RegisterResponse{}, errors.New("unable to get role")
-	}
+	phoneNumberIndex := s.Encryption.HashPhoneNumber(req.PhoneNumber)
 
 	firstName := utils.ValidationTrimSpace(req.FirstName)
 	lastName := utils.ValidationTrimSpace(req.LastName)
@@ -178,31 +204,38 @@ func (s authService) Register(req *in.RegisterRequest, deviceID string) (out.Reg
 	if err := utils.ValidateEmail(req.Email); err != nil {
 		return out.RegisterResponse{}, errors.New("email is invalid")
 	}
+	encryptedEmail, err := s.Encryption.Encrypt(req.Email)
+	if err != nil {
+		return out.RegisterResponse{}, errors.New("email is invalid")
+	}
+	emailIndex := s.Encryption.HashEmail(req.Email)
 
 	//check email exists
-	_, err = s.UserRepository.GetUserByEmail(req.Email)
+	_, err = s.UserRepository.GetUserByEmail(emailIndex)
 	if err == nil {
 		return out.RegisterResponse{}, errors.New("email already exist")
 	}
 
-	phone, err := s.UserRepository.GetUserByPhoneNumber(hashPhoneNumber)
+	phone, err := s.UserRepository.GetUserByPhoneNumber(phoneNumberIndex)
 	if phone != nil && phone.PhoneNumber != "" {
 		return out.RegisterResponse{}, errors.New("phone Number already exist")
 	}
 
 	user := &models.Users{
-		ClientID:       utils.GenerateClientID(),
-		Username:       req.Username,
-		Password:       *hashedPassword,
-		PinCode:        hashedPin,
-		PinAttempts:    0,
-		PinLastUpdated: time.Now(),
-		FirstName:      firstName,
-		LastName:       lastName,
-		FullName:       fullName,
-		Email:          req.Email,
-		PhoneNumber:    hashPhoneNumber,
-		RoleID:         role.RoleID,
+		ClientID:         utils.GenerateClientID(),
+		Username:         req.Username,
+		Password:         *hashedPassword,
+		PinCode:          hashedPin,
+		PinAttempts:      0,
+		PinLastUpdated:   time.Now(),
+		FirstName:        firstName,
+		LastName:         lastName,
+		FullName:         fullName,
+		Email:            encryptedEmail,
+		EmailIndex:       &emailIndex,
+		PhoneNumber:      hashPhoneNumber,
+		PhoneNumberIndex: &phoneNumberIndex,
+		RoleID:           role.RoleID,
 		DeviceID: func() *string {
 			if hashDeviceID == "" {
 				return nil
@@ -217,6 +250,7 @@ func (s authService) Register(req *in.RegisterRequest, deviceID string) (out.Reg
 	if err := s.UserTransactionRepository.RegistrationUser(user); err != nil {
 		return out.RegisterResponse{}, errors.New("Unable to register user")
 	}
+	s.recordPassword(user, "system")
 
 	resource, err := s.ResourceRepository.GetResourceByUserID(user.UserID)
 	if err != nil {
@@ -267,6 +301,7 @@ func (s authService) Register(req *in.RegisterRequest, deviceID string) (out.Reg
 		return out.RegisterResponse{}, errors.New("User not found")
 	}
 	_ = s.RedisService.SaveData(utils.Token, user.ClientID, token)
+	userRedis.Email = utils.DecryptString(userRedis.Email, s.Encryption)
 	_ = s.RedisService.SaveData(utils.User, user.ClientID, userRedis)
 	_ = s.RedisService.SaveData(utils.UserKey, user.ClientID, userKeys)
 
@@ -295,11 +330,11 @@ func (s authService) RegisterDeviceToken(req *struct {
 		return errors.New("user not found")
 	}
 
-	if user.DeviceToken == nil {
-		user.DeviceToken = &req.DeviceToken
-	} else {
-		user.DeviceToken = &req.DeviceToken
+	deviceToken, err := s.Encryption.Encrypt(req.DeviceToken)
+	if err != nil {
+		return errors.New("device token is invalid")
 	}
+	user.DeviceToken = &deviceToken
 
 	if err := s.UserRepository.UpdateUser(user); err != nil {
 		return errors.New("unable to update user")
@@ -307,17 +342,35 @@ func (s authService) RegisterDeviceToken(req *struct {
 	return nil
 }
 
-func (s authService) Login(req *in.LoginRequest, deviceID string) (interface{}, error) {
+func (s authService) Login(req *in.LoginRequest, deviceID, ipAddress string) (interface{}, error) {
+	if err := s.LoginAttemptService.Check(req.Username, ipAddress); err != nil {
+		return nil, err
+	}
+
 	user, err := s.UserRepository.GetUserByUsername(req.Username)
 	if err != nil {
+		s.LoginAttemptService.RegisterFailure(req.Username, ipAddress)
 		return nil, errors.New("username or Password is incorrect")
 	}
-	if err := utils.CheckPassword(user.Password, req.Password); err != nil {
+	if err := s.Encryption.CheckPassword(user.Password, req.Password); err != nil {
+		s.LoginAttemptService.RegisterFailure(req.Username, ipAddress)
 		return nil, errors.New("username or Password is incorrect")
 	}
+	s.rehashPassword(user, req.Password)
+
+	// the throttle is only cleared once every factor is verified
+	if s.TwoFactorService.IsTotpEnabled(user.UserID) {
+		return s.startTwoFactorChallenge(user, utils.LoginMethodPassword, deviceID, req.DeviceID)
+	}
+	s.LoginAttemptService.Reset(req.Username)
+
+	return s.passwordLoginResponse(user, deviceID, req.DeviceID)
+}
 
-	if deviceID == "MOBILE" && req.DeviceID != "" && (user.DeviceID == nil || *user.DeviceID != req.DeviceID) {
-		hashDeviceID, err := s.Encryption.Encrypt(req.DeviceID)
+// passwordLoginResponse issues tokens once every factor of a password login is verified
+func (s authService) passwordLoginResponse(user *models.Users, deviceID, reqDeviceID string) (interface{}, error) {
+	if deviceID == "MOBILE" && reqDeviceID != "" && utils.DerefStr(utils.DecryptOptionalString(user.DeviceID, s.Encryption)) != reqDeviceID {
+		hashDeviceID, err := s.Encryption.Encrypt(reqDeviceID)
 		if err != nil {
 			return nil, errors.New("device ID is invalid")
 		}
@@ -376,6 +429,7 @@ func (s authService) Login(req *in.LoginRequest, deviceID string) (interface{},
 	}
 
 	_ = s.RedisService.SaveData(utils.Token, user.ClientID, token)
+	userRedis.Email = utils.DecryptString(userRedis.Email, s.Encryption)
 	_ = s.RedisService.SaveData(utils.User, user.ClientID, userRedis)
 
 	var phoneNumber string
@@ -415,9 +469,9 @@ func (s authService) Login(req *in.LoginRequest, deviceID string) (interface{},
 		FirstName:      user.FirstName,
 		LastName:       user.LastName,
 		PhoneNumber:    phoneNumber,
-		Email:          user.Email,
+		Email:          s.decryptEmail(user),
 		DeviceID:       deviceIdResponse,
-		DeviceToken:    user.DeviceToken,
+		DeviceToken:    utils.DecryptOptionalString(user.DeviceToken, s.Encryption),
 		ProfilePicture: user.ProfilePicture,
 		UserSetting:    userSettingModel,
 		Token:          token.AccessToken,
@@ -430,7 +484,7 @@ func (s authService) ReLogin(req struct {
 	UserID       uint   `json:"user_id" binding:"required"`
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }) (interface{}, error) {
-	userSession, err := s.UserSessionRepository.GetUserSessionByRefreshTokenAndUserID(req.UserID, req.RefreshToken)
+	userSession, err := s.UserSessionRepository.GetUserSessionByRefreshTokenAndUserID(req.UserID, s.TokenHasher.Hash(req.RefreshToken))
 	if err != nil {
 		return nil, errors.New("invalid Refresh Token")
 	}
@@ -442,16 +496,11 @@ func (s authService) ReLogin(req struct {
 
 	return s.Login(&in.LoginRequest{
 		Username: user.Username,
-	}, *user.DeviceID)
+	}, *user.DeviceID, "")
 }
 
 func (s authService) LoginPhoneNumber(req *in.LoginPhoneNumber, deviceID string) (interface{}, error) {
-	hashPhoneNumber, err := s.Encryption.Encrypt(req.PhoneNumber)
-	if err != nil {
-		return nil, errors.New("phone Number is invalid")
-	}
-
-	user, err := s.UserRepository.GetUserByPhoneNumber(hashPhoneNumber)
+	user, err := s.UserRepository.GetUserByPhoneNumber(s.Encryption.HashPhoneNumber(req.PhoneNumber))
 	if err != nil {
 		return nil, errors.New("phone Number is invalid")
 	}
@@ -459,16 +508,21 @@ func (s authService) LoginPhoneNumber(req *in.LoginPhoneNumber, deviceID string)
 		return nil, errors.New("pin Code is not set")
 	}
 
-	err = s.Encryption.CheckPassword(*user.PinCode, req.PinCode)
-	if err != nil {
-		if updateErr := s.UserRepository.UpdatePinAttempts(user.ClientID); updateErr != nil {
-			return nil, errors.New("invalid User")
-		}
-		return nil, errors.New("invalid Pin Code")
+	if err := s.checkPinCode(user, req.PinCode); err != nil {
+		return nil, err
 	}
 
-	if deviceID == "MOBILE" && req.DeviceID != "" && (user.DeviceID == nil || *user.DeviceID != req.DeviceID) {
-		hashDeviceID, err := s.Encryption.Encrypt(req.DeviceID)
+	if s.TwoFactorService.IsTotpEnabled(user.UserID) {
+		return s.startTwoFactorChallenge(user, utils.LoginMethodPhone, deviceID, req.DeviceID)
+	}
+
+	return s.phoneLoginResponse(user, deviceID, req.DeviceID)
+}
+
+// phoneLoginResponse issues tokens once every factor of a phone number login is verified
+func (s authService) phoneLoginResponse(user *models.Users, deviceID, reqDeviceID string) (interface{}, error) {
+	if deviceID == "MOBILE" && reqDeviceID != "" && utils.DerefStr(utils.DecryptOptionalString(user.DeviceID, s.Encryption)) != reqDeviceID {
+		hashDeviceID, err := s.Encryption.Encrypt(reqDeviceID)
 		if err != nil {
 			return nil, errors.New("device ID is invalid")
 		}
@@ -498,7 +552,7 @@ func (s authService) LoginPhoneNumber(req *in.LoginPhoneNumber, deviceID string)
 	}
 
 	_ = s.RedisService.SaveData(utils.Token, user.ClientID, token)
-	_ = s.RedisService.SaveData(utils.User, user.ClientID, user)
+	s.cacheUser(user)
 
 	var phoneNumber string
 	decrypt, err := s.Encryption.Decrypt(user.PhoneNumber)
@@ -517,7 +571,7 @@ func (s authService) LoginPhoneNumber(req *in.LoginPhoneNumber, deviceID string)
 		FirstName:      user.FirstName,
 		LastName:       user.LastName,
 		PhoneNumber:    phoneNumber,
-		Email:          user.Email,
+		Email:          s.decryptEmail(user),
 		DeviceID:       decryptDeviceID,
 		DeviceToken:    decryptDeviceToken,
 		ProfilePicture: user.ProfilePicture,
@@ -527,16 +581,77 @@ func (s authService) LoginPhoneNumber(req *in.LoginPhoneNumber, deviceID string)
 	return responses, nil
 }
 
+// startTwoFactorChallenge parks a verified first factor in Redis until the TOTP code is supplied
+func (s authService) startTwoFactorChallenge(user *models.Users, method, deviceID, reqDeviceID string) (interface{}, error) {
+	challenge := models.TwoFactorChallenge{
+		ChallengeID: uuid.New().String(),
+		ClientID:    user.ClientID,
+		Method:      method,
+		DeviceType:  deviceID,
+		DeviceID:    reqDeviceID,
+	}
+
+	if err := s.RedisService.SaveDataExpired(utils.TwoFactorChallenge, challenge.ChallengeID, utils.TwoFactorChallengeExpiry, challenge); err != nil {
+		return nil, errors.New("unable to start two-factor authentication")
+	}
+
+	return out.TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeID:       challenge.ChallengeID,
+		Method:            "totp",
+		ExpiresAt:         time.Now().Add(utils.TwoFactorChallengeExpiry * time.Minute).Unix(),
+	}, nil
+}
+
+func (s authService) VerifyTwoFactorLogin(req *in.TwoFactorLoginRequest, deviceID string) (interface{}, error) {
+	var challenge models.TwoFactorChallenge
+	if err := s.RedisService.GetData(utils.TwoFactorChallenge, req.ChallengeID, &challenge); err != nil {
+		return nil, errors.New("two-factor challenge is invalid or expired")
+	}
+
+	if challenge.DeviceType != deviceID {
+		return nil, errors.New("two-factor challenge is invalid or expired")
+	}
+
+	user, err := s.UserRepository.GetUserByClientID(challenge.ClientID)
+	if err != nil || user.UserID == 0 {
+		_ = s.RedisService.DeleteData(utils.TwoFactorChallenge, req.ChallengeID)
+		return nil, errors.New("user not found")
+	}
+
+	if err := s.TwoFactorService.VerifyTotp(user.UserID, req.Code); err != nil {
+		challenge.Attempts++
+		if challenge.Attempts >= utils.TwoFactorMaxAttempts {
+			_ = s.RedisService.DeleteData(utils.TwoFactorChallenge, req.ChallengeID)
+			return nil, errors.New("too many invalid two-factor codes, please login again")
+		}
+		_ = s.RedisService.SaveDataExpired(utils.TwoFactorChallenge, req.ChallengeID, utils.TwoFactorChallengeExpiry, challenge)
+		return nil, err
+	}
+
+	_ = s.RedisService.DeleteData(utils.TwoFactorChallenge, req.ChallengeID)
+
+	switch challenge.Method {
+	case utils.LoginMethodPassword:
+		s.LoginAttemptService.Reset(user.Username)
+		return s.passwordLoginResponse(user, challenge.DeviceType, challenge.DeviceID)
+	case utils.LoginMethodPhone:
+		return s.phoneLoginResponse(user, challenge.DeviceType, challenge.DeviceID)
+	default:
+		return nil, errors.New("unsupported login method")
+	}
+}
+
+// CompleteLogin issues tokens for a user already authenticated by another mechanism, such as a passkey
+func (s authService) CompleteLogin(user *models.Users, deviceID string) (interface{}, error) {
+	return s.passwordLoginResponse(user, deviceID, "")
+}
+
 func (s authService) ChangeDeviceID(req *struct {
 	PhoneNumber string `json:"phone_number" binding:"required"`
 	DeviceID    string `json:"device_id" binding:"required"`
 }) (interface{}, error) {
-	hashPhoneNumber, err := s.Encryption.Encrypt(req.PhoneNumber)
-	if err != nil {
-		return nil, errors.New("phone Number is invalid")
-	}
-
-	user, err := s.UserRepository.GetUserByPhoneNumber(hashPhoneNumber)
+	user, err := s.UserRepository.GetUserByPhoneNumber(s.Encryption.HashPhoneNumber(req.PhoneNumber))
 	if err != nil {
 		return nil, errors.New("phone Number is invalid")
 	}
@@ -576,12 +691,8 @@ func (s authService) VerifyDeviceID(req *struct {
 		return nil, errors.New("Pin Code is not set")
 	}
 
-	err = s.Encryption.CheckPassword(*user.PinCode, req.PinCode)
-	if err != nil {
-		if updateErr := s.UserRepository.UpdatePinAttempts(data.ClientID); updateErr != nil {
-			return nil, errors.New("Invalid User")
-		}
-		return nil, errors.New("Invalid Pin Code")
+	if err := s.checkPinCode(user, req.PinCode); err != nil {
+		return nil, err
 	}
 
 	user.DeviceID = data.DeviceID
@@ -602,7 +713,9 @@ func (s authService) VerifyDeviceID(req *struct {
 	}
 
 	user.DeviceID = &deviceID
+	user.DeviceToken = utils.DecryptOptionalString(user.DeviceToken, s.Encryption)
 	user.PhoneNumber = phoneNumber
+	user.Email = s.decryptEmail(user)
 
 	_ = s.RedisService.SaveData(utils.User, user.ClientID, user)
 
@@ -626,12 +739,8 @@ func (s authService) VerifyPinCode(req *struct {
 		return nil, errors.New("pin Code is not set")
 	}
 
-	err = s.Encryption.CheckPassword(*user.PinCode, req.PinCode)
-	if err != nil {
-		if updateErr := s.UserRepository.UpdatePinAttempts(data.ClientID); updateErr != nil {
-			return nil, errors.New("invalid User")
-		}
-		return nil, errors.New("invalid Pin Code")
+	if err := s.checkPinCode(user, req.PinCode); err != nil {
+		return nil, err
 	}
 
 	var requestID = uuid.New().String()
@@ -667,20 +776,26 @@ func (s authService) ChangePinCode(req *struct {
 		return errors.New("pin Code is not set")
 	}
 
-	err = s.Encryption.CheckPassword(*user.PinCode, req.OldPinCode)
-	if err != nil {
+	// the old PIN counts towards the lockout like any other PIN entry
+	if err := s.checkPinCode(user, req.OldPinCode); err != nil {
+		if errors.Is(err, utils.ErrPinLocked) {
+			return err
+		}
 		return errors.New("old Pin Code is incorrect")
 	}
 
+	if err := utils.ValidatePinCode(req.NewPinCode); err != nil {
+		return err
+	}
+	if s.Encryption.CheckPassword(*user.PinCode, req.NewPinCode) == nil {
+		return errors.New("old Pin and New Pin is same")
+	}
+
 	hashedNewPin, err := s.Encryption.HashPassword(req.NewPinCode)
 	if err != nil {
 		return errors.New("invalid Pin Code")
 	}
 
-	if hashedNewPin == user.PinCode {
-		return errors.New("old Pin and New Pin is same")
-	}
-
 	user.PinCode = hashedNewPin
 	user.PinLastUpdated = time.Now()
 	user.PinAttempts = 0
@@ -733,19 +848,31 @@ func (s authService) UpdateToken(userID uint, clientID string) (*models.TokenDet
 	if userRedis == nil {
 		return nil, errors.New("user not found")
 	}
-	_ = s.RedisService.SaveData(utils.Token, user.ClientID, token)
-	_ = s.RedisService.SaveData(utils.User, user.ClientID, userRedis)
 
-	userSession, err := s.UserSessionRepository.GetUserSessionByUserID(user.UserID)
-	if err != nil {
-		return nil, errors.New("user session not found")
+	// only the session holding the user's latest token is reissued, their other devices keep theirs
+	var userSession *models.UserSession
+	var previous models.TokenDetails
+	if err := s.RedisService.GetData(utils.Token, user.ClientID, &previous); err == nil && previous.AccessUUID != "" {
+		userSession, err = s.UserSessionRepository.GetUserSessionByAccessUUID(previous.AccessUUID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user session not found")
+		}
+		if userSession != nil && (userSession.UserID != user.UserID || !userSession.IsActive) {
+			userSession = nil
+		}
 	}
 
+	_ = s.RedisService.SaveData(utils.Token, user.ClientID, token)
+	userRedis.Email = utils.DecryptString(userRedis.Email, s.Encryption)
+	_ = s.RedisService.SaveData(utils.User, user.ClientID, userRedis)
+
 	if userSession == nil {
 		userSession = &models.UserSession{
 			UserID:       user.UserID,
-			SessionToken: token.AccessToken,
-			RefreshToken: token.RefreshToken,
+			SessionToken: s.TokenHasher.Hash(token.AccessToken),
+			RefreshToken: s.TokenHasher.Hash(token.RefreshToken),
+			TokensHashed: true,
+			AccessUUID:   token.AccessUUID,
 			ExpiresAt:    time.Unix(token.AtExpires, 0),
 			LoginTime:    time.Now(),
 			CreatedBy:    admin.ClientID,
@@ -756,8 +883,18 @@ func (s authService) UpdateToken(userID uint, clientID string) (*models.TokenDet
 			return nil, errors.New("unable to add session")
 		}
 	} else {
-		userSession.SessionToken = token.AccessToken
-		userSession.RefreshToken = token.RefreshToken
+		// the replaced tokens still carry the old resources
+		if err := s.TokenRevocation.RevokeToken(userSession.AccessUUID, userSession.ExpiresAt.Unix()); err != nil {
+			return nil, errors.New("unable to revoke previous token")
+		}
+		if err := s.RefreshTokenService.RevokeFamilyByToken(userSession.RefreshToken, "token_reissued", admin.ClientID); err != nil {
+			return nil, errors.New("unable to revoke previous refresh token")
+		}
+
+		userSession.SessionToken = s.TokenHasher.Hash(token.AccessToken)
+		userSession.RefreshToken = s.TokenHasher.Hash(token.RefreshToken)
+		userSession.TokensHashed = true
+		userSession.AccessUUID = token.AccessUUID
 		userSession.ExpiresAt = time.Unix(token.AtExpires, 0)
 		userSession.LoginTime = time.Now()
 		userSession.UpdatedBy = admin.ClientID
@@ -768,22 +905,36 @@ func (s authService) UpdateToken(userID uint, clientID string) (*models.TokenDet
 		}
 	}
 
+	if err := s.RefreshTokenService.StartFamily(user.UserID, token.RefreshToken, "", admin.ClientID); err != nil {
+		return nil, errors.New("unable to save refresh token")
+	}
+
 	return &token, nil
 }
 
 func (s authService) RefreshToken(req *struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
-}, id string) (interface{}, error) {
+}, id, ipAddress string) (interface{}, error) {
 	user, err := s.UserRepository.GetUserByClientID(id)
 	if err != nil {
 		return nil, errors.New("user not found")
 	}
 
-	userSession, err := s.UserSessionRepository.GetUserSessionByRefreshTokenAndUserID(user.UserID, req.RefreshToken)
-	if err != nil {
+	// a token issued to an OAuth client must keep its scope, so it is only accepted at /oauth/token
+	if oauthToken, err := s.RedisService.Exists(utils.OauthRefreshToken, s.TokenHasher.Hash(req.RefreshToken)); err != nil || oauthToken {
 		return nil, errors.New("invalid Refresh Token")
 	}
-	if userSession == nil {
+
+	userSession, err := s.UserSessionRepository.GetUserSessionByRefreshTokenAndUserID(user.UserID, s.TokenHasher.Hash(req.RefreshToken))
+	if err != nil || userSession == nil {
+		// a rotated token no longer matches a session, Consume still has to see it to catch a replay
+		if _, err := s.RefreshTokenService.Consume(req.RefreshToken, ipAddress); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("invalid Refresh Token")
+	}
+
+	if !userSession.IsActive {
 		return nil, errors.New("invalid Refresh Token")
 	}
 
@@ -791,9 +942,6 @@ func (s authService) RefreshToken(req *struct {
 		return nil, errors.New("refresh Token is expired")
 	}
 
-	_ = s.RedisService.DeleteData(utils.Token, user.ClientID)
-	_ = s.RedisService.DeleteData(utils.User, user.ClientID)
-
 	resource, err := s.ResourceRepository.GetResourceByUserID(user.UserID)
 	if err != nil {
 		return nil, errors.New("unable to get resource")
@@ -814,14 +962,28 @@ func (s authService) RefreshToken(req *struct {
 		return nil, errors.New("user or Password is incorrect")
 	}
 
+	// the presented token is retired only once everything else has been checked, so a failed
+	// request does not burn it; retiring it is still what catches a replay
+	refreshToken, err := s.RefreshTokenService.Consume(req.RefreshToken, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+	if refreshToken.UserID != user.UserID {
+		return nil, errors.New("invalid Refresh Token")
+	}
+
 	_ = s.RedisService.SaveData(utils.Token, user.ClientID, token)
-	_ = s.RedisService.SaveData(utils.User, user.ClientID, user)
+	s.cacheUser(user)
 
-	userSession.SessionToken = token.AccessToken
-	userSession.RefreshToken = token.RefreshToken
+	now := time.Now()
+	userSession.SessionToken = s.TokenHasher.Hash(token.AccessToken)
+	userSession.RefreshToken = s.TokenHasher.Hash(token.RefreshToken)
+	userSession.TokensHashed = true
+	userSession.AccessUUID = token.AccessUUID
 	userSession.ExpiresAt = time.Unix(token.AtExpires, 0)
-	userSession.LoginTime = time.Unix(token.AtExpires, 0)
-	userSession.UpdatedAt = time.Now()
+	userSession.IPAddress = ipAddress
+	userSession.LastSeenAt = &now
+	userSession.UpdatedAt = now
 	userSession.UpdatedBy = user.ClientID
 
 	err = s.UserSessionRepository.UpdateSession(userSession)
@@ -829,24 +991,9 @@ func (s authService) RefreshToken(req *struct {
 		return nil, errors.New("unable to update session")
 	}
 
-	return token, nil
-}
-
-func (s authService) RegisterInternalToken(req *struct {
-	ResourceName string `json:"resource_name" binding:"required"`
-}) (interface{}, error) {
-	resource, err := s.ResourceRepository.GetResourceByName(req.ResourceName)
-	if err != nil {
-		return nil, errors.New("Resource not found")
-	}
-
-	token, err := s.JWTService.GenerateInternalToken(resource.Name)
+	err = s.RefreshTokenService.IssueInFamily(refreshToken, token.RefreshToken, ipAddress)
 	if err != nil {
-		return nil, errors.New("Unable to generate token")
-	}
-
-	if err := s.AuthRepository.CreateInternalToken(resource.ResourceID, token); err != nil {
-		return nil, errors.New("Unable to create token")
+		return nil, errors.New("unable to save refresh token")
 	}
 
 	return token, nil
@@ -869,6 +1016,11 @@ func (s authService) UpdateRole(userID uint, roleID uint, clientID string) error
 	if err != nil {
 		return nil
 	}
+
+	// outstanding tokens still carry the previous role
+	if err := s.TokenRevocation.RevokeUserTokens(user.UserID); err != nil {
+		return errors.New("unable to revoke user tokens")
+	}
 	return nil
 }
 
@@ -919,22 +1071,32 @@ func (s authService) ChangePassword(password *struct {
 		return errors.New("user not found")
 	}
 
-	if err := utils.CheckPassword(user.Password, password.OldPassword); err != nil {
+	if err := s.Encryption.CheckPassword(user.Password, password.OldPassword); err != nil {
 		return errors.New("Old Password is incorrect")
 	}
 
-	hashedPassword, err := utils.HashPassword(password.NewPassword)
+	if err := s.validatePassword(user, "new_password", password.NewPassword); err != nil {
+		return err
+	}
+
+	if err := s.checkPasswordReuse(user, "new_password", password.NewPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.Encryption.HashPassword(password.NewPassword)
 	if err != nil {
 		return errors.New("Invalid Password")
 	}
 
-	user.Password = hashedPassword
+	user.Password = *hashedPassword
 	user.UpdatedBy = user.FullName
 	err = s.UserRepository.ChangePassword(user)
 	if err != nil {
 		return errors.New("Unable to change password")
 	}
-	return nil
+	s.recordPassword(user, user.ClientID)
+
+	return s.endUserSessions(user, "password_change")
 }
 
 func (s authService) ResetPinAttempts() {
@@ -949,9 +1111,187 @@ func (s authService) ResetPinAttempts() {
 	}
 }
 
+// ReencryptPII rewrites emails, phone numbers, device IDs, device tokens and TOTP secrets not yet
+// encrypted with AES-GCM under the newest key, and fills in missing blind indexes
+func (s authService) ReencryptPII() {
+	s.TwoFactorService.ReencryptTotpSecrets()
+
+	var reencrypted int
+	for {
+		users, err := s.UserRepository.GetUsersToReencrypt(utils.KeyVersionPrefix(s.Encryption.KeyVersion()), utils.ReencryptPIIBatchSize)
+		if err != nil {
+			logger.Error().Err(err).Msg("Error getting users to re-encrypt")
+			return
+		}
+
+		var updated int
+		for _, user := range *users {
+			if err := s.reencryptUserPII(&user); err != nil {
+				logger.Error().Err(err).Uint("user_id", user.UserID).Msg("Unable to re-encrypt personal data")
+				continue
+			}
+			if err := s.UserRepository.UpdateUserPII(&user); err != nil {
+				logger.Error().Err(err).Uint("user_id", user.UserID).Msg("Unable to update encrypted fields")
+				continue
+			}
+			updated++
+		}
+
+		reencrypted += updated
+		if len(*users) < utils.ReencryptPIIBatchSize || updated == 0 {
+			if reencrypted > 0 {
+				logger.Info().Int("users", reencrypted).Msg("Re-encrypted personal data")
+			}
+			return
+		}
+	}
+}
+
+// BackfillBlindIndexes fills in the email and phone number blind indexes of users created before they
+// existed, so lookups by email or phone number find them before ReencryptPII has run
+func (s authService) BackfillBlindIndexes() {
+	var filled int
+	var lastUserID uint
+	for {
+		users, err := s.UserRepository.GetUsersWithoutBlindIndex(lastUserID, utils.ReencryptPIIBatchSize)
+		if err != nil {
+			logger.Error().Err(err).Msg("Error getting users without blind index")
+			return
+		}
+
+		for _, user := range *users {
+			lastUserID = user.UserID
+			if err := s.fillBlindIndexes(&user); err != nil {
+				logger.Error().Err(err).Uint("user_id", user.UserID).Msg("Unable to compute blind index")
+				continue
+			}
+			if err := s.UserRepository.UpdateBlindIndexes(&user); err != nil {
+				logger.Error().Err(err).Uint("user_id", user.UserID).Msg("Unable to update blind index")
+				continue
+			}
+			filled++
+		}
+
+		if len(*users) < utils.ReencryptPIIBatchSize {
+			if filled > 0 {
+				logger.Info().Int("users", filled).Msg("Filled in blind indexes")
+			}
+			return
+		}
+	}
+}
+
+func (s authService) fillBlindIndexes(user *models.Users) error {
+	email, err := s.decryptStoredValue(user.Email)
+	if err != nil {
+		return err
+	}
+	emailIndex := s.Encryption.HashEmail(email)
+	user.EmailIndex = &emailIndex
+
+	if user.PhoneNumber != "" {
+		phoneNumber, err := s.decryptStoredValue(user.PhoneNumber)
+		if err != nil {
+			return err
+		}
+		phoneNumberIndex := s.Encryption.HashPhoneNumber(phoneNumber)
+		user.PhoneNumberIndex = &phoneNumberIndex
+	}
+	return nil
+}
+
+// reencryptUserPII encrypts the personal data of a user under the newest key and fills in the
+// blind indexes of their email and phone number
+func (s authService) reencryptUserPII(user *models.Users) error {
+	email, err := s.decryptStoredValue(user.Email)
+	if err != nil {
+		return err
+	}
+	emailIndex := s.Encryption.HashEmail(email)
+	user.EmailIndex = &emailIndex
+	if user.Email, err = s.reencryptValue(user.Email, email); err != nil {
+		return err
+	}
+
+	if user.PhoneNumber != "" {
+		phoneNumber, err := s.decryptStoredValue(user.PhoneNumber)
+		if err != nil {
+			return err
+		}
+		phoneNumberIndex := s.Encryption.HashPhoneNumber(phoneNumber)
+		user.PhoneNumberIndex = &phoneNumberIndex
+		if user.PhoneNumber, err = s.reencryptValue(user.PhoneNumber, phoneNumber); err != nil {
+			return err
+		}
+	}
+
+	if user.DeviceID, err = s.reencryptOptionalValue(user.DeviceID); err != nil {
+		return err
+	}
+	user.DeviceToken, err = s.reencryptOptionalValue(user.DeviceToken)
+	return err
+}
+
+func (s authService) reencryptOptionalValue(value *string) (*string, error) {
+	if value == nil || *value == "" {
+		return value, nil
+	}
+	plain, err := s.decryptStoredValue(*value)
+	if err != nil {
+		return nil, err
+	}
+	reencrypted, err := s.reencryptValue(*value, plain)
+	if err != nil {
+		return nil, err
+	}
+	return &reencrypted, nil
+}
+
+// reencryptValue encrypts the plain text of a stored value under the newest key, values already
+// encrypted with it are kept
+func (s authService) reencryptValue(stored, plain string) (string, error) {
+	if !s.Encryption.NeedsReencrypt(stored) {
+		return stored, nil
+	}
+	return s.Encryption.Encrypt(plain)
+}
+
+// decryptStoredValue reads a stored value with whichever scheme wrote it. Values that were never
+// encrypted, such as seeded rows and device tokens stored in plain text, are returned as they are.
+// A legacy value that does not decrypt to text is an error rather than taken for plain text.
+func (s authService) decryptStoredValue(value string) (string, error) {
+	decrypted, err := s.Encryption.Decrypt(value)
+	if errors.Is(err, utils.ErrNotCiphertext) {
+		return value, nil
+	}
+	return decrypted, err
+}
+
+// UnlockPinCode lets an admin lift a PIN lock before it expires
+func (s authService) UnlockPinCode(userID uint, clientID string) error {
+	admin, err := s.UserRepository.GetUserByClientID(clientID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+
+	user, err := s.UserRepository.GetUserByID(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+
+	if err := s.UserRepository.UnlockPinCode(user.UserID, admin.ClientID); err != nil {
+		return errors.New("unable to unlock pin code")
+	}
+	return nil
+}
+
+// ForgetPinCode sets a new PIN for a user who forgot theirs. A bearer token alone is not enough, the
+// account password has to be confirmed too. The current lock is lifted but the lock count is kept,
+// so a reset cannot be used to start the lockout escalation over.
 func (s authService) ForgetPinCode(req *struct {
-	Email   string `json:"email" binding:"required"`
-	PinCode string `json:"pin_code" binding:"required"`
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	PinCode  string `json:"pin_code" binding:"required"`
 }, clientID string) error {
 	user, err := s.UserRepository.GetUserByClientID(clientID)
 	if err != nil {
@@ -962,15 +1302,21 @@ func (s authService) ForgetPinCode(req *struct {
 		return errors.New("Email is invalid")
 	}
 
-	user, err = s.UserRepository.GetUserByEmail(req.Email)
-	if err != nil {
+	// only the caller's own PIN can be reset, and with it the lock; other users go through the admin unlock
+	emailUser, err := s.UserRepository.GetUserByEmail(s.Encryption.HashEmail(req.Email))
+	if err != nil || emailUser.UserID != user.UserID {
 		return errors.New("Email not found")
 	}
 
-	hashedPin, err := s.Encryption.HashPassword(req.PinCode)
+	if err := s.Encryption.CheckPassword(user.Password, req.Password); err != nil {
+		return errors.New("password is incorrect")
+	}
 
-	log.Printf("Pin Code: %s", req.PinCode)
-	log.Printf("Pin Code: %s", hashedPin)
+	if err := utils.ValidatePinCode(req.PinCode); err != nil {
+		return err
+	}
+
+	hashedPin, err := s.Encryption.HashPassword(req.PinCode)
 	if err != nil {
 		return errors.New("Invalid Pin Code")
 	}
@@ -978,13 +1324,14 @@ func (s authService) ForgetPinCode(req *struct {
 	user.PinCode = hashedPin
 	user.PinLastUpdated = time.Now()
 	user.PinAttempts = 0
+	user.LockedUntil = nil
 	user.UpdatedBy = user.ClientID
 	err = s.UserRepository.UpdateUser(user)
 	if err != nil {
 		return errors.New("Unable to update pin code")
 	}
 
-	_ = s.RedisService.SaveData(utils.User, user.ClientID, user)
+	s.cacheUser(user)
 
 	return nil
 }
@@ -1053,7 +1400,7 @@ func (s authService) RequestForgotPassword(req *struct {
 		return errors.New("email is invalid")
 	}
 
-	user, err := s.UserRepository.GetUserByEmail(req.Email)
+	user, err := s.UserRepository.GetUserByEmail(s.Encryption.HashEmail(req.Email))
 	if err != nil {
 		return errors.New("email not found")
 	}
@@ -1062,7 +1409,7 @@ func (s authService) RequestForgotPassword(req *struct {
 	_ = s.RedisService.SaveDataExpired(utils.ForgotPassword, requestID, 10, user)
 	// TODO: URL should be configurable
 	email := models.Email{
-		To:       user.Email,
+		To:       s.decryptEmail(user),
 		FullName: user.FullName,
 		Subject:  "Forgot Password Request",
 		URL:      "http://192.168.1.170:8000/v1/reset-redirect?request_id=" + requestID,
@@ -1089,22 +1436,226 @@ func (s authService) ResetPassword(req *struct {
 		return errors.New("invalid request ID")
 	}
 
-	checkuser, err := s.UserRepository.GetUserByEmail(user.Email)
-	if err != nil || checkuser.UserID != user.UserID {
+	checkuser, err := s.UserRepository.GetUserByID(user.UserID)
+	if err != nil || !strings.EqualFold(s.decryptEmail(checkuser), s.decryptEmail(user)) {
 		return errors.New("invalid user")
 	}
 
+	if err := s.validatePassword(checkuser, "new_password", req.NewPassword); err != nil {
+		return err
+	}
+
+	if err := s.checkPasswordReuse(checkuser, "new_password", req.NewPassword); err != nil {
+		return err
+	}
+
 	hashedPassword, err := s.Encryption.HashPassword(req.NewPassword)
 	if err != nil {
 		return errors.New("invalid Password")
 	}
 
-	user.Password = *hashedPassword
-	user.UpdatedBy = user.ClientID
+	// update the stored user, the cached copy lacks the fields that are never serialized
+	checkuser.Password = *hashedPassword
+	checkuser.UpdatedBy = checkuser.ClientID
 
-	if err := s.UserRepository.UpdateUser(user); err != nil {
+	if err := s.UserRepository.ChangePassword(checkuser); err != nil {
 		return errors.New("unable to update password")
 	}
+	s.recordPassword(checkuser, checkuser.ClientID)
+
+	return s.endUserSessions(checkuser, "password_reset")
+}
+
+// validatePassword checks a new password against the policy of the user's role
+func (s authService) validatePassword(user *models.Users, field, password string) error {
+	role, err := s.RoleRepository.GetRoleByID(user.RoleID)
+	if err != nil {
+		return errors.New("unable to get role")
+	}
+	return s.PasswordValidator.Validate(role.Name, field, password, utils.PasswordSubject{
+		Username: user.Username,
+		Email:    s.decryptEmail(user),
+	})
+}
+
+// decryptEmail returns the email address of a user, which is stored encrypted
+func (s authService) decryptEmail(user *models.Users) string {
+	return utils.DecryptString(user.Email, s.Encryption)
+}
+
+// cacheUser stores a copy of the user with the email decrypted, like the user key is read everywhere else
+func (s authService) cacheUser(user *models.Users) {
+	cached := *user
+	cached.Email = s.decryptEmail(user)
+	_ = s.RedisService.SaveData(utils.User, user.ClientID, cached)
+}
+
+// checkPasswordReuse rejects any of the last PasswordHistorySize passwords. The newest history entry
+// is the current password; users from before the history was kept are checked against it directly.
+func (s authService) checkPasswordReuse(user *models.Users, field, password string) error {
+	reused := &utils.PasswordPolicyError{Errors: []utils.FieldError{{
+		Field:   field,
+		Code:    "reused",
+		Message: fmt.Sprintf("must not match any of your last %d passwords", s.PasswordHistorySize),
+	}}}
+
+	history, err := s.PasswordHistoryRepository.GetPasswordHistory(user.UserID, s.PasswordHistorySize)
+	if err != nil {
+		return errors.New("unable to get password history")
+	}
+	if len(*history) == 0 && s.Encryption.CheckPassword(user.Password, password) == nil {
+		return reused
+	}
+	for _, previous := range *history {
+		if s.Encryption.CheckPassword(previous.Password, password) == nil {
+			return reused
+		}
+	}
+	return nil
+}
+
+// recordPassword adds the user's new password hash to their history and forgets the oldest ones
+func (s authService) recordPassword(user *models.Users, createdBy string) {
+	if err := s.PasswordHistoryRepository.AddPasswordHistory(&models.PasswordHistory{
+		UserID:    user.UserID,
+		Password:  user.Password,
+		CreatedBy: createdBy,
+	}); err != nil {
+		logger.Error().Err(err).Msg("Error recording password history")
+		return
+	}
+	if err := s.PasswordHistoryRepository.PrunePasswordHistory(user.UserID, s.PasswordHistorySize); err != nil {
+		logger.Error().Err(err).Msg("Error pruning password history")
+	}
+}
+
+// endUserSessions signs the user out everywhere: outstanding access tokens are rejected,
+// refresh tokens and API keys revoked and every session closed
+func (s authService) endUserSessions(user *models.Users, reason string) error {
+	if err := s.TokenRevocation.RevokeUserTokens(user.UserID); err != nil {
+		return errors.New("unable to revoke user tokens")
+	}
+
+	if err := s.ApiKeyService.RevokeAllUserApiKeys(user.UserID, user.ClientID); err != nil {
+		return err
+	}
+
+	if err := s.RefreshTokenService.RevokeUserTokens(user.UserID, reason, user.ClientID); err != nil {
+		return errors.New("unable to revoke refresh tokens")
+	}
+
+	userSessions, err := s.UserSessionRepository.GetActiveUserSessionsByUserID(user.UserID)
+	if err != nil {
+		return errors.New("unable to get user sessions")
+	}
+
+	now := time.Now()
+	for i := range *userSessions {
+		userSession := &(*userSessions)[i]
+		userSession.IsActive = false
+		userSession.LogoutTime = &now
+		userSession.UpdatedBy = user.ClientID
+		if err := s.UserSessionRepository.UpdateSession(userSession); err != nil {
+			return errors.New("unable to update session")
+		}
+	}
+
+	_ = s.RedisService.DeleteData(utils.UserSession, user.ClientID)
+	_ = s.RedisService.DeleteData(utils.Token, user.ClientID)
+	return nil
+}
+
+// checkPinCode verifies a PIN under the lockout policy: a locked PIN is refused without being checked,
+// wrong entries count towards the next lock and a correct one clears the escalation
+func (s authService) checkPinCode(user *models.Users, pinCode string) error {
+	if s.PinLockPolicy.IsLocked(user.LockedUntil) {
+		return utils.ErrPinLocked
+	}
 
+	if err := s.Encryption.CheckPassword(*user.PinCode, pinCode); err != nil {
+		attempts, updateErr := s.UserRepository.UpdatePinAttempts(user.ClientID)
+		if updateErr != nil {
+			return errors.New("invalid User")
+		}
+		if attempts >= s.PinLockPolicy.MaxAttempts {
+			s.lockPinCode(user)
+			return utils.ErrPinLocked
+		}
+		return errors.New("invalid Pin Code")
+	}
+
+	if user.PinAttempts > 0 || user.PinLockCount > 0 || user.LockedUntil != nil {
+		if err := s.UserRepository.UnlockPinCode(user.UserID, user.ClientID); err != nil {
+			logger.Error().Err(err).Msg("Error resetting pin attempts")
+		}
+	}
+	s.rehashPinCode(user, pinCode)
 	return nil
 }
+
+// rehashPassword upgrades a verified password whose hash is weaker than the configured target
+func (s authService) rehashPassword(user *models.Users, password string) {
+	if !s.Encryption.NeedsRehash(user.Password) {
+		return
+	}
+	hashed, err := s.Encryption.HashPassword(password)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error rehashing password")
+		return
+	}
+	if err := s.UserRepository.UpdatePasswordHash(user.UserID, *hashed); err != nil {
+		logger.Error().Err(err).Msg("Error rehashing password")
+		return
+	}
+	user.Password = *hashed
+}
+
+// rehashPinCode upgrades a verified PIN whose hash is weaker than the configured target
+func (s authService) rehashPinCode(user *models.Users, pinCode string) {
+	if !s.Encryption.NeedsRehash(*user.PinCode) {
+		return
+	}
+	hashed, err := s.Encryption.HashPassword(pinCode)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error rehashing pin code")
+		return
+	}
+	if err := s.UserRepository.UpdatePinHash(user.UserID, *hashed); err != nil {
+		logger.Error().Err(err).Msg("Error rehashing pin code")
+		return
+	}
+	user.PinCode = hashed
+}
+
+// lockPinCode locks the PIN for the window matching the number of earlier locks and tells the user
+func (s authService) lockPinCode(user *models.Users) {
+	duration := s.PinLockPolicy.LockDuration(user.PinLockCount)
+	lockedUntil := time.Now().Add(duration)
+	if err := s.UserRepository.LockPinCode(user.UserID, lockedUntil); err != nil {
+		logger.Error().Err(err).Msg("Error locking pin code")
+		return
+	}
+
+	if user.DeviceToken == nil {
+		logger.Debug().Uint("user_id", user.UserID).Msg("Device token is nil, skipping notification")
+		return
+	}
+
+	notification := models.Notification{
+		TargetToken:   utils.DerefStr(utils.DecryptOptionalString(user.DeviceToken, s.Encryption)),
+		Title:         "PIN Locked",
+		Body:          fmt.Sprintf("Your PIN has been locked until %s after too many wrong attempts", lockedUntil.Format("2006-01-02 15:04 MST")),
+		Priority:      "high",
+		Color:         "#E53935",
+		Platform:      "android",
+		ServiceSource: "authentication",
+		EventType:     utils.EventPinLocked,
+		ClickAction:   "OPEN_ACTIVITY",
+		Payload: map[string]string{
+			"locked_until": lockedUntil.Format(time.RFC3339),
+		},
+	}
+	if err := s.NatsService.RequestNotification("authentication", notification); err != nil {
+		logger.Error().Err(err).Msg("Error sending pin locked notification")
+	}
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"authentication/internal/models"
+	"authentication/internal/utils"
+	"authentication/internal/utils/redistest"
+	"testing"
+)
+
+func TestRequestForgotPasswordByEmailIndex(t *testing.T) {
+	enc := utils.NewEncryption(map[int]string{1: "encryption-key"}, "legacy-iv", "blind-index-key")
+	encrypted, err := enc.Encrypt("Alice@Example.com")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	emailIndex := enc.HashEmail("Alice@Example.com")
+	events := &securityEvents{}
+	service := authService{
+		UserRepository: newUserStore(models.Users{UserID: 7, ClientID: "client-7", Email: encrypted, EmailIndex: &emailIndex}),
+		RedisService:   redistest.NewMemory(),
+		Encryption:     enc,
+		NatsService:    events,
+	}
+
+	tests := []struct {
+		name    string
+		email   string
+		wantErr bool
+	}{
+		{"address as registered", "Alice@Example.com", false},
+		{"address in another case", "alice@example.COM", false},
+		{"unknown address", "bob@example.com", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events.emails = nil
+			err := service.RequestForgotPassword(&struct {
+				Email string `json:"email" binding:"required"`
+			}{Email: tt.email})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RequestForgotPassword() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(events.emails) != 1 || events.emails[0].To != "Alice@Example.com" {
+				t.Errorf("emails = %+v, want one to the decrypted address", events.emails)
+			}
+		})
+	}
+}
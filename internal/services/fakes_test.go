@@ -0,0 +1,217 @@
+package services
+
+import (
+	"authentication/internal/models"
+	"authentication/internal/repository"
+	"authentication/internal/utils"
+	"errors"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// userStore keeps users in memory. Repository methods a test does not reach fall through to the
+// embedded nil interface and panic, which points straight at the missing fake.
+type userStore struct {
+	repository.UserRepository
+	users map[uint]*models.Users
+}
+
+func newUserStore(users ...models.Users) *userStore {
+	store := &userStore{users: make(map[uint]*models.Users)}
+	for i := range users {
+		store.users[users[i].UserID] = &users[i]
+	}
+	return store
+}
+
+func (r *userStore) GetUserByID(id uint) (*models.Users, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *userStore) GetUserByClientID(clientID string) (*models.Users, error) {
+	for _, user := range r.users {
+		if user.ClientID == clientID {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *userStore) GetUserByEmail(emailIndex string) (*models.Users, error) {
+	for _, user := range r.users {
+		if user.EmailIndex != nil && *user.EmailIndex == emailIndex {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *userStore) UpdateUser(user *models.Users) error {
+	copied := *user
+	r.users[user.UserID] = &copied
+	return nil
+}
+
+func (r *userStore) ChangePassword(user *models.Users) error {
+	return r.UpdateUser(user)
+}
+
+func (r *userStore) UpdatePasswordHash(userID uint, hash string) error {
+	r.users[userID].Password = hash
+	return nil
+}
+
+func (r *userStore) UpdatePinHash(userID uint, hash string) error {
+	r.users[userID].PinCode = &hash
+	return nil
+}
+
+func (r *userStore) UpdatePinAttempts(clientID string) (int, error) {
+	for _, user := range r.users {
+		if user.ClientID == clientID {
+			user.PinAttempts++
+			return user.PinAttempts, nil
+		}
+	}
+	return 0, gorm.ErrRecordNotFound
+}
+
+func (r *userStore) LockPinCode(userID uint, lockedUntil time.Time) error {
+	user := r.users[userID]
+	user.PinAttempts = 0
+	user.PinLockCount++
+	user.LockedUntil = &lockedUntil
+	return nil
+}
+
+func (r *userStore) UnlockPinCode(userID uint, updatedBy string) error {
+	user := r.users[userID]
+	user.PinAttempts = 0
+	user.PinLockCount = 0
+	user.LockedUntil = nil
+	user.UpdatedBy = updatedBy
+	return nil
+}
+
+// sessionStore keeps user sessions in memory
+type sessionStore struct {
+	repository.UserSessionRepository
+	sessions []*models.UserSession
+}
+
+func (r *sessionStore) AddUserSession(session *models.UserSession) error {
+	session.UserSessionID = uint(len(r.sessions) + 1)
+	copied := *session
+	r.sessions = append(r.sessions, &copied)
+	return nil
+}
+
+func (r *sessionStore) UpdateSession(session *models.UserSession) error {
+	copied := *session
+	r.sessions[session.UserSessionID-1] = &copied
+	return nil
+}
+
+func (r *sessionStore) GetUserSessionByAccessUUID(accessUUID string) (*models.UserSession, error) {
+	for _, session := range r.sessions {
+		if session.AccessUUID == accessUUID {
+			copied := *session
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *sessionStore) GetActiveUserSessionsByUserID(userID uint) (*[]models.UserSession, error) {
+	var sessions []models.UserSession
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.IsActive {
+			sessions = append(sessions, *session)
+		}
+	}
+	return &sessions, nil
+}
+
+func (r *sessionStore) GetUserSessionByRefreshTokenAndUserID(userID uint, refreshToken string) (*models.UserSession, error) {
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.RefreshToken == refreshToken {
+			copied := *session
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// roleStore returns the same role for every role ID
+type roleStore struct {
+	repository.RoleRepository
+	name string
+}
+
+func (r roleStore) GetRoleByID(id uint) (*models.Role, error) {
+	return &models.Role{RoleID: id, Name: r.name}, nil
+}
+
+// plainHasher stands in for password hashing with a reversible prefix, so tests stay fast, and
+// keeps encrypted columns in the clear
+type plainHasher struct {
+	utils.Encryption
+}
+
+func (plainHasher) Encrypt(text string) (string, error) {
+	return text, nil
+}
+
+func (plainHasher) Decrypt(encryptedText string) (string, error) {
+	return encryptedText, nil
+}
+
+func (plainHasher) HashEmail(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func (plainHasher) HashPassword(password string) (*string, error) {
+	hashed := "hashed:" + password
+	return &hashed, nil
+}
+
+func (plainHasher) CheckPassword(hash, password string) error {
+	if hash != "hashed:"+password {
+		return errors.New("password does not match")
+	}
+	return nil
+}
+
+func (plainHasher) NeedsRehash(hash string) bool {
+	return false
+}
+
+// securityEvents records what a service publishes to NATS
+type securityEvents struct {
+	events        []models.SecurityEvent
+	notifications []models.Notification
+	emails        []models.Email
+}
+
+func (n *securityEvents) RequestNotification(_ string, notification models.Notification) error {
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+func (n *securityEvents) PublishEmail(_ string, email models.Email) error {
+	n.emails = append(n.emails, email)
+	return nil
+}
+
+func (n *securityEvents) PublishSecurityEvent(_ string, event models.SecurityEvent) error {
+	n.events = append(n.events, event)
+	return nil
+}
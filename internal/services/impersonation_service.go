@@ -0,0 +1,183 @@
+package services
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/dto/out"
+	"authentication/internal/models"
+	"authentication/internal/repository"
+	"authentication/internal/utils"
+	"authentication/internal/utils/logger"
+	"errors"
+	"strings"
+	"time"
+)
+
+type ImpersonationService interface {
+	Exchange(req *in.TokenExchangeRequest, clientID, ipAddress, userAgent string) (out.TokenExchangeResponse, error)
+	RecordWrite(claims *utils.TokenClaims, method, path string, status int, ipAddress, userAgent string)
+	GetAudits(filter in.ImpersonationAuditFilter, index, size int) ([]out.ImpersonationAuditResponse, int64, error)
+}
+
+type impersonationService struct {
+	ImpersonationAuditRepository repository.ImpersonationAuditRepository
+	UserRepository               repository.UserRepository
+	RoleRepository               repository.RoleRepository
+	ResourceRepository           repository.ResourceRepository
+	JWTService                   utils.JWTService
+	TokenTTL                     time.Duration
+}
+
+func NewImpersonationService(
+	impersonationAuditRepo repository.ImpersonationAuditRepository,
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	resourceRepo repository.ResourceRepository,
+	jwtService utils.JWTService,
+	tokenTTL time.Duration,
+) ImpersonationService {
+	return impersonationService{
+		ImpersonationAuditRepository: impersonationAuditRepo,
+		UserRepository:               userRepo,
+		RoleRepository:               roleRepo,
+		ResourceRepository:           resourceRepo,
+		JWTService:                   jwtService,
+		TokenTTL:                     tokenTTL,
+	}
+}
+
+// Exchange lets a Super Admin obtain a short-lived access token for another user. The subject
+// token is the target user's ID; errors are OAuth errors.
+func (s impersonationService) Exchange(req *in.TokenExchangeRequest, clientID, ipAddress, userAgent string) (out.TokenExchangeResponse, error) {
+	if req.GrantType != utils.GrantTypeTokenExchange {
+		return out.TokenExchangeResponse{}, utils.NewOAuthError(utils.OAuthErrUnsupportedGrantType, "grant_type must be "+utils.GrantTypeTokenExchange)
+	}
+	if req.SubjectTokenType != utils.TokenTypeUserID {
+		return out.TokenExchangeResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidRequest, "subject_token_type must be "+utils.TokenTypeUserID)
+	}
+	if req.RequestedTokenType != "" && req.RequestedTokenType != utils.TokenTypeAccessToken {
+		return out.TokenExchangeResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidRequest, "only access tokens can be requested")
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return out.TokenExchangeResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidRequest, "reason is required")
+	}
+	subjectID, err := utils.ConvertToUint(req.SubjectToken)
+	if err != nil {
+		return out.TokenExchangeResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidRequest, "subject_token must be a user ID")
+	}
+
+	actor, err := s.UserRepository.GetUserByClientID(clientID)
+	if err != nil {
+		return out.TokenExchangeResponse{}, utils.NewOAuthError(utils.OAuthErrAccessDenied, "user not found")
+	}
+	actorRole, err := s.RoleRepository.GetRoleByID(actor.RoleID)
+	if err != nil || !strings.EqualFold(actorRole.Name, utils.RoleSuperAdmin) {
+		return out.TokenExchangeResponse{}, utils.NewOAuthError(utils.OAuthErrAccessDenied, "only a Super Admin can impersonate users")
+	}
+
+	subject, err := s.UserRepository.GetUserByID(subjectID)
+	if err != nil {
+		return out.TokenExchangeResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidRequest, "user not found")
+	}
+	if subject.UserID == actor.UserID {
+		return out.TokenExchangeResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidRequest, "you cannot impersonate yourself")
+	}
+	subjectRole, err := s.RoleRepository.GetRoleByID(subject.RoleID)
+	if err != nil {
+		return out.TokenExchangeResponse{}, utils.NewOAuthError(utils.OAuthErrServerError, "unable to get role")
+	}
+	// admins are not impersonated, so an impersonated token never carries admin rights
+	if strings.EqualFold(subjectRole.Name, "Admin") || strings.EqualFold(subjectRole.Name, utils.RoleSuperAdmin) {
+		return out.TokenExchangeResponse{}, utils.NewOAuthError(utils.OAuthErrAccessDenied, "admins cannot be impersonated")
+	}
+
+	resource, err := s.ResourceRepository.GetResourceByUserID(subject.UserID)
+	if err != nil {
+		return out.TokenExchangeResponse{}, utils.NewOAuthError(utils.OAuthErrServerError, "unable to get resource")
+	}
+	var resourceName []string
+	for _, res := range *resource {
+		resourceName = append(resourceName, res.Name)
+	}
+
+	token, err := s.JWTService.GenerateImpersonationToken(*subject, resourceName, subjectRole.Name, utils.Actor{
+		Sub:    actor.ClientID,
+		UserID: actor.UserID,
+	}, s.TokenTTL)
+	if err != nil {
+		return out.TokenExchangeResponse{}, utils.NewOAuthError(utils.OAuthErrServerError, "unable to generate token")
+	}
+
+	// no audit record, no token
+	audit := &models.ImpersonationAudit{
+		AccessUUID:    token.AccessUUID,
+		ActorUserID:   actor.UserID,
+		ActorClientID: actor.ClientID,
+		SubjectUserID: subject.UserID,
+		Action:        utils.ImpersonationActionTokenExchange,
+		Reason:        req.Reason,
+		IPAddress:     ipAddress,
+		UserAgent:     userAgent,
+	}
+	if err := s.ImpersonationAuditRepository.AddImpersonationAudit(audit); err != nil {
+		return out.TokenExchangeResponse{}, utils.NewOAuthError(utils.OAuthErrServerError, "unable to record impersonation")
+	}
+
+	logger.Info().
+		Uint("actor_user_id", actor.UserID).
+		Uint("subject_user_id", subject.UserID).
+		Str("access_uuid", token.AccessUUID).
+		Msg("Impersonation token issued")
+
+	return out.TokenExchangeResponse{
+		AccessToken:     token.AccessToken,
+		IssuedTokenType: utils.TokenTypeAccessToken,
+		TokenType:       "Bearer",
+		ExpiresIn:       token.AtExpires - time.Now().Unix(),
+	}, nil
+}
+
+// RecordWrite stores a state-changing request made with an impersonated token
+func (s impersonationService) RecordWrite(claims *utils.TokenClaims, method, path string, status int, ipAddress, userAgent string) {
+	audit := &models.ImpersonationAudit{
+		AccessUUID:    claims.AccessUUID,
+		ActorUserID:   claims.Actor.UserID,
+		ActorClientID: claims.Actor.Sub,
+		SubjectUserID: claims.UserID,
+		Action:        utils.ImpersonationActionWrite,
+		Method:        method,
+		Path:          path,
+		Status:        status,
+		IPAddress:     ipAddress,
+		UserAgent:     userAgent,
+	}
+	if err := s.ImpersonationAuditRepository.AddImpersonationAudit(audit); err != nil {
+		logger.Error().Err(err).Str("access_uuid", claims.AccessUUID).Msg("Error recording impersonated write")
+	}
+}
+
+func (s impersonationService) GetAudits(filter in.ImpersonationAuditFilter, index, size int) ([]out.ImpersonationAuditResponse, int64, error) {
+	audits, total, err := s.ImpersonationAuditRepository.GetImpersonationAuditsByFilter(filter, index, size)
+	if err != nil {
+		return nil, 0, errors.New("unable to get impersonation audits")
+	}
+
+	responses := make([]out.ImpersonationAuditResponse, 0, len(*audits))
+	for _, audit := range *audits {
+		responses = append(responses, out.ImpersonationAuditResponse{
+			ID:            audit.ID,
+			AccessUUID:    audit.AccessUUID,
+			ActorUserID:   audit.ActorUserID,
+			ActorClientID: audit.ActorClientID,
+			SubjectUserID: audit.SubjectUserID,
+			Action:        audit.Action,
+			Method:        audit.Method,
+			Path:          audit.Path,
+			Status:        audit.Status,
+			Reason:        audit.Reason,
+			IPAddress:     audit.IPAddress,
+			UserAgent:     audit.UserAgent,
+			CreatedAt:     audit.CreatedAt,
+		})
+	}
+	return responses, total, nil
+}
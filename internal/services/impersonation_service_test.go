@@ -0,0 +1,122 @@
+package services
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/models"
+	"authentication/internal/repository"
+	"authentication/internal/utils"
+	"errors"
+	"testing"
+	"time"
+)
+
+// impersonationAudits records audit rows in memory
+type impersonationAudits struct {
+	repository.ImpersonationAuditRepository
+	rows []models.ImpersonationAudit
+}
+
+func (r *impersonationAudits) AddImpersonationAudit(audit *models.ImpersonationAudit) error {
+	r.rows = append(r.rows, *audit)
+	return nil
+}
+
+// roleTable names roles by ID
+type roleTable struct {
+	repository.RoleRepository
+	names map[uint]string
+}
+
+func (r roleTable) GetRoleByID(id uint) (*models.Role, error) {
+	return &models.Role{RoleID: id, Name: r.names[id]}, nil
+}
+
+func exchangeRequest(subject, reason string) *in.TokenExchangeRequest {
+	return &in.TokenExchangeRequest{
+		GrantType:        utils.GrantTypeTokenExchange,
+		SubjectToken:     subject,
+		SubjectTokenType: utils.TokenTypeUserID,
+		Reason:           reason,
+	}
+}
+
+func TestImpersonationExchange(t *testing.T) {
+	users := newUserStore(
+		models.Users{UserID: 1, ClientID: "super-admin-1", RoleID: 1},
+		models.Users{UserID: 2, ClientID: "admin-2", RoleID: 2},
+		models.Users{UserID: 3, ClientID: "super-admin-3", RoleID: 1},
+		models.Users{UserID: 7, ClientID: "client-7", RoleID: 3},
+	)
+	roles := roleTable{names: map[uint]string{1: utils.RoleSuperAdmin, 2: "Admin", 3: "User"}}
+	jwtService := utils.NewJWTService("jwt-secret", nil, "authentication")
+
+	tests := []struct {
+		name     string
+		clientID string
+		req      *in.TokenExchangeRequest
+		wantCode string
+	}{
+		{"another admin", "super-admin-1", exchangeRequest("2", "support ticket 42"), utils.OAuthErrAccessDenied},
+		{"another Super Admin", "super-admin-1", exchangeRequest("3", "support ticket 42"), utils.OAuthErrAccessDenied},
+		{"themselves", "super-admin-1", exchangeRequest("1", "support ticket 42"), utils.OAuthErrInvalidRequest},
+		{"by an Admin", "admin-2", exchangeRequest("7", "support ticket 42"), utils.OAuthErrAccessDenied},
+		{"without a reason", "super-admin-1", exchangeRequest("7", " "), utils.OAuthErrInvalidRequest},
+		{"unknown user", "super-admin-1", exchangeRequest("99", "support ticket 42"), utils.OAuthErrInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audits := &impersonationAudits{}
+			service := NewImpersonationService(audits, users, roles, userResources{}, jwtService, 15*time.Minute)
+
+			_, err := service.Exchange(tt.req, tt.clientID, "10.0.0.1", "curl")
+			var oauthErr *utils.OAuthError
+			if !errors.As(err, &oauthErr) || oauthErr.Code != tt.wantCode {
+				t.Fatalf("Exchange() error = %v, want %s", err, tt.wantCode)
+			}
+			if len(audits.rows) != 0 {
+				t.Errorf("Exchange() wrote %d audit rows for a refused exchange", len(audits.rows))
+			}
+		})
+	}
+
+	t.Run("regular user", func(t *testing.T) {
+		audits := &impersonationAudits{}
+		service := NewImpersonationService(audits, users, roles, userResources{names: map[uint][]string{7: {"auth"}}}, jwtService, 15*time.Minute)
+
+		issued, err := service.Exchange(exchangeRequest("7", "support ticket 42"), "super-admin-1", "10.0.0.1", "curl")
+		if err != nil {
+			t.Fatalf("Exchange() error = %v", err)
+		}
+		if issued.ExpiresIn <= 0 || issued.ExpiresIn > int64((15*time.Minute).Seconds()) {
+			t.Errorf("ExpiresIn = %d, want at most the impersonation TTL", issued.ExpiresIn)
+		}
+
+		claims, err := jwtService.ExtractClaims(issued.AccessToken)
+		if err != nil {
+			t.Fatalf("ExtractClaims() error = %v", err)
+		}
+		if claims.UserID != 7 || claims.Actor == nil || claims.Actor.UserID != 1 || claims.Actor.Sub != "super-admin-1" {
+			t.Errorf("claims = %+v, want user 7 acted on by super-admin-1", claims)
+		}
+		if _, err := jwtService.ValidateTokenAdmin(issued.AccessToken); err == nil {
+			t.Error("ValidateTokenAdmin() accepted an impersonated token")
+		}
+
+		service.RecordWrite(claims, "POST", "/v1/change-profile", 200, "10.0.0.1", "curl")
+		want := []models.ImpersonationAudit{
+			{AccessUUID: claims.AccessUUID, ActorUserID: 1, ActorClientID: "super-admin-1", SubjectUserID: 7,
+				Action: utils.ImpersonationActionTokenExchange, Reason: "support ticket 42", IPAddress: "10.0.0.1", UserAgent: "curl"},
+			{AccessUUID: claims.AccessUUID, ActorUserID: 1, ActorClientID: "super-admin-1", SubjectUserID: 7,
+				Action: utils.ImpersonationActionWrite, Method: "POST", Path: "/v1/change-profile", Status: 200, IPAddress: "10.0.0.1", UserAgent: "curl"},
+		}
+		if len(audits.rows) != len(want) {
+			t.Fatalf("audit rows = %+v, want %+v", audits.rows, want)
+		}
+		for i := range want {
+			if audits.rows[i] != want[i] {
+				t.Errorf("audit row %d = %+v, want %+v", i, audits.rows[i], want[i])
+			}
+		}
+	})
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"authentication/internal/dto/out"
+	"authentication/internal/models"
+	"authentication/internal/repository"
+	"authentication/internal/utils"
+	"crypto/subtle"
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+type InternalTokenService interface {
+	CreateToken(resourceID uint, clientID string) (out.InternalTokenResponse, error)
+	GetTokens(resourceID uint) ([]out.InternalTokenResponse, error)
+	RotateToken(id uint, clientID string) (out.InternalTokenResponse, error)
+	RevokeToken(id uint, clientID string) error
+	ValidateToken(token string) (*utils.InternalClaims, error)
+}
+
+type internalTokenService struct {
+	InternalTokenRepository repository.InternalTokenRepository
+	ResourceRepository      repository.ResourceRepository
+	JWTService              utils.JWTService
+	TokenHasher             utils.TokenHasher
+	TTL                     time.Duration
+	RotationGrace           time.Duration
+	Audience                string
+}
+
+func NewInternalTokenService(
+	internalTokenRepo repository.InternalTokenRepository,
+	resourceRepo repository.ResourceRepository,
+	jwtService utils.JWTService,
+	tokenHasher utils.TokenHasher,
+	ttl time.Duration,
+	rotationGrace time.Duration,
+	audience string,
+) InternalTokenService {
+	return internalTokenService{
+		InternalTokenRepository: internalTokenRepo,
+		ResourceRepository:      resourceRepo,
+		JWTService:              jwtService,
+		TokenHasher:             tokenHasher,
+		TTL:                     ttl,
+		RotationGrace:           rotationGrace,
+		Audience:                audience,
+	}
+}
+
+// CreateToken mints a token for the resource's service. The token itself is only returned here.
+func (s internalTokenService) CreateToken(resourceID uint, clientID string) (out.InternalTokenResponse, error) {
+	resource, err := s.ResourceRepository.GetResourceByID(resourceID)
+	if err != nil {
+		return out.InternalTokenResponse{}, errors.New("resource not found")
+	}
+
+	return s.issueToken(resource, clientID)
+}
+
+func (s internalTokenService) GetTokens(resourceID uint) ([]out.InternalTokenResponse, error) {
+	if _, err := s.ResourceRepository.GetResourceByID(resourceID); err != nil {
+		return nil, errors.New("resource not found")
+	}
+
+	tokens, err := s.InternalTokenRepository.GetInternalTokensByResourceID(resourceID)
+	if err != nil {
+		return nil, errors.New("unable to get internal tokens")
+	}
+
+	responses := make([]out.InternalTokenResponse, 0, len(*tokens))
+	for i := range *tokens {
+		responses = append(responses, internalTokenResponse(&(*tokens)[i]))
+	}
+	return responses, nil
+}
+
+// RotateToken mints a replacement token and lets the old one live for the rotation grace period,
+// so the service can roll out the new token without failing calls in between.
+func (s internalTokenService) RotateToken(id uint, clientID string) (out.InternalTokenResponse, error) {
+	token, err := s.InternalTokenRepository.GetInternalTokenByID(id)
+	if err != nil {
+		return out.InternalTokenResponse{}, errors.New("internal token not found")
+	}
+	if internalTokenStatus(token) != utils.InternalTokenStatusActive {
+		return out.InternalTokenResponse{}, errors.New("only active internal tokens can be rotated")
+	}
+
+	resource, err := s.ResourceRepository.GetResourceByID(token.ResourceID)
+	if err != nil {
+		return out.InternalTokenResponse{}, errors.New("resource not found")
+	}
+
+	rotated, err := s.issueToken(resource, clientID)
+	if err != nil {
+		return out.InternalTokenResponse{}, err
+	}
+
+	if graceEnd := time.Now().Add(s.RotationGrace); graceEnd.Before(token.ExpiresAt) {
+		token.ExpiresAt = graceEnd
+	}
+	token.UpdatedBy = clientID
+	if err := s.InternalTokenRepository.UpdateInternalToken(token); err != nil {
+		return out.InternalTokenResponse{}, errors.New("unable to update internal token")
+	}
+
+	return rotated, nil
+}
+
+func (s internalTokenService) RevokeToken(id uint, clientID string) error {
+	token, err := s.InternalTokenRepository.GetInternalTokenByID(id)
+	if err != nil {
+		return errors.New("internal token not found")
+	}
+
+	token.Expired = true
+	token.UpdatedBy = clientID
+	if err := s.InternalTokenRepository.UpdateInternalToken(token); err != nil {
+		return errors.New("unable to revoke internal token")
+	}
+	return nil
+}
+
+// ValidateToken checks the token's signature, audience and its stored record. Tokens that are not
+// ours return ErrInternalTokenInvalid, revoked or expired ones ErrInternalTokenRevoked.
+func (s internalTokenService) ValidateToken(token string) (*utils.InternalClaims, error) {
+	claims, err := s.JWTService.ValidateInternalToken(token)
+	if err != nil {
+		return nil, utils.ErrInternalTokenInvalid
+	}
+	if !claims.VerifyAudience(s.Audience, true) {
+		return nil, utils.ErrInternalTokenInvalid
+	}
+
+	record, err := s.InternalTokenRepository.GetInternalTokenByTokenID(claims.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrInternalTokenRevoked
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(record.Token), []byte(s.TokenHasher.Hash(token))) != 1 {
+		return nil, utils.ErrInternalTokenInvalid
+	}
+	if internalTokenStatus(record) != utils.InternalTokenStatusActive {
+		return nil, utils.ErrInternalTokenRevoked
+	}
+
+	return claims, nil
+}
+
+func (s internalTokenService) issueToken(resource *models.Resource, clientID string) (out.InternalTokenResponse, error) {
+	tokenID := uuid.New().String()
+	expiresAt := time.Now().Add(s.TTL)
+
+	signed, err := s.JWTService.GenerateInternalToken(resource.Name, tokenID, []string{s.Audience}, expiresAt)
+	if err != nil {
+		return out.InternalTokenResponse{}, errors.New("unable to generate internal token")
+	}
+
+	token := &models.InternalToken{
+		ResourceID: resource.ResourceID,
+		TokenID:    tokenID,
+		Token:      s.TokenHasher.Hash(signed),
+		ExpiresAt:  expiresAt,
+		CreatedBy:  clientID,
+		UpdatedBy:  clientID,
+	}
+	if err := s.InternalTokenRepository.AddInternalToken(token); err != nil {
+		return out.InternalTokenResponse{}, errors.New("unable to create internal token")
+	}
+
+	response := internalTokenResponse(token)
+	response.Token = signed
+	return response, nil
+}
+
+func internalTokenStatus(token *models.InternalToken) string {
+	if token.Expired {
+		return utils.InternalTokenStatusRevoked
+	}
+	if !time.Now().Before(token.ExpiresAt) {
+		return utils.InternalTokenStatusExpired
+	}
+	return utils.InternalTokenStatusActive
+}
+
+func internalTokenResponse(token *models.InternalToken) out.InternalTokenResponse {
+	return out.InternalTokenResponse{
+		ID:         token.ID,
+		ResourceID: token.ResourceID,
+		TokenID:    token.TokenID,
+		Status:     internalTokenStatus(token),
+		ExpiresAt:  token.ExpiresAt,
+		CreatedAt:  token.CreatedAt,
+		CreatedBy:  token.CreatedBy,
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/dto/out"
+	"authentication/internal/repository"
+	"authentication/internal/utils"
+	"errors"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type IntrospectionService interface {
+	Introspect(req *in.IntrospectionRequest) (out.IntrospectionResponse, error)
+}
+
+type introspectionService struct {
+	JWTService            utils.JWTService
+	TokenRevocation       utils.TokenRevocation
+	UserSessionRepository repository.UserSessionRepository
+	RoleRepository        repository.RoleRepository
+	ApiKeyService         ApiKeyService
+}
+
+func NewIntrospectionService(
+	jwtService utils.JWTService,
+	tokenRevocation utils.TokenRevocation,
+	userSessionRepo repository.UserSessionRepository,
+	roleRepo repository.RoleRepository,
+	apiKeyService ApiKeyService,
+) IntrospectionService {
+	return introspectionService{
+		JWTService:            jwtService,
+		TokenRevocation:       tokenRevocation,
+		UserSessionRepository: userSessionRepo,
+		RoleRepository:        roleRepo,
+		ApiKeyService:         apiKeyService,
+	}
+}
+
+// Introspect reports whether a user access token or API key is still usable. Besides the signature,
+// an access token must not be revoked and its session must still be active. Errors are only returned
+// when that cannot be decided, never for an inactive token.
+func (s introspectionService) Introspect(req *in.IntrospectionRequest) (out.IntrospectionResponse, error) {
+	// the hint only saves a lookup, API keys are recognised by their prefix either way
+	if _, ok := utils.ParseApiKeyPrefix(req.Token); ok {
+		return s.introspectApiKey(req.Token)
+	}
+	return s.introspectAccessToken(req.Token)
+}
+
+func (s introspectionService) introspectAccessToken(token string) (out.IntrospectionResponse, error) {
+	inactive := out.IntrospectionResponse{Active: false}
+
+	claims, err := s.JWTService.ExtractClaims(token)
+	if err != nil || !claims.Authorized || claims.ClientID == "" || claims.Exp < utils.GetCurrentTime() {
+		return inactive, nil
+	}
+
+	revoked, err := s.TokenRevocation.IsRevoked(claims)
+	if err != nil {
+		return out.IntrospectionResponse{}, err
+	}
+	if revoked {
+		return inactive, nil
+	}
+
+	// impersonated tokens are short-lived and have no session, only revocation applies to them
+	if claims.Actor != nil {
+		response := s.activeResponse(claims)
+		response.TokenType = "Bearer"
+		response.Jti = claims.AccessUUID
+		response.SessionStatus = utils.SessionStatusImpersonation
+		response.Act = &out.IntrospectionActor{Sub: claims.Actor.Sub, UserID: claims.Actor.UserID}
+		return response, nil
+	}
+
+	session, err := s.UserSessionRepository.GetUserSessionByAccessUUID(claims.AccessUUID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return inactive, nil
+	}
+	if err != nil {
+		return out.IntrospectionResponse{}, err
+	}
+	if session.UserID != claims.UserID || !session.IsActive || !time.Now().Before(session.ExpiresAt) {
+		return inactive, nil
+	}
+
+	response := s.activeResponse(claims)
+	response.TokenType = "Bearer"
+	response.Jti = claims.AccessUUID
+	response.SessionStatus = utils.SessionStatusActive
+	response.SessionID = session.UserSessionID
+	response.SessionExpiresAt = &session.ExpiresAt
+	return response, nil
+}
+
+func (s introspectionService) introspectApiKey(key string) (out.IntrospectionResponse, error) {
+	claims, err := s.ApiKeyService.Authenticate(key)
+	if errors.Is(err, utils.ErrApiKeyInvalid) || errors.Is(err, utils.ErrApiKeyRevoked) {
+		return out.IntrospectionResponse{Active: false}, nil
+	}
+	if err != nil {
+		return out.IntrospectionResponse{}, err
+	}
+
+	revoked, err := s.TokenRevocation.IsRevoked(claims)
+	if err != nil {
+		return out.IntrospectionResponse{}, err
+	}
+	if revoked {
+		return out.IntrospectionResponse{Active: false}, nil
+	}
+
+	response := s.activeResponse(claims)
+	response.TokenType = strings.TrimSpace(utils.ApiKeyScheme)
+	response.Jti = claims.AccessUUID
+	response.ApiKeyID = claims.ApiKeyID
+	return response, nil
+}
+
+func (s introspectionService) activeResponse(claims *utils.TokenClaims) out.IntrospectionResponse {
+	response := out.IntrospectionResponse{
+		Active:   true,
+		Sub:      strconv.FormatUint(uint64(claims.UserID), 10),
+		ClientID: claims.ClientID,
+		Scope:    strings.Join(claims.Resource, " "),
+		Resource: claims.Resource,
+		RoleID:   claims.RoleID,
+		Exp:      claims.Exp,
+		Iat:      claims.IssuedAt,
+	}
+	if role, err := s.RoleRepository.GetRoleByID(claims.RoleID); err == nil {
+		response.Role = role.Name
+	}
+	return response
+}
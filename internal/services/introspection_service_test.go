@@ -0,0 +1,147 @@
+package services
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/models"
+	"authentication/internal/utils"
+	"authentication/internal/utils/redistest"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// claimsJWT treats a token as a key into a table of already verified claims
+type claimsJWT struct {
+	utils.JWTService
+	claims map[string]utils.TokenClaims
+}
+
+func (j claimsJWT) ExtractClaims(token string) (*utils.TokenClaims, error) {
+	claims, ok := j.claims[token]
+	if !ok {
+		return nil, errors.New("token is malformed")
+	}
+	return &claims, nil
+}
+
+// unreachableRevocation fails every revocation check
+type unreachableRevocation struct {
+	utils.TokenRevocation
+}
+
+func (unreachableRevocation) IsRevoked(*utils.TokenClaims) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func accessClaims(accessUUID string) utils.TokenClaims {
+	return utils.TokenClaims{
+		Authorized: true,
+		AccessUUID: accessUUID,
+		UserID:     7,
+		ClientID:   "client-7",
+		RoleID:     2,
+		Resource:   []string{"auth"},
+		Exp:        time.Now().Add(time.Hour).Unix(),
+		IssuedAt:   time.Now().Unix(),
+	}
+}
+
+func TestIntrospect(t *testing.T) {
+	expired := accessClaims("expired")
+	expired.Exp = time.Now().Add(-time.Minute).Unix()
+	jwtService := claimsJWT{claims: map[string]utils.TokenClaims{
+		"active":        accessClaims("active"),
+		"revoked":       accessClaims("revoked"),
+		"expired":       expired,
+		"logged-out":    accessClaims("logged-out"),
+		"no-session":    accessClaims("no-session"),
+		"other-session": accessClaims("other-session"),
+	}}
+
+	sessions := &sessionStore{}
+	for _, session := range []models.UserSession{
+		{UserID: 7, AccessUUID: "active", IsActive: true, ExpiresAt: time.Now().Add(time.Hour)},
+		{UserID: 7, AccessUUID: "revoked", IsActive: true, ExpiresAt: time.Now().Add(time.Hour)},
+		{UserID: 7, AccessUUID: "expired", IsActive: true, ExpiresAt: time.Now().Add(time.Hour)},
+		{UserID: 7, AccessUUID: "logged-out", IsActive: false, ExpiresAt: time.Now().Add(time.Hour)},
+		{UserID: 8, AccessUUID: "other-session", IsActive: true, ExpiresAt: time.Now().Add(time.Hour)},
+	} {
+		_ = sessions.AddUserSession(&session)
+	}
+
+	revocation := utils.NewTokenRevocation(redistest.NewMemory())
+	if err := revocation.RevokeToken("revoked", time.Now().Add(time.Hour).Unix()); err != nil {
+		t.Fatalf("RevokeToken() error = %v", err)
+	}
+
+	keys := &apiKeyStore{keys: map[uint]*models.ApiKey{}}
+	apiKeys := NewApiKeyService(keys, newUserStore(models.Users{UserID: 7, ClientID: "client-7", RoleID: 2}),
+		userResources{names: map[uint][]string{7: {"auth"}}}, testTokenHasher, 5)
+	issueKey := func() string {
+		created, err := apiKeys.CreateApiKey(&in.ApiKeyRequest{Name: "ci", Scopes: []string{"auth"}}, "client-7")
+		if err != nil {
+			t.Fatalf("CreateApiKey() error = %v", err)
+		}
+		return created.Key
+	}
+	activeKey := issueKey()
+	revokedKey := issueKey()
+	if err := apiKeys.RevokeApiKey(2, "client-7"); err != nil {
+		t.Fatalf("RevokeApiKey() error = %v", err)
+	}
+
+	service := NewIntrospectionService(jwtService, revocation, sessions, roleStore{name: "User"}, apiKeys)
+
+	tests := []struct {
+		name          string
+		token         string
+		wantActive    bool
+		wantTokenType string
+	}{
+		{"active access token", "active", true, "Bearer"},
+		{"revoked access token", "revoked", false, ""},
+		{"expired access token", "expired", false, ""},
+		{"access token of a logged out session", "logged-out", false, ""},
+		{"access token without a session", "no-session", false, ""},
+		{"session of another user", "other-session", false, ""},
+		{"malformed token", "garbage", false, ""},
+		{"active API key", activeKey, true, "ApiKey"},
+		{"revoked API key", revokedKey, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.Introspect(&in.IntrospectionRequest{Token: tt.token})
+			if err != nil {
+				t.Fatalf("Introspect() error = %v", err)
+			}
+			if got.Active != tt.wantActive || got.TokenType != tt.wantTokenType {
+				t.Errorf("Introspect() = %+v, want active %v with token type %q", got, tt.wantActive, tt.wantTokenType)
+			}
+			if !got.Active && (got.Sub != "" || got.ClientID != "") {
+				t.Errorf("Introspect() = %+v, inactive tokens must only carry active=false", got)
+			}
+			if got.Active && (got.Sub != "7" || got.Role != "User") {
+				t.Errorf("Introspect() = %+v, want user 7 with role User", got)
+			}
+		})
+	}
+
+	// a key that never expires reports no exp at all rather than one in 1970
+	got, err := service.Introspect(&in.IntrospectionRequest{Token: activeKey})
+	if err != nil {
+		t.Fatalf("Introspect() error = %v", err)
+	}
+	body, _ := json.Marshal(got)
+	var fields map[string]interface{}
+	_ = json.Unmarshal(body, &fields)
+	if _, ok := fields["exp"]; ok || fields["jti"] != "apikey:1" {
+		t.Errorf("Introspect() of an API key without expiry = %s, want jti apikey:1 and no exp", body)
+	}
+
+	unavailable := NewIntrospectionService(jwtService, unreachableRevocation{}, sessions, roleStore{name: "User"}, apiKeys)
+	if _, err := unavailable.Introspect(&in.IntrospectionRequest{Token: "active"}); err == nil {
+		t.Error("Introspect() reported a token state although the revocation list was unreachable")
+	}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"authentication/internal/models"
+	"authentication/internal/utils"
+	"authentication/internal/utils/logger"
+	nt "authentication/internal/utils/nats"
+	"strings"
+	"time"
+)
+
+// LoginAttemptService throttles password logins. Failures are counted per username and per
+// client IP: every failure on a username delays its next attempt a little longer, and
+// reaching either limit locks the username or the IP out for a while.
+type LoginAttemptService interface {
+	Check(username, ipAddress string) error
+	RegisterFailure(username, ipAddress string)
+	Reset(username string)
+}
+
+type loginAttemptService struct {
+	RedisService    utils.RedisService
+	NatsService     nt.Service
+	MaxAttempts     int
+	MaxIPAttempts   int
+	Window          time.Duration
+	LockoutDuration time.Duration
+}
+
+func NewLoginAttemptService(
+	redis utils.RedisService,
+	natsService nt.Service,
+	maxAttempts int,
+	maxIPAttempts int,
+	window time.Duration,
+	lockoutDuration time.Duration,
+) LoginAttemptService {
+	return loginAttemptService{
+		RedisService:    redis,
+		NatsService:     natsService,
+		MaxAttempts:     maxAttempts,
+		MaxIPAttempts:   maxIPAttempts,
+		Window:          window,
+		LockoutDuration: lockoutDuration,
+	}
+}
+
+// Check refuses the attempt while the username or the IP is blocked. Redis being unavailable
+// does not stop logins.
+func (s loginAttemptService) Check(username, ipAddress string) error {
+	if err := s.checkBlocked(utils.LoginBlockedUser, normalizeUsername(username)); err != nil {
+		return err
+	}
+	if ipAddress == "" {
+		return nil
+	}
+	return s.checkBlocked(utils.LoginBlockedIP, ipAddress)
+}
+
+func (s loginAttemptService) RegisterFailure(username, ipAddress string) {
+	username = normalizeUsername(username)
+	logger.Info().Str("username", username).Str("client_ip", ipAddress).Msg("Failed login")
+
+	count, err := s.RedisService.Increment(utils.LoginAttemptUser, username, s.Window)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error counting failed login")
+	} else if int(count) >= s.MaxAttempts {
+		s.lockOut(utils.LoginAttemptUser, utils.LoginBlockedUser, username, username, ipAddress)
+	} else {
+		_ = s.RedisService.SaveDataWithTTL(utils.LoginBlockedUser, username, loginDelay(count), true)
+	}
+
+	if ipAddress == "" {
+		return
+	}
+
+	count, err = s.RedisService.Increment(utils.LoginAttemptIP, ipAddress, s.Window)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error counting failed login")
+	} else if int(count) >= s.MaxIPAttempts {
+		s.lockOut(utils.LoginAttemptIP, utils.LoginBlockedIP, ipAddress, username, ipAddress)
+	}
+}
+
+// Reset clears the username's failures after a successful login. The IP counter is left to
+// expire so an attacker cannot clear it by logging into an account of their own.
+func (s loginAttemptService) Reset(username string) {
+	username = normalizeUsername(username)
+	_ = s.RedisService.DeleteData(utils.LoginAttemptUser, username)
+	_ = s.RedisService.DeleteData(utils.LoginBlockedUser, username)
+}
+
+func (s loginAttemptService) checkBlocked(key, id string) error {
+	ttl, err := s.RedisService.TTL(key, id)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error checking login throttle")
+		return nil
+	}
+	if ttl > 0 {
+		return utils.NewThrottleError(ttl)
+	}
+	return nil
+}
+
+// lockOut blocks the username or IP for the lockout duration and starts a new round of attempts
+func (s loginAttemptService) lockOut(counterKey, blockedKey, id, username, ipAddress string) {
+	if err := s.RedisService.SaveDataWithTTL(blockedKey, id, s.LockoutDuration, true); err != nil {
+		logger.Error().Err(err).Msg("Error locking out login")
+		return
+	}
+	_ = s.RedisService.DeleteData(counterKey, id)
+
+	event := models.SecurityEvent{
+		EventType: utils.EventLoginLocked,
+		IPAddress: ipAddress,
+		Details: map[string]string{
+			"username": username,
+			"scope":    strings.TrimPrefix(blockedKey, "login_blocked_"),
+			"until":    time.Now().Add(s.LockoutDuration).Format(time.RFC3339),
+		},
+		OccurredAt: time.Now(),
+	}
+	if err := s.NatsService.PublishSecurityEvent(utils.SecurityEventSubject, event); err != nil {
+		logger.Error().Err(err).Msg("Error publishing login lockout event")
+	}
+}
+
+// loginDelay doubles the pause after every failure, starting at one second
+func loginDelay(failures int64) time.Duration {
+	if failures > 5 {
+		return utils.LoginAttemptDelayMax * time.Second
+	}
+	delay := time.Duration(1<<(failures-1)) * time.Second
+	if delay > utils.LoginAttemptDelayMax*time.Second {
+		return utils.LoginAttemptDelayMax * time.Second
+	}
+	return delay
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
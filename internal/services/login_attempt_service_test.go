@@ -0,0 +1,121 @@
+package services
+
+import (
+	"authentication/internal/utils"
+	"authentication/internal/utils/redistest"
+	"errors"
+	"testing"
+	"time"
+)
+
+func retryAfter(err error) time.Duration {
+	var throttleErr *utils.ThrottleError
+	if !errors.As(err, &throttleErr) {
+		return 0
+	}
+	return throttleErr.RetryAfter
+}
+
+func TestLoginAttemptsPerUsername(t *testing.T) {
+	redis := redistest.NewMemory()
+	events := &securityEvents{}
+	service := NewLoginAttemptService(redis, events, 4, 100, 15*time.Minute, 10*time.Minute)
+
+	if err := service.Check("alice", "192.0.2.1"); err != nil {
+		t.Fatalf("Check() before any failure = %v", err)
+	}
+
+	// every failure delays the next attempt twice as long; case and spaces do not make a new username
+	for i, username := range []string{"alice", " Alice", "ALICE"} {
+		service.RegisterFailure(username, "192.0.2.1")
+		want := time.Second << i
+		if got := retryAfter(service.Check("alice", "192.0.2.1")); got <= want-time.Second/2 || got > want {
+			t.Errorf("after %d failures retry after %v, want %v", i+1, got, want)
+		}
+		redis.Expire(utils.LoginBlockedUser, "alice")
+	}
+
+	service.RegisterFailure("alice", "192.0.2.1")
+	if got := retryAfter(service.Check("alice", "192.0.2.2")); got <= 9*time.Minute {
+		t.Errorf("after the last failure retry after %v, want the 10m lockout from any IP", got)
+	}
+	if err := service.Check("bob", "192.0.2.1"); err != nil {
+		t.Errorf("Check() for another username = %v, the IP is under its limit", err)
+	}
+	if len(events.events) != 1 || events.events[0].EventType != utils.EventLoginLocked || events.events[0].Details["scope"] != "user" {
+		t.Errorf("security events = %+v, want one user lockout", events.events)
+	}
+
+	service.Reset("Alice")
+	if err := service.Check("alice", "192.0.2.1"); err != nil {
+		t.Errorf("Check() after Reset() = %v", err)
+	}
+}
+
+func TestLoginAttemptsPerIP(t *testing.T) {
+	redis := redistest.NewMemory()
+	events := &securityEvents{}
+	service := NewLoginAttemptService(redis, events, 100, 3, 15*time.Minute, 10*time.Minute)
+
+	// spraying one password over many usernames is caught by the IP counter
+	for _, username := range []string{"alice", "bob", "carol"} {
+		service.RegisterFailure(username, "192.0.2.1")
+	}
+
+	if got := retryAfter(service.Check("dave", "192.0.2.1")); got <= 9*time.Minute {
+		t.Errorf("Check() from the blocked IP retry after %v, want the 10m lockout", got)
+	}
+	if err := service.Check("dave", "192.0.2.2"); err != nil {
+		t.Errorf("Check() from another IP = %v", err)
+	}
+	if len(events.events) != 1 || events.events[0].Details["scope"] != "ip" {
+		t.Errorf("security events = %+v, want one IP lockout", events.events)
+	}
+
+	// a successful login of some account does not clear the IP
+	service.Reset("dave")
+	if err := service.Check("dave", "192.0.2.1"); err == nil {
+		t.Error("Check() after Reset() let the blocked IP through")
+	}
+}
+
+// unavailableRedis fails every call
+type unavailableRedis struct {
+	utils.RedisService
+}
+
+func (unavailableRedis) TTL(string, string) (time.Duration, error) {
+	return 0, errors.New("connection refused")
+}
+
+func (unavailableRedis) Increment(string, string, time.Duration) (int64, error) {
+	return 0, errors.New("connection refused")
+}
+
+func TestLoginAttemptsWithoutRedis(t *testing.T) {
+	service := NewLoginAttemptService(unavailableRedis{}, &securityEvents{}, 1, 1, time.Minute, time.Minute)
+	service.RegisterFailure("alice", "192.0.2.1")
+	if err := service.Check("alice", "192.0.2.1"); err != nil {
+		t.Errorf("Check() = %v, logins must not depend on Redis", err)
+	}
+}
+
+func TestLoginDelay(t *testing.T) {
+	tests := []struct {
+		failures int64
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{5, 16 * time.Second},
+		{6, utils.LoginAttemptDelayMax * time.Second},
+		{40, utils.LoginAttemptDelayMax * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := loginDelay(tt.failures); got != tt.want {
+			t.Errorf("loginDelay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
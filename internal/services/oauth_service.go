@@ -0,0 +1,534 @@
+package services
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/dto/out"
+	"authentication/internal/models"
+	"authentication/internal/repository"
+	"authentication/internal/utils"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type OauthService interface {
+	PrepareAuthorize(req *in.OauthAuthorizeRequest) (out.OauthConsentResponse, string, error)
+	Authorize(req *in.OauthAuthorizeRequest, clientID string, approved bool) (string, error)
+	Login(req *in.OauthLoginRequest, ipAddress string) (string, error)
+	GetBrowserSession(sessionID string) (*models.OauthBrowserSession, error)
+	Token(req *in.OauthTokenRequest, ipAddress, userAgent string) (out.OauthTokenResponse, error)
+	CreateClient(req *in.OauthClientRequest, clientID string) (out.OauthClientResponse, error)
+	GetClients() ([]out.OauthClientResponse, error)
+	DeleteClient(id uint, clientID string) error
+}
+
+type oauthService struct {
+	OauthClientRepository repository.OauthClientRepository
+	UserRepository        repository.UserRepository
+	ResourceRepository    repository.ResourceRepository
+	RoleRepository        repository.RoleRepository
+	UserSessionRepository repository.UserSessionRepository
+	UsersSessionService   UsersSessionService
+	RefreshTokenService   RefreshTokenService
+	ServiceAccountService ServiceAccountService
+	LoginAttemptService   LoginAttemptService
+	TwoFactorService      TwoFactorService
+	RedisService          utils.RedisService
+	JWTService            utils.JWTService
+	TokenHasher           utils.TokenHasher
+	TokenRevocation       utils.TokenRevocation
+	Encryption            utils.Encryption
+}
+
+func NewOauthService(
+	oauthClientRepo repository.OauthClientRepository,
+	userRepo repository.UserRepository,
+	resourceRepo repository.ResourceRepository,
+	roleRepo repository.RoleRepository,
+	userSessionRepo repository.UserSessionRepository,
+	usersSessionService UsersSessionService,
+	refreshTokenService RefreshTokenService,
+	serviceAccountService ServiceAccountService,
+	loginAttemptService LoginAttemptService,
+	twoFactorService TwoFactorService,
+	redis utils.RedisService,
+	jwtService utils.JWTService,
+	tokenHasher utils.TokenHasher,
+	tokenRevocation utils.TokenRevocation,
+	encryption utils.Encryption,
+) OauthService {
+	return oauthService{
+		OauthClientRepository: oauthClientRepo,
+		UserRepository:        userRepo,
+		ResourceRepository:    resourceRepo,
+		RoleRepository:        roleRepo,
+		UserSessionRepository: userSessionRepo,
+		UsersSessionService:   usersSessionService,
+		RefreshTokenService:   refreshTokenService,
+		ServiceAccountService: serviceAccountService,
+		LoginAttemptService:   loginAttemptService,
+		TwoFactorService:      twoFactorService,
+		RedisService:          redis,
+		JWTService:            jwtService,
+		TokenHasher:           tokenHasher,
+		TokenRevocation:       tokenRevocation,
+		Encryption:            encryption,
+	}
+}
+
+// PrepareAuthorize validates an authorization request before the user is asked for consent. A non-empty
+// redirect carries an error for the client; errors are only returned when the client or redirect URI
+// cannot be trusted.
+func (s oauthService) PrepareAuthorize(req *in.OauthAuthorizeRequest) (out.OauthConsentResponse, string, error) {
+	client, redirectURI, scope, errorRedirect, err := s.validateAuthorize(req)
+	if err != nil || errorRedirect != "" {
+		return out.OauthConsentResponse{}, errorRedirect, err
+	}
+
+	return out.OauthConsentResponse{
+		ClientName:  client.Name,
+		RedirectURI: redirectURI,
+		Scopes:      strings.Fields(scope),
+	}, "", nil
+}
+
+// Authorize returns the redirect URL carrying either the authorization code or an error once the
+// logged-in user has approved or denied the request. Errors are only returned directly when the client
+// or redirect URI cannot be trusted.
+func (s oauthService) Authorize(req *in.OauthAuthorizeRequest, clientID string, approved bool) (string, error) {
+	client, redirectURI, scope, errorRedirect, err := s.validateAuthorize(req)
+	if err != nil || errorRedirect != "" {
+		return errorRedirect, err
+	}
+
+	if !approved {
+		return authorizeRedirect(redirectURI, req.State, utils.OAuthErrAccessDenied, "the user denied the request"), nil
+	}
+
+	user, err := s.UserRepository.GetUserByClientID(clientID)
+	if err != nil || user.UserID == 0 {
+		return authorizeRedirect(redirectURI, req.State, utils.OAuthErrAccessDenied, "user not found"), nil
+	}
+
+	code, err := utils.GenerateOAuthToken()
+	if err != nil {
+		return authorizeRedirect(redirectURI, req.State, utils.OAuthErrServerError, "unable to generate authorization code"), nil
+	}
+
+	authorizationCode := models.OauthAuthorizationCode{
+		ClientID:            client.ClientID,
+		UserID:              user.UserID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               scope,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+	}
+	if err := s.RedisService.SaveDataExpired(utils.OauthAuthorizationCode, s.TokenHasher.Hash(code), utils.OauthAuthorizationCodeExpiry, authorizationCode); err != nil {
+		return authorizeRedirect(redirectURI, req.State, utils.OAuthErrServerError, "unable to store authorization code"), nil
+	}
+
+	redirect, _ := url.Parse(redirectURI)
+	query := redirect.Query()
+	query.Set("code", code)
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+	redirect.RawQuery = query.Encode()
+	return redirect.String(), nil
+}
+
+// Login authenticates the user on the authorization page and starts the browser session kept behind the
+// oauth_session cookie. It is throttled and asks for the second factor like a password login.
+func (s oauthService) Login(req *in.OauthLoginRequest, ipAddress string) (string, error) {
+	if err := s.LoginAttemptService.Check(req.Username, ipAddress); err != nil {
+		return "", err
+	}
+
+	user, err := s.UserRepository.GetUserByUsername(req.Username)
+	if err != nil || s.Encryption.CheckPassword(user.Password, req.Password) != nil {
+		s.LoginAttemptService.RegisterFailure(req.Username, ipAddress)
+		return "", errors.New("username or Password is incorrect")
+	}
+
+	if s.TwoFactorService.IsTotpEnabled(user.UserID) {
+		if strings.TrimSpace(req.Code) == "" {
+			return "", errors.New("enter the code from your authenticator app")
+		}
+		if err := s.TwoFactorService.VerifyTotp(user.UserID, req.Code); err != nil {
+			return "", err
+		}
+	}
+	s.LoginAttemptService.Reset(req.Username)
+
+	sessionID, err := utils.GenerateOAuthToken()
+	if err != nil {
+		return "", errors.New("unable to start session")
+	}
+	csrfToken, err := utils.GenerateOAuthToken()
+	if err != nil {
+		return "", errors.New("unable to start session")
+	}
+
+	session := models.OauthBrowserSession{
+		UserID:    user.UserID,
+		ClientID:  user.ClientID,
+		CSRFToken: csrfToken,
+		IssuedAt:  time.Now().Unix(),
+	}
+	if err := s.RedisService.SaveDataExpired(utils.OauthBrowserSession, s.TokenHasher.Hash(sessionID), utils.OauthBrowserSessionExpiry, session); err != nil {
+		return "", errors.New("unable to start session")
+	}
+	return sessionID, nil
+}
+
+// GetBrowserSession resolves the oauth_session cookie. Sessions end with the user's other tokens, e.g.
+// on a forced logout or a password change.
+func (s oauthService) GetBrowserSession(sessionID string) (*models.OauthBrowserSession, error) {
+	if sessionID == "" {
+		return nil, errors.New("not logged in")
+	}
+
+	var session models.OauthBrowserSession
+	if err := s.RedisService.GetData(utils.OauthBrowserSession, s.TokenHasher.Hash(sessionID), &session); err != nil {
+		return nil, errors.New("not logged in")
+	}
+
+	revoked, err := s.TokenRevocation.IsRevoked(&utils.TokenClaims{UserID: session.UserID, IssuedAt: session.IssuedAt})
+	if err != nil || revoked {
+		_ = s.RedisService.DeleteData(utils.OauthBrowserSession, s.TokenHasher.Hash(sessionID))
+		return nil, errors.New("not logged in")
+	}
+	return &session, nil
+}
+
+func (s oauthService) Token(req *in.OauthTokenRequest, ipAddress, userAgent string) (out.OauthTokenResponse, error) {
+	// machine clients are service accounts, whose tokens InternalMiddleware accepts
+	if req.GrantType == utils.GrantTypeClientCredentials {
+		return s.ServiceAccountService.Token(req.ClientID, req.ClientSecret, req.Scope)
+	}
+	if utils.IsServiceAccountClientID(req.ClientID) {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrUnauthorizedClient, "service accounts can only use the client credentials grant")
+	}
+
+	client, err := s.authenticateClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return out.OauthTokenResponse{}, err
+	}
+
+	switch req.GrantType {
+	case utils.GrantTypeAuthorizationCode:
+		return s.authorizationCodeGrant(client, req, ipAddress, userAgent)
+	case utils.GrantTypeRefreshToken:
+		return s.refreshTokenGrant(client, req, ipAddress, userAgent)
+	case "":
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidRequest, "grant_type is required")
+	default:
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrUnsupportedGrantType, "grant type is not supported")
+	}
+}
+
+func (s oauthService) authorizationCodeGrant(client *models.OauthClient, req *in.OauthTokenRequest, ipAddress, userAgent string) (out.OauthTokenResponse, error) {
+	if !utils.ContainsString(client.GrantTypes, utils.GrantTypeAuthorizationCode) {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrUnauthorizedClient, "client is not allowed to use the authorization code grant")
+	}
+	if req.Code == "" {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidRequest, "code is required")
+	}
+
+	var code models.OauthAuthorizationCode
+	if err := s.RedisService.GetAndDeleteData(utils.OauthAuthorizationCode, s.TokenHasher.Hash(req.Code), &code); err != nil {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidGrant, "authorization code is invalid or expired")
+	}
+
+	if code.ClientID != client.ClientID {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidGrant, "authorization code was issued to another client")
+	}
+	// RFC 6749 section 4.1.3: a redirect_uri sent with the authorization request must be repeated verbatim
+	if code.RedirectURI != "" && req.RedirectURI != code.RedirectURI {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidGrant, "redirect_uri does not match the authorization request")
+	}
+	if code.CodeChallenge != "" && !utils.VerifyCodeChallenge(req.CodeVerifier, code.CodeChallenge) {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidGrant, "code_verifier is invalid")
+	}
+
+	user, err := s.UserRepository.GetUserByID(code.UserID)
+	if err != nil {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidGrant, "user not found")
+	}
+
+	return s.issueUserToken(user, client, code.Scope, ipAddress, userAgent, nil)
+}
+
+func (s oauthService) refreshTokenGrant(client *models.OauthClient, req *in.OauthTokenRequest, ipAddress, userAgent string) (out.OauthTokenResponse, error) {
+	if !utils.ContainsString(client.GrantTypes, utils.GrantTypeRefreshToken) {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrUnauthorizedClient, "client is not allowed to use the refresh token grant")
+	}
+	if req.RefreshToken == "" {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidRequest, "refresh_token is required")
+	}
+
+	// the token is retired before the grant lookup so a replayed token revokes its family
+	refreshToken, err := s.RefreshTokenService.Consume(req.RefreshToken, ipAddress)
+	if err != nil {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidGrant, err.Error())
+	}
+
+	var grant models.OauthRefreshGrant
+	if err := s.RedisService.GetAndDeleteData(utils.OauthRefreshToken, s.TokenHasher.Hash(req.RefreshToken), &grant); err != nil {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidGrant, "refresh token is invalid or expired")
+	}
+	if grant.ClientID != client.ClientID || grant.UserID != refreshToken.UserID {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidGrant, "refresh token was issued to another client")
+	}
+
+	session, err := s.UserSessionRepository.GetUserSessionByRefreshTokenAndUserID(grant.UserID, s.TokenHasher.Hash(req.RefreshToken))
+	if err != nil || session == nil || !session.IsActive {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidGrant, "refresh token is invalid or expired")
+	}
+
+	scope := grant.Scope
+	if req.Scope != "" {
+		narrowed, ok := utils.ResolveScope(req.Scope, strings.Fields(grant.Scope))
+		if !ok {
+			return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidScope, "requested scope exceeds the original grant")
+		}
+		scope = narrowed
+	}
+
+	user, err := s.UserRepository.GetUserByID(grant.UserID)
+	if err != nil {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidGrant, "user not found")
+	}
+
+	return s.issueUserToken(user, client, scope, ipAddress, userAgent, refreshToken)
+}
+
+// issueUserToken mints a user token limited to the granted scope and records it in user_sessions.
+// A nil parent starts a new refresh token family, otherwise the new refresh token succeeds parent.
+func (s oauthService) issueUserToken(user *models.Users, client *models.OauthClient, scope, ipAddress, userAgent string, parent *models.RefreshToken) (out.OauthTokenResponse, error) {
+	role, err := s.RoleRepository.GetRoleByID(user.RoleID)
+	if err != nil {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrServerError, "unable to get role")
+	}
+
+	resource, err := s.ResourceRepository.GetResourceByUserID(user.UserID)
+	if err != nil {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrServerError, "unable to get resource")
+	}
+
+	// the token only reaches the granted resources the user still has
+	granted := strings.Fields(scope)
+	var resourceName []string
+	for _, res := range *resource {
+		if utils.ContainsString(granted, res.Name) {
+			resourceName = append(resourceName, res.Name)
+		}
+	}
+
+	token, err := s.JWTService.GenerateDelegatedToken(*user, resourceName, role.Name, client.ClientID, scope)
+	if err != nil {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrServerError, "unable to generate token")
+	}
+
+	userRedis, err := s.UserRepository.GetUserRedisByClientID(user.ClientID)
+	if err == nil && userRedis != nil {
+		userRedis.Email = utils.DecryptString(userRedis.Email, s.Encryption)
+		_ = s.RedisService.SaveData(utils.User, user.ClientID, userRedis)
+	}
+	_ = s.RedisService.SaveData(utils.Token, user.ClientID, token)
+
+	if parent == nil {
+		err = s.UsersSessionService.AddUserSession(user.UserID, token.AccessToken, token.RefreshToken, ipAddress, userAgent)
+	} else {
+		err = s.UsersSessionService.RotateUserSession(parent, token.AccessToken, token.RefreshToken, ipAddress, userAgent)
+	}
+	if err != nil {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrServerError, "unable to create user session")
+	}
+
+	grant := models.OauthRefreshGrant{
+		ClientID: client.ClientID,
+		UserID:   user.UserID,
+		Scope:    scope,
+	}
+	refreshExpiry := float32(time.Until(time.Unix(token.RtExpires, 0)).Minutes())
+	if err := s.RedisService.SaveDataExpired(utils.OauthRefreshToken, s.TokenHasher.Hash(token.RefreshToken), refreshExpiry, grant); err != nil {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrServerError, "unable to store refresh token")
+	}
+
+	return out.OauthTokenResponse{
+		AccessToken:  token.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    token.AtExpires - time.Now().Unix(),
+		RefreshToken: token.RefreshToken,
+		Scope:        scope,
+	}, nil
+}
+
+// validateAuthorize checks an authorization request against its client and resolves the redirect URI and
+// scope. A non-empty errorRedirect sends an error back to the client; err means the client cannot be trusted.
+func (s oauthService) validateAuthorize(req *in.OauthAuthorizeRequest) (client *models.OauthClient, redirectURI, scope, errorRedirect string, err error) {
+	client, err = s.OauthClientRepository.GetClientByClientID(req.ClientID)
+	if err != nil || !client.IsActive {
+		return nil, "", "", "", utils.NewOAuthError(utils.OAuthErrInvalidRequest, "unknown client")
+	}
+
+	redirectURI = req.RedirectURI
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !utils.ContainsString(client.RedirectURIs, redirectURI) {
+		return nil, "", "", "", utils.NewOAuthError(utils.OAuthErrInvalidRequest, "redirect_uri is not registered for this client")
+	}
+
+	if req.ResponseType != "code" {
+		return nil, "", "", authorizeRedirect(redirectURI, req.State, utils.OAuthErrUnsupportedResponseType, "only the code response type is supported"), nil
+	}
+
+	if !utils.ContainsString(client.GrantTypes, utils.GrantTypeAuthorizationCode) {
+		return nil, "", "", authorizeRedirect(redirectURI, req.State, utils.OAuthErrUnauthorizedClient, "client is not allowed to use the authorization code grant"), nil
+	}
+
+	scope, ok := utils.ResolveScope(req.Scope, client.Scopes)
+	if !ok {
+		return nil, "", "", authorizeRedirect(redirectURI, req.State, utils.OAuthErrInvalidScope, "requested scope is not allowed for this client"), nil
+	}
+
+	if client.IsPublic && req.CodeChallenge == "" {
+		return nil, "", "", authorizeRedirect(redirectURI, req.State, utils.OAuthErrInvalidRequest, "code_challenge is required for public clients"), nil
+	}
+	if req.CodeChallenge != "" && req.CodeChallengeMethod != utils.CodeChallengeMethodS256 {
+		return nil, "", "", authorizeRedirect(redirectURI, req.State, utils.OAuthErrInvalidRequest, "code_challenge_method must be S256"), nil
+	}
+
+	return client, redirectURI, scope, "", nil
+}
+
+func (s oauthService) authenticateClient(clientID, clientSecret string) (*models.OauthClient, error) {
+	if clientID == "" {
+		return nil, utils.NewOAuthError(utils.OAuthErrInvalidClient, "client authentication failed")
+	}
+
+	client, err := s.OauthClientRepository.GetClientByClientID(clientID)
+	if err != nil || !client.IsActive {
+		return nil, utils.NewOAuthError(utils.OAuthErrInvalidClient, "client authentication failed")
+	}
+
+	if client.IsPublic {
+		return client, nil
+	}
+
+	if client.ClientSecret == nil || s.Encryption.CheckPassword(*client.ClientSecret, clientSecret) != nil {
+		return nil, utils.NewOAuthError(utils.OAuthErrInvalidClient, "client authentication failed")
+	}
+	return client, nil
+}
+
+func (s oauthService) CreateClient(req *in.OauthClientRequest, clientID string) (out.OauthClientResponse, error) {
+	for _, grantType := range req.GrantTypes {
+		switch grantType {
+		case utils.GrantTypeAuthorizationCode, utils.GrantTypeRefreshToken:
+		case utils.GrantTypeClientCredentials:
+			return out.OauthClientResponse{}, errors.New("register a service account for the client credentials grant")
+		default:
+			return out.OauthClientResponse{}, errors.New("unsupported grant type: " + grantType)
+		}
+	}
+
+	if utils.ContainsString(req.GrantTypes, utils.GrantTypeAuthorizationCode) && len(req.RedirectURIs) == 0 {
+		return out.OauthClientResponse{}, errors.New("at least one redirect URI is required for the authorization code grant")
+	}
+	for _, redirectURI := range req.RedirectURIs {
+		parsed, err := url.Parse(redirectURI)
+		if err != nil || !parsed.IsAbs() || parsed.Fragment != "" {
+			return out.OauthClientResponse{}, errors.New("invalid redirect URI: " + redirectURI)
+		}
+	}
+
+	client := &models.OauthClient{
+		ClientID:     utils.GenerateClientID(),
+		Name:         req.Name,
+		RedirectURIs: req.RedirectURIs,
+		GrantTypes:   req.GrantTypes,
+		Scopes:       req.Scopes,
+		IsPublic:     req.IsPublic,
+		IsActive:     true,
+		CreatedBy:    clientID,
+		UpdatedBy:    clientID,
+	}
+
+	var secret string
+	if !req.IsPublic {
+		generated, err := utils.GenerateOAuthToken()
+		if err != nil {
+			return out.OauthClientResponse{}, errors.New("unable to generate client secret")
+		}
+		hashed, err := s.Encryption.HashPassword(generated)
+		if err != nil {
+			return out.OauthClientResponse{}, errors.New("unable to hash client secret")
+		}
+		secret = generated
+		client.ClientSecret = hashed
+	}
+
+	if err := s.OauthClientRepository.AddClient(client); err != nil {
+		return out.OauthClientResponse{}, errors.New("unable to create client")
+	}
+
+	response := oauthClientResponse(client)
+	response.ClientSecret = secret
+	return response, nil
+}
+
+func (s oauthService) GetClients() ([]out.OauthClientResponse, error) {
+	clients, err := s.OauthClientRepository.GetClients()
+	if err != nil {
+		return nil, errors.New("unable to get clients")
+	}
+
+	responses := make([]out.OauthClientResponse, 0, len(*clients))
+	for i := range *clients {
+		responses = append(responses, oauthClientResponse(&(*clients)[i]))
+	}
+	return responses, nil
+}
+
+func (s oauthService) DeleteClient(id uint, clientID string) error {
+	client, err := s.OauthClientRepository.GetClientByID(id)
+	if err != nil {
+		return errors.New("client not found")
+	}
+
+	client.DeletedBy = clientID
+	if err := s.OauthClientRepository.DeleteClient(client); err != nil {
+		return errors.New("unable to delete client")
+	}
+	return nil
+}
+
+func authorizeRedirect(redirectURI, state, code, description string) string {
+	redirect, _ := url.Parse(redirectURI)
+	query := redirect.Query()
+	query.Set("error", code)
+	query.Set("error_description", description)
+	if state != "" {
+		query.Set("state", state)
+	}
+	redirect.RawQuery = query.Encode()
+	return redirect.String()
+}
+
+func oauthClientResponse(client *models.OauthClient) out.OauthClientResponse {
+	return out.OauthClientResponse{
+		ID:           client.ID,
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+		GrantTypes:   client.GrantTypes,
+		Scopes:       client.Scopes,
+		IsPublic:     client.IsPublic,
+		IsActive:     client.IsActive,
+		CreatedAt:    client.CreatedAt,
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/models"
+	"authentication/internal/repository"
+	"authentication/internal/utils"
+	"authentication/internal/utils/redistest"
+	"errors"
+	"net/url"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// oauthClientStore serves the registered OAuth clients by client ID
+type oauthClientStore struct {
+	repository.OauthClientRepository
+	clients map[string]*models.OauthClient
+}
+
+func (r oauthClientStore) GetClientByClientID(clientID string) (*models.OauthClient, error) {
+	client, ok := r.clients[clientID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return client, nil
+}
+
+func publicClient(clientID string) *models.OauthClient {
+	return &models.OauthClient{
+		ClientID:     clientID,
+		RedirectURIs: []string{"https://" + clientID + ".example.com/callback"},
+		GrantTypes:   []string{utils.GrantTypeAuthorizationCode, utils.GrantTypeRefreshToken},
+		Scopes:       []string{"auth", "profile"},
+		IsPublic:     true,
+		IsActive:     true,
+	}
+}
+
+func TestAuthorizationCodeIsStoredByHash(t *testing.T) {
+	redis := redistest.NewMemory()
+	service := oauthService{
+		OauthClientRepository: oauthClientStore{clients: map[string]*models.OauthClient{
+			"app":   publicClient("app"),
+			"other": publicClient("other"),
+		}},
+		UserRepository: newUserStore(models.Users{UserID: 7, ClientID: "client-7"}),
+		RedisService:   redis,
+		TokenHasher:    testTokenHasher,
+	}
+
+	redirect, err := service.Authorize(&in.OauthAuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            "app",
+		Scope:               "auth",
+		CodeChallenge:       "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		CodeChallengeMethod: utils.CodeChallengeMethodS256,
+	}, "client-7", true)
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+	parsed, _ := url.Parse(redirect)
+	code := parsed.Query().Get("code")
+	if code == "" {
+		t.Fatalf("Authorize() redirect = %q, want a code", redirect)
+	}
+
+	if redis.Has(utils.OauthAuthorizationCode, code) {
+		t.Error("Authorize() stored the raw authorization code as a Redis key")
+	}
+	if !redis.Has(utils.OauthAuthorizationCode, testTokenHasher.Hash(code)) {
+		t.Fatal("Authorize() did not store the authorization code under its hash")
+	}
+
+	exchange := func() string {
+		_, err := service.Token(&in.OauthTokenRequest{GrantType: utils.GrantTypeAuthorizationCode, ClientID: "other", Code: code}, "10.0.0.1", "curl")
+		var oauthErr *utils.OAuthError
+		if !errors.As(err, &oauthErr) {
+			t.Fatalf("Token() error = %v, want an OAuth error", err)
+		}
+		return oauthErr.Description
+	}
+	// the code is found by its hash and consumed even though another client presented it
+	if got := exchange(); got != "authorization code was issued to another client" {
+		t.Errorf("first exchange error = %q, want the code found and refused", got)
+	}
+	if got := exchange(); got != "authorization code is invalid or expired" {
+		t.Errorf("second exchange error = %q, want the code consumed", got)
+	}
+}
+
+func TestRefreshTokenRejectsOauthRefreshTokens(t *testing.T) {
+	redis := redistest.NewMemory()
+	service := authService{
+		UserRepository: newUserStore(models.Users{UserID: 7, ClientID: "client-7"}),
+		RedisService:   redis,
+		TokenHasher:    testTokenHasher,
+	}
+	_ = redis.SaveData(utils.OauthRefreshToken, testTokenHasher.Hash("oauth-refresh-token"), models.OauthRefreshGrant{ClientID: "app", UserID: 7})
+
+	_, err := service.RefreshToken(&struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}{RefreshToken: "oauth-refresh-token"}, "client-7", "10.0.0.1")
+	if err == nil || err.Error() != "invalid Refresh Token" {
+		t.Errorf("RefreshToken() error = %v, want a refresh token issued to an OAuth client refused", err)
+	}
+}
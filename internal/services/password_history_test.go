@@ -0,0 +1,105 @@
+package services
+
+import (
+	"authentication/internal/models"
+	"authentication/internal/repository"
+	"authentication/internal/utils"
+	"authentication/internal/utils/redistest"
+	"errors"
+	"testing"
+)
+
+// passwordHistoryStore keeps password hashes in memory, newest last
+type passwordHistoryStore struct {
+	repository.PasswordHistoryRepository
+	history []models.PasswordHistory
+}
+
+func (r *passwordHistoryStore) AddPasswordHistory(history *models.PasswordHistory) error {
+	r.history = append(r.history, *history)
+	return nil
+}
+
+func (r *passwordHistoryStore) GetPasswordHistory(userID uint, limit int) (*[]models.PasswordHistory, error) {
+	var newest []models.PasswordHistory
+	for i := len(r.history) - 1; i >= 0 && len(newest) < limit; i-- {
+		if r.history[i].UserID == userID {
+			newest = append(newest, r.history[i])
+		}
+	}
+	return &newest, nil
+}
+
+func (r *passwordHistoryStore) PrunePasswordHistory(userID uint, keep int) error {
+	kept, _ := r.GetPasswordHistory(userID, keep)
+	r.history = nil
+	for i := len(*kept) - 1; i >= 0; i-- {
+		r.history = append(r.history, (*kept)[i])
+	}
+	return nil
+}
+
+func changePassword(s authService, oldPassword, newPassword string) error {
+	return s.ChangePassword(&struct {
+		OldPassword string `json:"old_password" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required"`
+	}{OldPassword: oldPassword, NewPassword: newPassword}, "client-7")
+}
+
+func TestChangePasswordRejectsRecentPasswords(t *testing.T) {
+	policy, _ := utils.ParsePasswordPolicy("8", nil)
+	users := newUserStore(models.Users{UserID: 7, ClientID: "client-7", Username: "alice", Password: "hashed:first-password"})
+	history := &passwordHistoryStore{}
+	service := authService{
+		UserRepository:            users,
+		RoleRepository:            roleStore{name: "User"},
+		UserSessionRepository:     &sessionStore{},
+		RedisService:              redistest.NewMemory(),
+		Encryption:                plainHasher{},
+		PasswordValidator:         utils.NewPasswordValidator(map[string]utils.PasswordPolicy{utils.DefaultPasswordPolicy: policy}),
+		PasswordHistoryRepository: history,
+		PasswordHistorySize:       3,
+		TokenRevocation:           utils.NewTokenRevocation(redistest.NewMemory()),
+		RefreshTokenService:       refreshTokenService{RefreshTokenRepository: &refreshTokenStore{}},
+		ApiKeyService:             apiKeyService{ApiKeyRepository: &apiKeyStore{keys: map[uint]*models.ApiKey{}}},
+	}
+	service.recordPassword(users.users[7], "system")
+
+	current := "first-password"
+	for _, next := range []string{"second-password", "third-password", "fourth-password"} {
+		if err := changePassword(service, current, next); err != nil {
+			t.Fatalf("ChangePassword(%q) error = %v", next, err)
+		}
+		current = next
+	}
+	if len(history.history) != 3 {
+		t.Fatalf("history holds %d passwords, want the last 3", len(history.history))
+	}
+
+	tests := []struct {
+		name      string
+		password  string
+		wantReuse bool
+	}{
+		{"current password", "fourth-password", true},
+		{"previous password", "third-password", true},
+		{"oldest password still remembered", "second-password", true},
+		{"password that dropped out of the history", "first-password", false},
+		{"new password", "fifth-password", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.checkPasswordReuse(users.users[7], "new_password", tt.password)
+			var policyErr *utils.PasswordPolicyError
+			reused := errors.As(err, &policyErr) && policyErr.Errors[0].Code == "reused"
+			if reused != tt.wantReuse {
+				t.Errorf("checkPasswordReuse(%q) = %v, want reused %v", tt.password, err, tt.wantReuse)
+			}
+		})
+	}
+
+	if err := changePassword(service, current, "third-password"); err == nil {
+		t.Error("ChangePassword() accepted a recent password")
+	}
+}
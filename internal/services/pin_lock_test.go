@@ -0,0 +1,255 @@
+package services
+
+import (
+	"authentication/internal/models"
+	"authentication/internal/repository"
+	"authentication/internal/utils"
+	"authentication/internal/utils/redistest"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newPinLockAuthService(users *userStore, events *securityEvents) authService {
+	redis := redistest.NewMemory()
+	for _, user := range users.users {
+		_ = redis.SaveData(utils.User, user.ClientID, user)
+	}
+
+	return authService{
+		UserRepository: users,
+		RedisService:   redis,
+		Encryption:     plainHasher{},
+		NatsService:    events,
+		PinLockPolicy:  utils.NewPinLockPolicy(3, []time.Duration{time.Minute, time.Hour}),
+	}
+}
+
+func verifyPin(s authService, pinCode string) error {
+	_, err := s.VerifyPinCode(&struct {
+		PinCode string `json:"pin_code" binding:"required"`
+	}{PinCode: pinCode}, "client-7")
+	return err
+}
+
+// expireLock moves the user's lock into the past as if its window had run out
+func expireLock(users *userStore) {
+	past := time.Now().Add(-time.Second)
+	users.users[7].LockedUntil = &past
+}
+
+func TestPinLockEscalates(t *testing.T) {
+	pinCode, _ := plainHasher{}.HashPassword("123456")
+	deviceToken := "device-token"
+	users := newUserStore(models.Users{UserID: 7, ClientID: "client-7", PinCode: pinCode, DeviceToken: &deviceToken})
+	events := &securityEvents{}
+	service := newPinLockAuthService(users, events)
+
+	for _, want := range []time.Duration{time.Minute, time.Hour, time.Hour} {
+		for attempt := 1; attempt <= 3; attempt++ {
+			err := verifyPin(service, "000000")
+			if attempt < 3 && (err == nil || errors.Is(err, utils.ErrPinLocked)) {
+				t.Fatalf("wrong PIN attempt %d: error = %v, want invalid Pin Code", attempt, err)
+			}
+			if attempt == 3 && !errors.Is(err, utils.ErrPinLocked) {
+				t.Fatalf("wrong PIN attempt %d: error = %v, want the PIN locked", attempt, err)
+			}
+		}
+
+		user := users.users[7]
+		if lockedFor := time.Until(*user.LockedUntil); lockedFor < want-time.Minute/2 || lockedFor > want {
+			t.Errorf("lock %d lasts %v, want %v", user.PinLockCount, lockedFor.Round(time.Second), want)
+		}
+
+		// the right PIN is not even checked while the lock holds
+		if err := verifyPin(service, "123456"); !errors.Is(err, utils.ErrPinLocked) {
+			t.Fatalf("correct PIN while locked: error = %v, want the PIN locked", err)
+		}
+		expireLock(users)
+	}
+
+	if users.users[7].PinLockCount != 3 {
+		t.Errorf("PinLockCount = %d, want 3", users.users[7].PinLockCount)
+	}
+	if len(events.notifications) != 3 || events.notifications[0].EventType != utils.EventPinLocked {
+		t.Errorf("sent %d notifications, want one %s per lock", len(events.notifications), utils.EventPinLocked)
+	}
+
+	// a correct PIN after the lock has run out starts over from the first window
+	if err := verifyPin(service, "123456"); err != nil {
+		t.Fatalf("correct PIN after the lock expired: error = %v", err)
+	}
+	if user := users.users[7]; user.PinLockCount != 0 || user.LockedUntil != nil || user.PinAttempts != 0 {
+		t.Errorf("escalation was not cleared: attempts %d, locks %d, locked until %v", user.PinAttempts, user.PinLockCount, user.LockedUntil)
+	}
+}
+
+func TestUnlockPinCode(t *testing.T) {
+	pinCode, _ := plainHasher{}.HashPassword("123456")
+	lockedUntil := time.Now().Add(time.Hour)
+	users := newUserStore(
+		models.Users{UserID: 1, ClientID: "admin-1"},
+		models.Users{UserID: 7, ClientID: "client-7", PinCode: pinCode, PinLockCount: 2, LockedUntil: &lockedUntil},
+	)
+	service := newPinLockAuthService(users, &securityEvents{})
+
+	if err := verifyPin(service, "123456"); !errors.Is(err, utils.ErrPinLocked) {
+		t.Fatalf("VerifyPinCode() error = %v, want the PIN locked", err)
+	}
+
+	if err := service.UnlockPinCode(7, "admin-1"); err != nil {
+		t.Fatalf("UnlockPinCode() error = %v", err)
+	}
+	if user := users.users[7]; user.PinLockCount != 0 || user.LockedUntil != nil || user.UpdatedBy != "admin-1" {
+		t.Errorf("UnlockPinCode() left locks %d, locked until %v, updated by %q", user.PinLockCount, user.LockedUntil, user.UpdatedBy)
+	}
+	if err := verifyPin(service, "123456"); err != nil {
+		t.Errorf("VerifyPinCode() after unlock error = %v", err)
+	}
+}
+
+// upgradingHasher reports every hash as weak and marks the hashes it writes, so a test can see
+// which hasher a rehash went through
+type upgradingHasher struct {
+	plainHasher
+}
+
+func (upgradingHasher) NeedsRehash(hash string) bool {
+	return true
+}
+
+func (upgradingHasher) HashPassword(password string) (*string, error) {
+	hashed := "upgraded:" + password
+	return &hashed, nil
+}
+
+func TestVerifyPinCodeRehashesThroughEncryption(t *testing.T) {
+	pinCode, _ := plainHasher{}.HashPassword("123456")
+	users := newUserStore(models.Users{UserID: 7, ClientID: "client-7", PinCode: pinCode})
+	service := newPinLockAuthService(users, &securityEvents{})
+	service.Encryption = upgradingHasher{}
+
+	if err := verifyPin(service, "123456"); err != nil {
+		t.Fatalf("VerifyPinCode() error = %v", err)
+	}
+	if got := *users.users[7].PinCode; got != "upgraded:123456" {
+		t.Errorf("stored PIN hash = %q, want it rehashed by Encryption", got)
+	}
+}
+
+// pinCodeStore writes PIN changes back to the user store
+type pinCodeStore struct {
+	repository.AuthRepository
+	users *userStore
+}
+
+func (r pinCodeStore) UpdatePinCode(user *models.Users) error {
+	return r.users.UpdateUser(user)
+}
+
+func TestChangePinCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		oldPinCode string
+		newPinCode string
+		wantErr    string
+	}{
+		{"new PIN", "123456", "654321", ""},
+		{"same PIN again", "123456", "123456", "old Pin and New Pin is same"},
+		{"wrong old PIN", "000000", "654321", "old Pin Code is incorrect"},
+		{"too short", "123456", "1234", utils.ErrPinCodeLength.Error()},
+		{"not numeric", "123456", "12345a", utils.ErrPinCodeInvalid.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinCode, _ := plainHasher{}.HashPassword("123456")
+			users := newUserStore(models.Users{UserID: 7, ClientID: "client-7", PinCode: pinCode})
+			service := newPinLockAuthService(users, &securityEvents{})
+			service.AuthRepository = pinCodeStore{users: users}
+
+			err := service.ChangePinCode(&struct {
+				OldPinCode string `json:"old_pin_code" binding:"required"`
+				NewPinCode string `json:"new_pin_code" binding:"required"`
+			}{OldPinCode: tt.oldPinCode, NewPinCode: tt.newPinCode}, "client-7")
+			var gotErr string
+			if err != nil {
+				gotErr = err.Error()
+			}
+			if gotErr != tt.wantErr {
+				t.Fatalf("ChangePinCode() error = %q, want %q", gotErr, tt.wantErr)
+			}
+
+			wantPin := "123456"
+			if tt.wantErr == "" {
+				wantPin = tt.newPinCode
+			}
+			if err := verifyPin(service, wantPin); err != nil {
+				t.Errorf("VerifyPinCode(%s) error = %v", wantPin, err)
+			}
+		})
+	}
+}
+
+func TestForgetPinCode(t *testing.T) {
+	const email = "alice@example.com"
+	emailIndex := plainHasher{}.HashEmail(email)
+	otherIndex := plainHasher{}.HashEmail("bob@example.com")
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		pinCode  string
+		wantErr  string
+	}{
+		{"own email and password", email, "account-password", "654321", ""},
+		{"wrong password", email, "guessed-password", "654321", "password is incorrect"},
+		{"email of another user", "bob@example.com", "account-password", "654321", "Email not found"},
+		{"PIN in the wrong format", email, "account-password", "12ab", utils.ErrPinCodeLength.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinCode, _ := plainHasher{}.HashPassword("123456")
+			password, _ := plainHasher{}.HashPassword("account-password")
+			lockedUntil := time.Now().Add(time.Hour)
+			users := newUserStore(
+				models.Users{UserID: 7, ClientID: "client-7", Email: email, EmailIndex: &emailIndex, Password: *password,
+					PinCode: pinCode, PinAttempts: 2, PinLockCount: 2, LockedUntil: &lockedUntil},
+				models.Users{UserID: 8, ClientID: "client-8", EmailIndex: &otherIndex},
+			)
+			service := newPinLockAuthService(users, &securityEvents{})
+
+			err := service.ForgetPinCode(&struct {
+				Email    string `json:"email" binding:"required"`
+				Password string `json:"password" binding:"required"`
+				PinCode  string `json:"pin_code" binding:"required"`
+			}{Email: tt.email, Password: tt.password, PinCode: tt.pinCode}, "client-7")
+			var gotErr string
+			if err != nil {
+				gotErr = err.Error()
+			}
+			if gotErr != tt.wantErr {
+				t.Fatalf("ForgetPinCode() error = %q, want %q", gotErr, tt.wantErr)
+			}
+
+			user := users.users[7]
+			if tt.wantErr != "" {
+				if user.LockedUntil == nil || *user.PinCode != *pinCode {
+					t.Errorf("refused reset changed the PIN or lifted the lock: %+v", user)
+				}
+				return
+			}
+			if user.LockedUntil != nil || user.PinAttempts != 0 {
+				t.Errorf("reset left attempts %d, locked until %v", user.PinAttempts, user.LockedUntil)
+			}
+			if user.PinLockCount != 2 {
+				t.Errorf("PinLockCount = %d, want the escalation kept at 2", user.PinLockCount)
+			}
+			if err := verifyPin(service, tt.pinCode); err != nil {
+				t.Errorf("VerifyPinCode() with the new PIN error = %v", err)
+			}
+		})
+	}
+}
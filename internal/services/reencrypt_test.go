@@ -0,0 +1,104 @@
+package services
+
+import (
+	"authentication/internal/models"
+	"authentication/internal/repository"
+	"authentication/internal/utils"
+	"strings"
+	"testing"
+)
+
+// twoFactorStore keeps TOTP enrollments in memory
+type twoFactorStore struct {
+	repository.UserTwoFactorRepository
+	twoFactors map[uint]*models.UserTwoFactor
+}
+
+func (r *twoFactorStore) GetUserTwoFactorsToReencrypt(keyPrefix string, limit int) (*[]models.UserTwoFactor, error) {
+	var twoFactors []models.UserTwoFactor
+	for _, twoFactor := range r.twoFactors {
+		if !strings.HasPrefix(twoFactor.TotpSecret, keyPrefix) && len(twoFactors) < limit {
+			twoFactors = append(twoFactors, *twoFactor)
+		}
+	}
+	return &twoFactors, nil
+}
+
+func (r *twoFactorStore) UpdateTotpSecret(userID uint, secret string) error {
+	r.twoFactors[userID].TotpSecret = secret
+	return nil
+}
+
+// signingKeyStore keeps signing keys in memory
+type signingKeyStore struct {
+	repository.SigningKeyRepository
+	keys map[string]*models.SigningKey
+}
+
+func (r *signingKeyStore) GetSigningKeysByStatus(statuses ...string) (*[]models.SigningKey, error) {
+	var keys []models.SigningKey
+	for _, key := range r.keys {
+		for _, status := range statuses {
+			if key.Status == status {
+				keys = append(keys, *key)
+			}
+		}
+	}
+	return &keys, nil
+}
+
+func (r *signingKeyStore) UpdateSigningKey(key *models.SigningKey) error {
+	stored := *key
+	r.keys[key.KeyID] = &stored
+	return nil
+}
+
+func TestReencryptSecretsSurvivesKeyRemoval(t *testing.T) {
+	oldKeyring := utils.NewEncryption(map[int]string{1: "key-one"}, "legacy-iv", "blind-index-key")
+	keyring := utils.NewEncryption(map[int]string{1: "key-one", 2: "key-two"}, "legacy-iv", "blind-index-key")
+	rotated := utils.NewEncryption(map[int]string{2: "key-two"}, "legacy-iv", "blind-index-key")
+
+	totpSecret, _ := oldKeyring.Encrypt("totp-secret")
+	privateKey, _ := oldKeyring.Encrypt("private-key")
+	twoFactors := &twoFactorStore{twoFactors: map[uint]*models.UserTwoFactor{7: {UserID: 7, TotpSecret: totpSecret}}}
+	signingKeys := &signingKeyStore{keys: map[string]*models.SigningKey{"kid-1": {KeyID: "kid-1", Status: utils.SigningKeyRetired, PrivateKey: privateKey}}}
+
+	twoFactorService{UserTwoFactorRepository: twoFactors, Encryption: keyring}.ReencryptTotpSecrets()
+	signingKeyService{SigningKeyRepository: signingKeys, Encryption: keyring}.ReencryptSigningKeys()
+
+	if got, err := rotated.Decrypt(twoFactors.twoFactors[7].TotpSecret); err != nil || got != "totp-secret" {
+		t.Errorf("TOTP secret after the old key is removed = %q, %v", got, err)
+	}
+	if got, err := rotated.Decrypt(signingKeys.keys["kid-1"].PrivateKey); err != nil || got != "private-key" {
+		t.Errorf("signing key after the old key is removed = %q, %v", got, err)
+	}
+}
+
+func TestDecryptStoredValue(t *testing.T) {
+	keyring := utils.NewEncryption(map[int]string{1: "key-one"}, "legacy-iv", "blind-index-key")
+	encrypted, _ := keyring.Encrypt("alice@example.com")
+	service := authService{Encryption: keyring}
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"encrypted value", encrypted, "alice@example.com", false},
+		{"never encrypted", "alice@example.com", "alice@example.com", false},
+		{"base64 that does not decrypt to text", "AAECAwQFBgcICQoLDA0ODw==", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.decryptStoredValue(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decryptStoredValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("decryptStoredValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"authentication/internal/models"
+	"authentication/internal/repository"
+	"authentication/internal/utils"
+	"authentication/internal/utils/logger"
+	nt "authentication/internal/utils/nats"
+	"errors"
+	"github.com/google/uuid"
+	"time"
+)
+
+type RefreshTokenService interface {
+	StartFamily(userID uint, refreshToken, ipAddress, createdBy string) error
+	Consume(refreshToken, ipAddress string) (*models.RefreshToken, error)
+	IssueInFamily(parent *models.RefreshToken, refreshToken, ipAddress string) error
+	RevokeUserTokens(userID uint, reason, updatedBy string) error
+	RevokeFamilyByToken(hashedToken, reason, updatedBy string) error
+	HashLegacyTokens() (int, error)
+}
+
+type refreshTokenService struct {
+	RefreshTokenRepository repository.RefreshTokenRepository
+	UserSessionRepository  repository.UserSessionRepository
+	UserRepository         repository.UserRepository
+	RedisService           utils.RedisService
+	NatsService            nt.Service
+	TokenHasher            utils.TokenHasher
+	TokenRevocation        utils.TokenRevocation
+}
+
+func NewRefreshTokenService(
+	refreshTokenRepo repository.RefreshTokenRepository,
+	userSessionRepo repository.UserSessionRepository,
+	userRepo repository.UserRepository,
+	redis utils.RedisService,
+	natsService nt.Service,
+	tokenHasher utils.TokenHasher,
+	tokenRevocation utils.TokenRevocation,
+) RefreshTokenService {
+	return refreshTokenService{
+		RefreshTokenRepository: refreshTokenRepo,
+		UserSessionRepository:  userSessionRepo,
+		UserRepository:         userRepo,
+		RedisService:           redis,
+		NatsService:            natsService,
+		TokenHasher:            tokenHasher,
+		TokenRevocation:        tokenRevocation,
+	}
+}
+
+// StartFamily records the refresh token handed out by a fresh login as the root of a new family
+func (s refreshTokenService) StartFamily(userID uint, refreshToken, ipAddress, createdBy string) error {
+	return s.RefreshTokenRepository.AddRefreshToken(&models.RefreshToken{
+		Token:       s.TokenHasher.Hash(refreshToken),
+		TokenHashed: true,
+		FamilyID:    uuid.New().String(),
+		UserID:      userID,
+		Status:      utils.RefreshTokenActive,
+		ExpiresAt:   time.Now().Add(time.Hour * utils.RefreshTokenExpiry),
+		IPAddress:   ipAddress,
+		CreatedBy:   createdBy,
+		UpdatedBy:   createdBy,
+	})
+}
+
+// Consume retires the presented token so it can be exchanged exactly once. Presenting a token
+// that was already rotated means it has been replayed: the whole family is revoked.
+func (s refreshTokenService) Consume(refreshToken, ipAddress string) (*models.RefreshToken, error) {
+	token, err := s.RefreshTokenRepository.GetRefreshTokenByToken(s.TokenHasher.Hash(refreshToken))
+	if err != nil {
+		return nil, errors.New("invalid Refresh Token")
+	}
+
+	switch token.Status {
+	case utils.RefreshTokenRevoked:
+		return nil, errors.New("invalid Refresh Token")
+	case utils.RefreshTokenRotated:
+		s.revokeFamily(token, ipAddress)
+		return nil, errors.New("refresh Token reuse detected, please login again")
+	}
+
+	if token.ExpiresAt.Before(time.Now()) {
+		return nil, errors.New("refresh Token is expired")
+	}
+
+	rotated, err := s.RefreshTokenRepository.MarkRefreshTokenRotated(token.ID, "system")
+	if err != nil {
+		return nil, errors.New("unable to rotate refresh token")
+	}
+	if !rotated {
+		// another request exchanged the same token first
+		s.revokeFamily(token, ipAddress)
+		return nil, errors.New("refresh Token reuse detected, please login again")
+	}
+
+	return token, nil
+}
+
+// IssueInFamily records the successor of a consumed token
+func (s refreshTokenService) IssueInFamily(parent *models.RefreshToken, refreshToken, ipAddress string) error {
+	return s.RefreshTokenRepository.AddRefreshToken(&models.RefreshToken{
+		Token:       s.TokenHasher.Hash(refreshToken),
+		TokenHashed: true,
+		FamilyID:    parent.FamilyID,
+		ParentID:    &parent.ID,
+		UserID:      parent.UserID,
+		Status:      utils.RefreshTokenActive,
+		ExpiresAt:   time.Now().Add(time.Hour * utils.RefreshTokenExpiry),
+		IPAddress:   ipAddress,
+		CreatedBy:   "system",
+		UpdatedBy:   "system",
+	})
+}
+
+func (s refreshTokenService) RevokeUserTokens(userID uint, reason, updatedBy string) error {
+	return s.RefreshTokenRepository.RevokeRefreshTokensByUserID(userID, reason, updatedBy)
+}
+
+// RevokeFamilyByToken revokes the family of a stored (already hashed) refresh token, e.g. when its session ends
+func (s refreshTokenService) RevokeFamilyByToken(hashedToken, reason, updatedBy string) error {
+	token, err := s.RefreshTokenRepository.GetRefreshTokenByToken(hashedToken)
+	if err != nil {
+		// sessions created before refresh token families have nothing to revoke
+		return nil
+	}
+	return s.RefreshTokenRepository.RevokeRefreshTokenFamily(token.FamilyID, reason, updatedBy)
+}
+
+// HashLegacyTokens replaces refresh tokens stored before hashing was introduced with their HMAC
+func (s refreshTokenService) HashLegacyTokens() (int, error) {
+	var hashed int
+	for {
+		tokens, err := s.RefreshTokenRepository.GetRefreshTokensWithRawToken(utils.LegacyTokenBatchSize)
+		if err != nil {
+			return hashed, err
+		}
+
+		var updated int
+		for i := range *tokens {
+			token := &(*tokens)[i]
+			token.Token = s.TokenHasher.Hash(token.Token)
+			token.TokenHashed = true
+			token.UpdatedBy = "system"
+			if err := s.RefreshTokenRepository.UpdateRefreshToken(token); err != nil {
+				logger.Error().Err(err).Uint("refresh_token_id", token.ID).Msg("Unable to hash refresh token")
+				continue
+			}
+			updated++
+		}
+
+		hashed += updated
+		if len(*tokens) < utils.LegacyTokenBatchSize || updated == 0 {
+			return hashed, nil
+		}
+	}
+}
+
+// revokeFamily kills every token in the family, ends the session holding its current token together
+// with the access token issued to it and publishes a security event
+func (s refreshTokenService) revokeFamily(token *models.RefreshToken, ipAddress string) {
+	current, _ := s.RefreshTokenRepository.GetActiveRefreshTokenByFamilyID(token.FamilyID)
+
+	if err := s.RefreshTokenRepository.RevokeRefreshTokenFamily(token.FamilyID, utils.EventRefreshTokenReused, "system"); err != nil {
+		logger.Error().Err(err).Msg("Error revoking refresh token family")
+	}
+
+	user, err := s.UserRepository.GetUserByID(token.UserID)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error getting user for revoked refresh token family")
+		return
+	}
+
+	if current != nil {
+		session, err := s.UserSessionRepository.GetUserSessionByRefreshTokenAndUserID(user.UserID, current.Token)
+		if err == nil && session != nil {
+			now := time.Now()
+			session.IsActive = false
+			session.LogoutTime = &now
+			session.UpdatedBy = "system"
+			if err := s.UserSessionRepository.UpdateSession(session); err != nil {
+				logger.Error().Err(err).Msg("Error ending session for revoked refresh token family")
+			}
+			if err := s.TokenRevocation.RevokeToken(session.AccessUUID, session.ExpiresAt.Unix()); err != nil {
+				logger.Error().Err(err).Msg("Error revoking access token for revoked refresh token family")
+			}
+			s.clearCachedSession(user.ClientID, session)
+		}
+	}
+
+	event := models.SecurityEvent{
+		EventType: utils.EventRefreshTokenReused,
+		UserID:    user.UserID,
+		ClientID:  user.ClientID,
+		IPAddress: ipAddress,
+		Details: map[string]string{
+			"family_id": token.FamilyID,
+		},
+		OccurredAt: time.Now(),
+	}
+	if err := s.NatsService.PublishSecurityEvent(utils.SecurityEventSubject, event); err != nil {
+		logger.Error().Err(err).Msg("Error publishing refresh token reuse event")
+	}
+}
+
+// clearCachedSession drops what Redis holds for the session. The per-client keys hold the user's
+// latest login, which may be another session, so they are only removed when they belong to this one.
+func (s refreshTokenService) clearCachedSession(clientID string, session *models.UserSession) {
+	_ = s.RedisService.DeleteData(utils.SessionLastSeen, session.AccessUUID)
+
+	var cached models.UserSession
+	if err := s.RedisService.GetData(utils.UserSession, clientID, &cached); err != nil || cached.AccessUUID != session.AccessUUID {
+		return
+	}
+	_ = s.RedisService.DeleteData(utils.UserSession, clientID)
+	_ = s.RedisService.DeleteData(utils.Token, clientID)
+	_ = s.RedisService.DeleteData(utils.User, clientID)
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"authentication/internal/models"
+	"authentication/internal/repository"
+	"authentication/internal/utils"
+	"authentication/internal/utils/redistest"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// refreshTokenStore keeps refresh tokens in memory with the same status transitions as the database
+type refreshTokenStore struct {
+	repository.RefreshTokenRepository
+	tokens []*models.RefreshToken
+	// lostRace makes the conditional update find the token already rotated by a concurrent request
+	lostRace bool
+}
+
+func (r *refreshTokenStore) AddRefreshToken(token *models.RefreshToken) error {
+	token.ID = uint(len(r.tokens) + 1)
+	copied := *token
+	r.tokens = append(r.tokens, &copied)
+	return nil
+}
+
+func (r *refreshTokenStore) GetRefreshTokenByToken(token string) (*models.RefreshToken, error) {
+	for _, stored := range r.tokens {
+		if stored.Token == token {
+			copied := *stored
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *refreshTokenStore) GetActiveRefreshTokenByFamilyID(familyID string) (*models.RefreshToken, error) {
+	for _, stored := range r.tokens {
+		if stored.FamilyID == familyID && stored.Status == utils.RefreshTokenActive {
+			copied := *stored
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *refreshTokenStore) MarkRefreshTokenRotated(id uint, updatedBy string) (bool, error) {
+	stored := r.tokens[id-1]
+	if r.lostRace || stored.Status != utils.RefreshTokenActive {
+		return false, nil
+	}
+	stored.Status = utils.RefreshTokenRotated
+	stored.UpdatedBy = updatedBy
+	return true, nil
+}
+
+func (r *refreshTokenStore) RevokeRefreshTokenFamily(familyID, reason, updatedBy string) error {
+	for _, stored := range r.tokens {
+		if stored.FamilyID == familyID && stored.Status != utils.RefreshTokenRevoked {
+			stored.Status = utils.RefreshTokenRevoked
+			stored.RevokedReason = reason
+			stored.UpdatedBy = updatedBy
+		}
+	}
+	return nil
+}
+
+func (r *refreshTokenStore) RevokeRefreshTokensByUserID(userID uint, reason, updatedBy string) error {
+	for _, stored := range r.tokens {
+		if stored.UserID == userID && stored.Status == utils.RefreshTokenActive {
+			stored.Status = utils.RefreshTokenRevoked
+			stored.RevokedReason = reason
+		}
+	}
+	return nil
+}
+
+var testTokenHasher = utils.NewTokenHasher("token-hash-key")
+
+func TestRefreshTokenConsume(t *testing.T) {
+	const reuseDetected = "refresh Token reuse detected, please login again"
+
+	tests := []struct {
+		name       string
+		presented  string
+		status     string
+		expiresIn  time.Duration
+		lostRace   bool
+		wantErr    string
+		wantStatus string
+	}{
+		{"active token is rotated", "token-1", utils.RefreshTokenActive, time.Hour, false, "", utils.RefreshTokenRotated},
+		{"unknown token", "token-2", utils.RefreshTokenActive, time.Hour, false, "invalid Refresh Token", utils.RefreshTokenActive},
+		{"revoked token", "token-1", utils.RefreshTokenRevoked, time.Hour, false, "invalid Refresh Token", utils.RefreshTokenRevoked},
+		{"expired token", "token-1", utils.RefreshTokenActive, -time.Minute, false, "refresh Token is expired", utils.RefreshTokenActive},
+		{"replayed token", "token-1", utils.RefreshTokenRotated, time.Hour, false, reuseDetected, utils.RefreshTokenRevoked},
+		{"token exchanged by a concurrent request", "token-1", utils.RefreshTokenActive, time.Hour, true, reuseDetected, utils.RefreshTokenRevoked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := &refreshTokenStore{lostRace: tt.lostRace}
+			_ = tokens.AddRefreshToken(&models.RefreshToken{
+				Token:     testTokenHasher.Hash("token-1"),
+				FamilyID:  "family-1",
+				UserID:    7,
+				Status:    tt.status,
+				ExpiresAt: time.Now().Add(tt.expiresIn),
+			})
+			events := &securityEvents{}
+			redis := redistest.NewMemory()
+			service := NewRefreshTokenService(tokens, &sessionStore{}, newUserStore(models.Users{UserID: 7, ClientID: "client-7"}), redis, events, testTokenHasher, utils.NewTokenRevocation(redis))
+
+			token, err := service.Consume(tt.presented, "192.0.2.1")
+			var gotErr string
+			if err != nil {
+				gotErr = err.Error()
+			}
+			if gotErr != tt.wantErr {
+				t.Fatalf("Consume() error = %q, want %q", gotErr, tt.wantErr)
+			}
+			if err == nil && token.FamilyID != "family-1" {
+				t.Errorf("Consume() = %+v, want the token of family-1", token)
+			}
+			if got := tokens.tokens[0].Status; got != tt.wantStatus {
+				t.Errorf("stored status = %q, want %q", got, tt.wantStatus)
+			}
+			if wantEvents := tt.wantErr == reuseDetected; (len(events.events) == 1) != wantEvents {
+				t.Errorf("published %d security events, want an event: %v", len(events.events), wantEvents)
+			}
+		})
+	}
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	tests := []struct {
+		name          string
+		cachedSession string
+		wantCleared   bool
+	}{
+		{"cache holds the revoked session", "access-3", true},
+		{"cache holds a later login on another device", "access-other", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := &refreshTokenStore{}
+			sessions := &sessionStore{}
+			redis := redistest.NewMemory()
+			revocation := utils.NewTokenRevocation(redis)
+			events := &securityEvents{}
+			service := NewRefreshTokenService(tokens, sessions, newUserStore(models.Users{UserID: 7, ClientID: "client-7"}), redis, events, testTokenHasher, revocation)
+
+			// the client logs in and refreshes twice
+			if err := service.StartFamily(7, "token-1", "192.0.2.1", "client-7"); err != nil {
+				t.Fatalf("StartFamily() error = %v", err)
+			}
+			for _, pair := range [][2]string{{"token-1", "token-2"}, {"token-2", "token-3"}} {
+				parent, err := service.Consume(pair[0], "192.0.2.1")
+				if err != nil {
+					t.Fatalf("Consume(%s) error = %v", pair[0], err)
+				}
+				if err := service.IssueInFamily(parent, pair[1], "192.0.2.1"); err != nil {
+					t.Fatalf("IssueInFamily() error = %v", err)
+				}
+			}
+			if tokens.tokens[0].Token == "token-1" {
+				t.Fatal("StartFamily() stored the bearer token instead of its hash")
+			}
+			_ = sessions.AddUserSession(&models.UserSession{
+				UserID:       7,
+				RefreshToken: testTokenHasher.Hash("token-3"),
+				AccessUUID:   "access-3",
+				IsActive:     true,
+				ExpiresAt:    time.Now().Add(time.Hour),
+			})
+			_ = redis.SaveData(utils.UserSession, "client-7", models.UserSession{UserID: 7, AccessUUID: tt.cachedSession})
+			_ = redis.SaveData(utils.Token, "client-7", "cached")
+			_ = redis.SaveData(utils.User, "client-7", "cached")
+			_ = redis.SaveData(utils.SessionLastSeen, "access-3", time.Now().Unix())
+
+			// a stolen copy of the first token is replayed
+			if _, err := service.Consume("token-1", "198.51.100.9"); err == nil {
+				t.Fatal("Consume() accepted a replayed token")
+			}
+
+			for _, token := range tokens.tokens {
+				if token.Status != utils.RefreshTokenRevoked || token.RevokedReason != utils.EventRefreshTokenReused {
+					t.Errorf("token %d: status = %q (%q), want revoked for reuse", token.ID, token.Status, token.RevokedReason)
+				}
+			}
+			if _, err := service.Consume("token-3", "192.0.2.1"); err == nil {
+				t.Error("Consume() accepted the newest token of a revoked family")
+			}
+
+			if session := sessions.sessions[0]; session.IsActive || session.LogoutTime == nil {
+				t.Errorf("session holding the newest token was not ended: %+v", session)
+			}
+			if revoked, err := revocation.IsRevoked(&utils.TokenClaims{AccessUUID: "access-3", UserID: 7, IssuedAt: time.Now().Unix()}); err != nil || !revoked {
+				t.Errorf("IsRevoked() = (%v, %v), want the access token of the ended session revoked", revoked, err)
+			}
+			if revoked, _ := revocation.IsRevoked(&utils.TokenClaims{AccessUUID: "access-other", UserID: 7, IssuedAt: time.Now().Unix()}); revoked {
+				t.Error("IsRevoked() reported the access token of another session revoked")
+			}
+			if redis.Has(utils.SessionLastSeen, "access-3") {
+				t.Error("last seen time of the ended session is still cached")
+			}
+			for _, key := range []string{utils.UserSession, utils.Token, utils.User} {
+				if redis.Has(key, "client-7") == tt.wantCleared {
+					t.Errorf("%s cached for client-7: %v, want cleared %v", key, redis.Has(key, "client-7"), tt.wantCleared)
+				}
+			}
+
+			if len(events.events) != 1 {
+				t.Fatalf("published %d security events, want 1", len(events.events))
+			}
+			event := events.events[0]
+			if event.EventType != utils.EventRefreshTokenReused || event.UserID != 7 || event.IPAddress != "198.51.100.9" || event.Details["family_id"] != tokens.tokens[0].FamilyID {
+				t.Errorf("security event = %+v", event)
+			}
+		})
+	}
+}
@@ -4,9 +4,10 @@ import (
 	"authentication/internal/dto/out"
 	"authentication/internal/models"
 	"authentication/internal/repository"
+	"authentication/internal/utils"
+	"authentication/internal/utils/logger"
 	nt "authentication/internal/utils/nats"
 	"errors"
-	"log"
 	"strings"
 )
 
@@ -29,9 +30,10 @@ type resourceService struct {
 	UserRepository         repository.UserRepository
 	NatsService            nt.Service
 	AuthService            AuthService
+	Encryption             utils.Encryption
 }
 
-func NewResourceService(resourceRepo repository.ResourceRepository, roleResourceRepo repository.UserResourceRepository, roleRepo repository.RoleRepository, userRepo repository.UserRepository, service nt.Service, authService AuthService) ResourceService {
+func NewResourceService(resourceRepo repository.ResourceRepository, roleResourceRepo repository.UserResourceRepository, roleRepo repository.RoleRepository, userRepo repository.UserRepository, service nt.Service, authService AuthService, encryption utils.Encryption) ResourceService {
 	return resourceService{
 		ResourceRepository:     resourceRepo,
 		UserResourceRepository: roleResourceRepo,
@@ -39,6 +41,7 @@ func NewResourceService(resourceRepo repository.ResourceRepository, roleResource
 		UserRepository:         userRepo,
 		NatsService:            service,
 		AuthService:            authService,
+		Encryption:             encryption,
 	}
 }
 
@@ -181,7 +184,7 @@ func (s resourceService) AssignUserResource(userID uint, resourceID uint, client
 
 	if user.DeviceToken != nil {
 		notification := models.Notification{
-			TargetToken:   *user.DeviceToken,
+			TargetToken:   utils.DerefStr(utils.DecryptOptionalString(user.DeviceToken, s.Encryption)),
 			Title:         "Assign User Resource",
 			Body:          "You have been assigned a new resource",
 			Priority:      "high",
@@ -198,7 +201,7 @@ func (s resourceService) AssignUserResource(userID uint, resourceID uint, client
 
 		err = s.NatsService.RequestNotification("authentication", notification)
 	} else {
-		log.Println("Device token is nil, skipping notification")
+		logger.Debug().Uint("user_id", user.UserID).Msg("Device token is nil, skipping notification")
 	}
 
 	return struct {
@@ -248,7 +251,7 @@ func (s resourceService) RemoveAssignUserResource(userID uint, resourceID uint,
 
 	if user.DeviceToken != nil {
 		notification := models.Notification{
-			TargetToken:   *user.DeviceToken,
+			TargetToken:   utils.DerefStr(utils.DecryptOptionalString(user.DeviceToken, s.Encryption)),
 			Title:         "Remove User Resource",
 			Body:          "You have been removed from a resource",
 			Priority:      "high",
@@ -264,7 +267,7 @@ func (s resourceService) RemoveAssignUserResource(userID uint, resourceID uint,
 		}
 		err = s.NatsService.RequestNotification("authentication", notification)
 	} else {
-		log.Println("Device token is nil, skipping notification")
+		logger.Debug().Uint("user_id", user.UserID).Msg("Device token is nil, skipping notification")
 	}
 
 	return nil
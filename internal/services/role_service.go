@@ -4,9 +4,8 @@ import (
 	"authentication/internal/dto/out"
 	"authentication/internal/models"
 	"authentication/internal/repository"
-	"encoding/json"
+	"authentication/internal/utils/logger"
 	"errors"
-	"log"
 )
 
 type RoleService interface {
@@ -157,11 +156,7 @@ func (s roleService) DeleteRole(roleID uint, clientID string) error {
 	}
 
 	if len(*users) > 0 {
-		usersJSON, err := json.Marshal(users)
-		if err != nil {
-			return err
-		}
-		log.Println("Role is still being used by users+ " + string(usersJSON))
+		logger.Warn().Uint("role_id", roleID).Int("users", len(*users)).Msg("Role is still being used by users")
 		return errors.New("role is still being used by users")
 	}
 
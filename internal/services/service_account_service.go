@@ -0,0 +1,286 @@
+package services
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/dto/out"
+	"authentication/internal/models"
+	"authentication/internal/repository"
+	"authentication/internal/utils"
+	"errors"
+	"gorm.io/gorm"
+	"strconv"
+	"time"
+)
+
+type ServiceAccountService interface {
+	CreateServiceAccount(req *in.ServiceAccountRequest, clientID string) (out.ServiceAccountResponse, error)
+	GetServiceAccounts() ([]out.ServiceAccountResponse, error)
+	UpdateServiceAccount(id uint, req *in.ServiceAccountRequest, clientID string) (out.ServiceAccountResponse, error)
+	RotateSecret(id uint, clientID string) (out.ServiceAccountResponse, error)
+	DeleteServiceAccount(id uint, clientID string) error
+	Token(clientID, clientSecret, scope string) (out.OauthTokenResponse, error)
+	ValidateToken(token string) (*utils.InternalClaims, error)
+}
+
+type serviceAccountService struct {
+	ServiceAccountRepository repository.ServiceAccountRepository
+	ResourceRepository       repository.ResourceRepository
+	JWTService               utils.JWTService
+	Encryption               utils.Encryption
+	DefaultTokenTTL          time.Duration
+	MaxTokenTTL              time.Duration
+	SecretRotationGrace      time.Duration
+	Audience                 string
+}
+
+func NewServiceAccountService(
+	serviceAccountRepo repository.ServiceAccountRepository,
+	resourceRepo repository.ResourceRepository,
+	jwtService utils.JWTService,
+	encryption utils.Encryption,
+	defaultTokenTTL time.Duration,
+	maxTokenTTL time.Duration,
+	secretRotationGrace time.Duration,
+	audience string,
+) ServiceAccountService {
+	return serviceAccountService{
+		ServiceAccountRepository: serviceAccountRepo,
+		ResourceRepository:       resourceRepo,
+		JWTService:               jwtService,
+		Encryption:               encryption,
+		DefaultTokenTTL:          defaultTokenTTL,
+		MaxTokenTTL:              maxTokenTTL,
+		SecretRotationGrace:      secretRotationGrace,
+		Audience:                 audience,
+	}
+}
+
+// CreateServiceAccount registers a machine identity. The client secret is only returned here and on rotation.
+func (s serviceAccountService) CreateServiceAccount(req *in.ServiceAccountRequest, clientID string) (out.ServiceAccountResponse, error) {
+	ttl, err := s.resolveTokenTTL(req.TokenTTL)
+	if err != nil {
+		return out.ServiceAccountResponse{}, err
+	}
+	if err := s.validateResources(req.Resources); err != nil {
+		return out.ServiceAccountResponse{}, err
+	}
+
+	secret, hashed, err := s.generateServiceAccountSecret()
+	if err != nil {
+		return out.ServiceAccountResponse{}, err
+	}
+
+	account := &models.ServiceAccount{
+		ClientID:     utils.GenerateServiceAccountClientID(),
+		ClientSecret: hashed,
+		Name:         req.Name,
+		Resources:    req.Resources,
+		TokenTTL:     ttl,
+		IsActive:     req.IsActive == nil || *req.IsActive,
+		CreatedBy:    clientID,
+		UpdatedBy:    clientID,
+	}
+	if err := s.ServiceAccountRepository.AddServiceAccount(account); err != nil {
+		return out.ServiceAccountResponse{}, errors.New("unable to create service account")
+	}
+
+	response := serviceAccountResponse(account)
+	response.ClientSecret = secret
+	return response, nil
+}
+
+func (s serviceAccountService) GetServiceAccounts() ([]out.ServiceAccountResponse, error) {
+	accounts, err := s.ServiceAccountRepository.GetServiceAccounts()
+	if err != nil {
+		return nil, errors.New("unable to get service accounts")
+	}
+
+	responses := make([]out.ServiceAccountResponse, 0, len(*accounts))
+	for i := range *accounts {
+		responses = append(responses, serviceAccountResponse(&(*accounts)[i]))
+	}
+	return responses, nil
+}
+
+func (s serviceAccountService) UpdateServiceAccount(id uint, req *in.ServiceAccountRequest, clientID string) (out.ServiceAccountResponse, error) {
+	account, err := s.ServiceAccountRepository.GetServiceAccountByID(id)
+	if err != nil {
+		return out.ServiceAccountResponse{}, errors.New("service account not found")
+	}
+
+	ttl, err := s.resolveTokenTTL(req.TokenTTL)
+	if err != nil {
+		return out.ServiceAccountResponse{}, err
+	}
+	if err := s.validateResources(req.Resources); err != nil {
+		return out.ServiceAccountResponse{}, err
+	}
+
+	account.Name = req.Name
+	account.Resources = req.Resources
+	account.TokenTTL = ttl
+	if req.IsActive != nil {
+		account.IsActive = *req.IsActive
+	}
+	account.UpdatedBy = clientID
+	if err := s.ServiceAccountRepository.UpdateServiceAccount(account); err != nil {
+		return out.ServiceAccountResponse{}, errors.New("unable to update service account")
+	}
+
+	return serviceAccountResponse(account), nil
+}
+
+// RotateSecret issues a new client secret. The old one keeps working for the rotation grace period
+// so jobs can pick up the new secret without a redeploy.
+func (s serviceAccountService) RotateSecret(id uint, clientID string) (out.ServiceAccountResponse, error) {
+	account, err := s.ServiceAccountRepository.GetServiceAccountByID(id)
+	if err != nil {
+		return out.ServiceAccountResponse{}, errors.New("service account not found")
+	}
+
+	secret, hashed, err := s.generateServiceAccountSecret()
+	if err != nil {
+		return out.ServiceAccountResponse{}, err
+	}
+
+	previousSecret := account.ClientSecret
+	previousSecretExpiresAt := time.Now().Add(s.SecretRotationGrace)
+	account.PreviousClientSecret = &previousSecret
+	account.PreviousSecretExpiresAt = &previousSecretExpiresAt
+	account.ClientSecret = hashed
+	account.UpdatedBy = clientID
+	if err := s.ServiceAccountRepository.UpdateServiceAccount(account); err != nil {
+		return out.ServiceAccountResponse{}, errors.New("unable to rotate client secret")
+	}
+
+	response := serviceAccountResponse(account)
+	response.ClientSecret = secret
+	return response, nil
+}
+
+func (s serviceAccountService) DeleteServiceAccount(id uint, clientID string) error {
+	account, err := s.ServiceAccountRepository.GetServiceAccountByID(id)
+	if err != nil {
+		return errors.New("service account not found")
+	}
+
+	account.DeletedBy = clientID
+	if err := s.ServiceAccountRepository.DeleteServiceAccount(account); err != nil {
+		return errors.New("unable to delete service account")
+	}
+	return nil
+}
+
+// Token handles the client_credentials grant for a service account; errors are OAuth errors
+func (s serviceAccountService) Token(clientID, clientSecret, scope string) (out.OauthTokenResponse, error) {
+	account, err := s.ServiceAccountRepository.GetServiceAccountByClientID(clientID)
+	if err != nil || !account.IsActive || !s.serviceAccountSecretMatches(account, clientSecret) {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidClient, "client authentication failed")
+	}
+
+	granted, ok := utils.ResolveScope(scope, account.Resources)
+	if !ok {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrInvalidScope, "requested scope is not allowed for this service account")
+	}
+
+	ttl := time.Duration(account.TokenTTL) * time.Second
+	token, expiresAt, err := s.JWTService.GenerateServiceToken(account.ClientID, account.Name, granted, []string{s.Audience}, ttl)
+	if err != nil {
+		return out.OauthTokenResponse{}, utils.NewOAuthError(utils.OAuthErrServerError, "unable to generate token")
+	}
+
+	return out.OauthTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   expiresAt - time.Now().Unix(),
+		Scope:       granted,
+	}, nil
+}
+
+// ValidateToken checks a service account token; deleting or disabling the account invalidates its tokens
+func (s serviceAccountService) ValidateToken(token string) (*utils.InternalClaims, error) {
+	claims, err := s.JWTService.ValidateServiceToken(token)
+	if err != nil {
+		return nil, utils.ErrInternalTokenInvalid
+	}
+	if !claims.VerifyAudience(s.Audience, true) {
+		return nil, utils.ErrInternalTokenInvalid
+	}
+
+	account, err := s.ServiceAccountRepository.GetServiceAccountByClientID(claims.Subject)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.ErrInternalTokenRevoked
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !account.IsActive {
+		return nil, utils.ErrInternalTokenRevoked
+	}
+
+	return claims, nil
+}
+
+// resolveTokenTTL turns the requested TTL in seconds into the stored one, zero picks the default
+func (s serviceAccountService) resolveTokenTTL(seconds int64) (int64, error) {
+	if seconds < 0 {
+		return 0, errors.New("token_ttl must not be negative")
+	}
+	if seconds == 0 {
+		return int64(s.DefaultTokenTTL / time.Second), nil
+	}
+	if max := int64(s.MaxTokenTTL / time.Second); seconds > max {
+		return 0, errors.New("token_ttl must not exceed " + strconv.FormatInt(max, 10) + " seconds")
+	}
+	return seconds, nil
+}
+
+func (s serviceAccountService) validateResources(resources []string) error {
+	if len(resources) == 0 {
+		return errors.New("at least one resource is required")
+	}
+	for _, name := range resources {
+		if _, err := s.ResourceRepository.GetResourceByName(name); err != nil {
+			return errors.New("resource not found: " + name)
+		}
+	}
+	return nil
+}
+
+func (s serviceAccountService) generateServiceAccountSecret() (string, string, error) {
+	secret, err := utils.GenerateOAuthToken()
+	if err != nil {
+		return "", "", errors.New("unable to generate client secret")
+	}
+	hashed, err := s.Encryption.HashPassword(secret)
+	if err != nil {
+		return "", "", errors.New("unable to hash client secret")
+	}
+	return secret, *hashed, nil
+}
+
+func (s serviceAccountService) serviceAccountSecretMatches(account *models.ServiceAccount, clientSecret string) bool {
+	if s.Encryption.CheckPassword(account.ClientSecret, clientSecret) == nil {
+		return true
+	}
+	if account.PreviousClientSecret == nil || account.PreviousSecretExpiresAt == nil || time.Now().After(*account.PreviousSecretExpiresAt) {
+		return false
+	}
+	return s.Encryption.CheckPassword(*account.PreviousClientSecret, clientSecret) == nil
+}
+
+func serviceAccountResponse(account *models.ServiceAccount) out.ServiceAccountResponse {
+	response := out.ServiceAccountResponse{
+		ID:        account.ID,
+		ClientID:  account.ClientID,
+		Name:      account.Name,
+		Resources: account.Resources,
+		TokenTTL:  account.TokenTTL,
+		IsActive:  account.IsActive,
+		CreatedAt: account.CreatedAt,
+	}
+	if account.PreviousSecretExpiresAt != nil && time.Now().Before(*account.PreviousSecretExpiresAt) {
+		response.PreviousSecretExpiresAt = account.PreviousSecretExpiresAt
+	}
+	return response
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/models"
+	"authentication/internal/repository"
+	"authentication/internal/utils"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// serviceAccountStore keeps service accounts in memory by ID
+type serviceAccountStore struct {
+	repository.ServiceAccountRepository
+	accounts map[uint]*models.ServiceAccount
+}
+
+func (r *serviceAccountStore) AddServiceAccount(account *models.ServiceAccount) error {
+	account.ID = uint(len(r.accounts) + 1)
+	r.accounts[account.ID] = account
+	return nil
+}
+
+func (r *serviceAccountStore) GetServiceAccountByID(id uint) (*models.ServiceAccount, error) {
+	account, ok := r.accounts[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *account
+	return &copied, nil
+}
+
+func (r *serviceAccountStore) GetServiceAccountByClientID(clientID string) (*models.ServiceAccount, error) {
+	for _, account := range r.accounts {
+		if account.ClientID == clientID {
+			copied := *account
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *serviceAccountStore) UpdateServiceAccount(account *models.ServiceAccount) error {
+	copied := *account
+	r.accounts[account.ID] = &copied
+	return nil
+}
+
+// resourceNames knows every resource by name
+type resourceNames struct {
+	repository.ResourceRepository
+}
+
+func (resourceNames) GetResourceByName(name string) (*models.Resource, error) {
+	return &models.Resource{Name: name}, nil
+}
+
+func TestServiceAccountSecretRotationGrace(t *testing.T) {
+	accounts := &serviceAccountStore{accounts: map[uint]*models.ServiceAccount{}}
+	service := NewServiceAccountService(accounts, resourceNames{}, utils.NewJWTService("jwt-secret", nil, "authentication"), plainHasher{},
+		time.Hour, 24*time.Hour, time.Hour, "authentication-service")
+
+	created, err := service.CreateServiceAccount(&in.ServiceAccountRequest{Name: "billing-job", Resources: []string{"billing"}}, "admin-1")
+	if err != nil {
+		t.Fatalf("CreateServiceAccount() error = %v", err)
+	}
+	rotated, err := service.RotateSecret(created.ID, "admin-1")
+	if err != nil {
+		t.Fatalf("RotateSecret() error = %v", err)
+	}
+	if rotated.PreviousSecretExpiresAt == nil {
+		t.Error("RotateSecret() response does not report when the old secret stops working")
+	}
+
+	token := func(secret string) error {
+		_, err := service.Token(created.ClientID, secret, "billing")
+		return err
+	}
+
+	if err := token(rotated.ClientSecret); err != nil {
+		t.Errorf("new secret: Token() error = %v", err)
+	}
+	if err := token(created.ClientSecret); err != nil {
+		t.Errorf("old secret within the grace period: Token() error = %v", err)
+	}
+	if err := token("not-the-secret"); err == nil {
+		t.Error("unknown secret: Token() succeeded")
+	}
+
+	graceEnded := time.Now().Add(-time.Second)
+	accounts.accounts[created.ID].PreviousSecretExpiresAt = &graceEnded
+	if err := token(created.ClientSecret); err == nil {
+		t.Error("old secret after the grace period: Token() succeeded")
+	}
+	if err := token(rotated.ClientSecret); err != nil {
+		t.Errorf("new secret after the grace period: Token() error = %v", err)
+	}
+
+	again, err := service.RotateSecret(created.ID, "admin-1")
+	if err != nil {
+		t.Fatalf("RotateSecret() error = %v", err)
+	}
+	if err := token(created.ClientSecret); err == nil {
+		t.Error("secret from two rotations ago: Token() succeeded")
+	}
+	if err := token(rotated.ClientSecret); err != nil {
+		t.Errorf("previous secret after a second rotation: Token() error = %v", err)
+	}
+	if err := token(again.ClientSecret); err != nil {
+		t.Errorf("newest secret: Token() error = %v", err)
+	}
+}
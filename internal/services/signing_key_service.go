@@ -0,0 +1,261 @@
+package services
+
+import (
+	"authentication/internal/models"
+	"authentication/internal/repository"
+	"authentication/internal/utils"
+	"authentication/internal/utils/logger"
+	"errors"
+	"time"
+)
+
+type SigningKeyService interface {
+	LoadSigningKeys() ([]*utils.SigningKey, error)
+	EnsureSigningKey(configured *utils.SigningKey) error
+	RotateSigningKeys()
+	ReencryptSigningKeys()
+}
+
+type signingKeyService struct {
+	SigningKeyRepository repository.SigningKeyRepository
+	Encryption           utils.Encryption
+	Algorithm            string
+	RotationInterval     time.Duration
+	PropagationPeriod    time.Duration
+	RetireGrace          time.Duration
+}
+
+func NewSigningKeyService(
+	signingKeyRepo repository.SigningKeyRepository,
+	encryption utils.Encryption,
+	algorithm string,
+	rotationInterval time.Duration,
+	propagationPeriod time.Duration,
+	retireGrace time.Duration,
+) SigningKeyService {
+	return signingKeyService{
+		SigningKeyRepository: signingKeyRepo,
+		Encryption:           encryption,
+		Algorithm:            algorithm,
+		RotationInterval:     rotationInterval,
+		PropagationPeriod:    propagationPeriod,
+		RetireGrace:          retireGrace,
+	}
+}
+
+// LoadSigningKeys returns every pending and active key; retired keys are no longer trusted
+func (s signingKeyService) LoadSigningKeys() ([]*utils.SigningKey, error) {
+	records, err := s.SigningKeyRepository.GetSigningKeysByStatus(utils.SigningKeyPending, utils.SigningKeyActive)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*utils.SigningKey, 0, len(*records))
+	for _, record := range *records {
+		privateKey, err := s.Encryption.Decrypt(record.PrivateKey)
+		if err != nil {
+			logger.Error().Err(err).Str("kid", record.KeyID).Msg("Unable to decrypt signing key")
+			continue
+		}
+
+		key, err := utils.ParseSigningKey(record.Algorithm, privateKey)
+		if err != nil {
+			logger.Error().Err(err).Str("kid", record.KeyID).Msg("Unable to parse signing key")
+			continue
+		}
+
+		key.Status = record.Status
+		if record.ActivatedAt != nil {
+			key.ActivatedAt = *record.ActivatedAt
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// EnsureSigningKey makes sure there is a key to sign with at startup. An operator-supplied key
+// that is not yet in the keyring is imported as the newest active key.
+func (s signingKeyService) EnsureSigningKey(configured *utils.SigningKey) error {
+	if s.Algorithm == utils.AlgorithmHS256 {
+		return nil
+	}
+
+	if configured != nil {
+		if _, err := s.SigningKeyRepository.GetSigningKeyByKeyID(configured.KeyID); err != nil {
+			return s.saveSigningKey(configured, utils.SigningKeyActive)
+		}
+	}
+
+	active, err := s.SigningKeyRepository.GetSigningKeysByStatus(utils.SigningKeyActive)
+	if err != nil {
+		return err
+	}
+	if len(*active) > 0 {
+		return nil
+	}
+
+	key, err := utils.GenerateSigningKey(s.Algorithm)
+	if err != nil {
+		return err
+	}
+	return s.saveSigningKey(key, utils.SigningKeyActive)
+}
+
+// RotateSigningKeys advances the key lifecycle:
+// a pending key is published in the JWKS for PropagationPeriod before it starts signing,
+// superseded active keys keep verifying until every token they signed has expired,
+// and a new pending key is staged so the next activation lands on RotationInterval.
+func (s signingKeyService) RotateSigningKeys() {
+	if s.Algorithm == utils.AlgorithmHS256 {
+		return
+	}
+
+	records, err := s.SigningKeyRepository.GetSigningKeysByStatus(utils.SigningKeyPending, utils.SigningKeyActive)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error loading signing keys")
+		return
+	}
+
+	now := time.Now()
+	var newest *models.SigningKey
+	var pending []*models.SigningKey
+	var active []*models.SigningKey
+	for i := range *records {
+		record := &(*records)[i]
+		switch record.Status {
+		case utils.SigningKeyPending:
+			pending = append(pending, record)
+		case utils.SigningKeyActive:
+			active = append(active, record)
+			if newest == nil || activatedAt(record).After(activatedAt(newest)) {
+				newest = record
+			}
+		}
+	}
+
+	var stillPending int
+	for _, record := range pending {
+		if now.Sub(record.CreatedAt) < s.PropagationPeriod {
+			stillPending++
+			continue
+		}
+		record.Status = utils.SigningKeyActive
+		record.ActivatedAt = &now
+		record.UpdatedBy = "system"
+		if err := s.SigningKeyRepository.UpdateSigningKey(record); err != nil {
+			logger.Error().Err(err).Msg("Error activating signing key")
+			stillPending++
+			continue
+		}
+		logger.Info().Str("kid", record.KeyID).Msg("Signing key activated")
+		active = append(active, record)
+		newest = record
+	}
+
+	if newest != nil {
+		graceEnds := activatedAt(newest).Add(s.RetireGrace)
+		for _, record := range active {
+			if record == newest || now.Before(graceEnds) {
+				continue
+			}
+			record.Status = utils.SigningKeyRetired
+			record.RetiredAt = &now
+			record.UpdatedBy = "system"
+			if err := s.SigningKeyRepository.UpdateSigningKey(record); err != nil {
+				logger.Error().Err(err).Msg("Error retiring signing key")
+				continue
+			}
+			logger.Info().Str("kid", record.KeyID).Msg("Signing key retired")
+		}
+	}
+
+	if stillPending > 0 {
+		return
+	}
+
+	status := utils.SigningKeyPending
+	if newest == nil {
+		// nothing can sign right now, skip the propagation window
+		status = utils.SigningKeyActive
+	} else if now.Sub(activatedAt(newest)) < s.RotationInterval-s.PropagationPeriod {
+		return
+	}
+
+	key, err := utils.GenerateSigningKey(s.Algorithm)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error generating signing key")
+		return
+	}
+	if err := s.saveSigningKey(key, status); err != nil {
+		logger.Error().Err(err).Msg("Error saving signing key")
+		return
+	}
+	logger.Info().Str("kid", key.KeyID).Str("status", status).Msg("Signing key created")
+}
+
+func (s signingKeyService) saveSigningKey(key *utils.SigningKey, status string) error {
+	privateKey, err := key.MarshalPrivateKey()
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := s.Encryption.Encrypt(privateKey)
+	if err != nil {
+		return errors.New("unable to encrypt signing key")
+	}
+
+	record := &models.SigningKey{
+		KeyID:      key.KeyID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: encrypted,
+		Status:     status,
+		CreatedBy:  "system",
+		UpdatedBy:  "system",
+	}
+	if status == utils.SigningKeyActive {
+		now := time.Now()
+		record.ActivatedAt = &now
+	}
+	return s.SigningKeyRepository.AddSigningKey(record)
+}
+
+func activatedAt(record *models.SigningKey) time.Time {
+	if record.ActivatedAt == nil {
+		return record.CreatedAt
+	}
+	return *record.ActivatedAt
+}
+
+// ReencryptSigningKeys rewrites private keys not yet encrypted with AES-GCM under the newest key, so
+// an old key version can be dropped from the keyring. Retired keys are included, they stay readable.
+func (s signingKeyService) ReencryptSigningKeys() {
+	records, err := s.SigningKeyRepository.GetSigningKeysByStatus(utils.SigningKeyPending, utils.SigningKeyActive, utils.SigningKeyRetired)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error getting signing keys to re-encrypt")
+		return
+	}
+
+	var reencrypted int
+	for _, record := range *records {
+		if !s.Encryption.NeedsReencrypt(record.PrivateKey) {
+			continue
+		}
+		privateKey, err := s.Encryption.Decrypt(record.PrivateKey)
+		if err != nil {
+			logger.Error().Err(err).Str("kid", record.KeyID).Msg("Unable to decrypt signing key")
+			continue
+		}
+		if record.PrivateKey, err = s.Encryption.Encrypt(privateKey); err != nil {
+			logger.Error().Err(err).Str("kid", record.KeyID).Msg("Unable to re-encrypt signing key")
+			continue
+		}
+		if err := s.SigningKeyRepository.UpdateSigningKey(&record); err != nil {
+			logger.Error().Err(err).Str("kid", record.KeyID).Msg("Unable to update signing key")
+			continue
+		}
+		reencrypted++
+	}
+	if reencrypted > 0 {
+		logger.Info().Int("keys", reencrypted).Msg("Re-encrypted signing keys")
+	}
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/dto/out"
+	"authentication/internal/models"
+	"authentication/internal/repository"
+	"authentication/internal/utils"
+	"authentication/internal/utils/logger"
+	"errors"
+	"strconv"
+	"time"
+)
+
+type TwoFactorService interface {
+	EnrollTotp(clientID string) (out.TotpEnrollResponse, error)
+	ConfirmTotp(req *in.TotpCodeRequest, clientID string) error
+	DisableTotp(req *in.TotpCodeRequest, clientID string) error
+	IsTotpEnabled(userID uint) bool
+	VerifyTotp(userID uint, code string) error
+	ReencryptTotpSecrets()
+}
+
+type twoFactorService struct {
+	UserRepository          repository.UserRepository
+	UserTwoFactorRepository repository.UserTwoFactorRepository
+	Encryption              utils.Encryption
+	RedisService            utils.RedisService
+	Issuer                  string
+}
+
+func NewTwoFactorService(
+	userRepo repository.UserRepository,
+	userTwoFactorRepo repository.UserTwoFactorRepository,
+	encryption utils.Encryption,
+	redis utils.RedisService,
+	issuer string,
+) TwoFactorService {
+	return twoFactorService{
+		UserRepository:          userRepo,
+		UserTwoFactorRepository: userTwoFactorRepo,
+		Encryption:              encryption,
+		RedisService:            redis,
+		Issuer:                  issuer,
+	}
+}
+
+func (s twoFactorService) EnrollTotp(clientID string) (out.TotpEnrollResponse, error) {
+	user, err := s.UserRepository.GetUserByClientID(clientID)
+	if err != nil {
+		return out.TotpEnrollResponse{}, errors.New("user not found")
+	}
+
+	existing, err := s.UserTwoFactorRepository.GetUserTwoFactorByUserID(user.UserID)
+	if err == nil && existing.IsEnabled {
+		return out.TotpEnrollResponse{}, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := utils.GenerateTotpSecret()
+	if err != nil {
+		return out.TotpEnrollResponse{}, errors.New("unable to generate secret")
+	}
+
+	encryptedSecret, err := s.Encryption.Encrypt(secret)
+	if err != nil {
+		return out.TotpEnrollResponse{}, errors.New("unable to encrypt secret")
+	}
+
+	twoFactor := &models.UserTwoFactor{
+		UserID:     user.UserID,
+		TotpSecret: encryptedSecret,
+		IsEnabled:  false,
+		CreatedBy:  user.ClientID,
+		UpdatedBy:  user.ClientID,
+	}
+	if existing != nil {
+		twoFactor.CreatedAt = existing.CreatedAt
+		twoFactor.CreatedBy = existing.CreatedBy
+	}
+
+	if err := s.UserTwoFactorRepository.SaveUserTwoFactor(twoFactor); err != nil {
+		return out.TotpEnrollResponse{}, errors.New("unable to save two-factor secret")
+	}
+
+	return out.TotpEnrollResponse{
+		Secret:     secret,
+		OtpauthURI: utils.GenerateTotpURI(s.Issuer, user.Username, secret),
+	}, nil
+}
+
+func (s twoFactorService) ConfirmTotp(req *in.TotpCodeRequest, clientID string) error {
+	user, err := s.UserRepository.GetUserByClientID(clientID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+
+	twoFactor, err := s.UserTwoFactorRepository.GetUserTwoFactorByUserID(user.UserID)
+	if err != nil {
+		return errors.New("two-factor enrollment not found")
+	}
+	if twoFactor.IsEnabled {
+		return errors.New("two-factor authentication is already enabled")
+	}
+
+	if err := s.checkCode(twoFactor, req.Code); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	twoFactor.IsEnabled = true
+	twoFactor.ConfirmedAt = &now
+	twoFactor.UpdatedBy = user.ClientID
+	if err := s.UserTwoFactorRepository.SaveUserTwoFactor(twoFactor); err != nil {
+		return errors.New("unable to enable two-factor authentication")
+	}
+	return nil
+}
+
+func (s twoFactorService) DisableTotp(req *in.TotpCodeRequest, clientID string) error {
+	user, err := s.UserRepository.GetUserByClientID(clientID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+
+	if err := s.VerifyTotp(user.UserID, req.Code); err != nil {
+		return err
+	}
+
+	if err := s.UserTwoFactorRepository.DeleteUserTwoFactor(user.UserID); err != nil {
+		return errors.New("unable to disable two-factor authentication")
+	}
+	return nil
+}
+
+func (s twoFactorService) IsTotpEnabled(userID uint) bool {
+	twoFactor, err := s.UserTwoFactorRepository.GetUserTwoFactorByUserID(userID)
+	if err != nil {
+		return false
+	}
+	return twoFactor.IsEnabled
+}
+
+// VerifyTotp checks a code of an enabled second factor. Failures are counted per user rather than
+// per challenge, so logging in again does not buy more guesses.
+func (s twoFactorService) VerifyTotp(userID uint, code string) error {
+	id := strconv.FormatUint(uint64(userID), 10)
+	ttl, err := s.RedisService.TTL(utils.TwoFactorBlocked, id)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error checking two-factor throttle")
+	} else if ttl > 0 {
+		return utils.NewThrottleError(ttl)
+	}
+
+	twoFactor, err := s.UserTwoFactorRepository.GetUserTwoFactorByUserID(userID)
+	if err != nil || !twoFactor.IsEnabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	if err := s.checkCode(twoFactor, code); err != nil {
+		s.registerFailure(id)
+		return err
+	}
+	_ = s.RedisService.DeleteData(utils.TwoFactorFailures, id)
+	return nil
+}
+
+// registerFailure counts a wrong code and locks the second factor once the user reaches the limit
+func (s twoFactorService) registerFailure(id string) {
+	count, err := s.RedisService.Increment(utils.TwoFactorFailures, id, utils.TwoFactorFailureWindow*time.Minute)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error counting failed two-factor code")
+		return
+	}
+	if count < utils.TwoFactorMaxFailures {
+		return
+	}
+
+	logger.Info().Str("user_id", id).Msg("Two-factor authentication locked")
+	if err := s.RedisService.SaveDataWithTTL(utils.TwoFactorBlocked, id, utils.TwoFactorLockout*time.Minute, true); err != nil {
+		logger.Error().Err(err).Msg("Error locking two-factor authentication")
+		return
+	}
+	_ = s.RedisService.DeleteData(utils.TwoFactorFailures, id)
+}
+
+// checkCode validates the code and consumes its time step so it cannot be replayed
+func (s twoFactorService) checkCode(twoFactor *models.UserTwoFactor, code string) error {
+	secret, err := s.Encryption.Decrypt(twoFactor.TotpSecret)
+	if err != nil {
+		return errors.New("unable to read two-factor secret")
+	}
+
+	step, valid := utils.ValidateTotpCode(secret, code, time.Now(), twoFactor.LastUsedStep)
+	if !valid {
+		return errors.New("invalid two-factor code")
+	}
+
+	updated, err := s.UserTwoFactorRepository.UpdateLastUsedStep(twoFactor.UserID, step)
+	if err != nil {
+		return errors.New("unable to verify two-factor code")
+	}
+	if !updated {
+		return errors.New("invalid two-factor code")
+	}
+	twoFactor.LastUsedStep = step
+	return nil
+}
+
+// ReencryptTotpSecrets rewrites TOTP secrets not yet encrypted with AES-GCM under the newest key, so
+// an old key version can be dropped from the keyring
+func (s twoFactorService) ReencryptTotpSecrets() {
+	var reencrypted int
+	for {
+		twoFactors, err := s.UserTwoFactorRepository.GetUserTwoFactorsToReencrypt(utils.KeyVersionPrefix(s.Encryption.KeyVersion()), utils.ReencryptPIIBatchSize)
+		if err != nil {
+			logger.Error().Err(err).Msg("Error getting TOTP secrets to re-encrypt")
+			return
+		}
+
+		var updated int
+		for _, twoFactor := range *twoFactors {
+			secret, err := s.Encryption.Decrypt(twoFactor.TotpSecret)
+			if err != nil {
+				logger.Error().Err(err).Uint("user_id", twoFactor.UserID).Msg("Unable to decrypt TOTP secret")
+				continue
+			}
+			encrypted, err := s.Encryption.Encrypt(secret)
+			if err != nil {
+				logger.Error().Err(err).Uint("user_id", twoFactor.UserID).Msg("Unable to re-encrypt TOTP secret")
+				continue
+			}
+			if err := s.UserTwoFactorRepository.UpdateTotpSecret(twoFactor.UserID, encrypted); err != nil {
+				logger.Error().Err(err).Uint("user_id", twoFactor.UserID).Msg("Unable to update TOTP secret")
+				continue
+			}
+			updated++
+		}
+
+		reencrypted += updated
+		if len(*twoFactors) < utils.ReencryptPIIBatchSize || updated == 0 {
+			if reencrypted > 0 {
+				logger.Info().Int("secrets", reencrypted).Msg("Re-encrypted TOTP secrets")
+			}
+			return
+		}
+	}
+}
@@ -1,18 +1,28 @@
 package services
 
 import (
+	"authentication/internal/dto/in"
+	"authentication/internal/dto/out"
 	"authentication/internal/models"
 	"authentication/internal/repository"
 	"authentication/internal/utils"
-	"github.com/rs/zerolog/log"
+	"authentication/internal/utils/logger"
+	"errors"
 	"time"
 )
 
 type UsersSessionService interface {
 	AddUserSession(userID uint, token, refreshToken, ipAddress, userAgent string) error
+	RotateUserSession(parent *models.RefreshToken, token, refreshToken, ipAddress, userAgent string) error
 	GetUserSessionByUserID(userID uint) (*models.UserSession, error)
-	LogoutSession(userID uint) error
+	GetUserSessions(userID uint, accessUUID string) ([]out.UserSessionResponse, error)
+	LogoutSession(userID uint, accessUUID string) error
+	RevokeSession(userID, sessionID uint) error
+	RevokeOtherSessions(userID uint, accessUUID string) (int, error)
+	GetSessions(filter in.AdminSessionFilter, index, size int) ([]out.AdminSessionResponse, int64, error)
+	ForceLogout(userID uint, clientID string) (int, error)
 	CheckUser()
+	HashLegacyTokens()
 }
 
 type usersSessionService struct {
@@ -20,6 +30,11 @@ type usersSessionService struct {
 	UserRepository        repository.UserRepository
 	JWTService            utils.JWTService
 	Redis                 utils.RedisService
+	RefreshTokenService   RefreshTokenService
+	TokenHasher           utils.TokenHasher
+	TokenRevocation       utils.TokenRevocation
+	SessionActivity       utils.SessionActivity
+	ApiKeyService         ApiKeyService
 }
 
 func NewUsersSessionService(
@@ -27,14 +42,26 @@ func NewUsersSessionService(
 	userRepo repository.UserRepository,
 	jwtService utils.JWTService,
 	redis utils.RedisService,
+	refreshTokenService RefreshTokenService,
+	tokenHasher utils.TokenHasher,
+	tokenRevocation utils.TokenRevocation,
+	sessionActivity utils.SessionActivity,
+	apiKeyService ApiKeyService,
 ) UsersSessionService {
 	return usersSessionService{
 		UserSessionRepository: userSessionRepo,
 		UserRepository:        userRepo,
 		JWTService:            jwtService,
 		Redis:                 redis,
+		RefreshTokenService:   refreshTokenService,
+		TokenHasher:           tokenHasher,
+		TokenRevocation:       tokenRevocation,
+		SessionActivity:       sessionActivity,
+		ApiKeyService:         apiKeyService,
 	}
 }
+
+// AddUserSession records a fresh login as a new session; its refresh token starts a new token family
 func (s usersSessionService) AddUserSession(userID uint, token, refreshToken, ipAddress, userAgent string) error {
 	user, err := s.UserRepository.GetUserByID(userID)
 	if err != nil {
@@ -42,65 +69,268 @@ func (s usersSessionService) AddUserSession(userID uint, token, refreshToken, ip
 	}
 
 	tokenClaims, err := s.JWTService.ExtractClaims(token)
-	var userSession = &models.UserSession{
-		UserID:       userID,
-		SessionToken: token,
-		RefreshToken: refreshToken,
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	userSession := &models.UserSession{
+		UserID:       user.UserID,
+		SessionToken: s.TokenHasher.Hash(token),
+		RefreshToken: s.TokenHasher.Hash(refreshToken),
+		TokensHashed: true,
+		AccessUUID:   tokenClaims.AccessUUID,
 		IPAddress:    ipAddress,
 		UserAgent:    userAgent,
-		LoginTime:    time.Now(),
-		LogoutTime:   nil,
+		LoginTime:    now,
+		LastSeenAt:   &now,
 		ExpiresAt:    time.Unix(tokenClaims.Exp, 0),
+		IsActive:     true,
 		CreatedBy:    user.ClientID,
 		UpdatedBy:    user.ClientID,
 	}
+	if err := s.UserSessionRepository.AddUserSession(userSession); err != nil {
+		return err
+	}
 
-	session, err := s.UserSessionRepository.GetUserSessionByUserID(userID)
-	if err != nil || session == nil {
-		_ = s.UserSessionRepository.AddUserSession(userSession)
-		return nil
+	_ = s.Redis.SaveData(utils.UserSession, user.ClientID, userSession)
+
+	return s.RefreshTokenService.StartFamily(userID, refreshToken, ipAddress, user.ClientID)
+}
+
+// RotateUserSession moves the session holding the consumed refresh token onto the newly issued
+// tokens, keeping the new refresh token in the same family
+func (s usersSessionService) RotateUserSession(parent *models.RefreshToken, token, refreshToken, ipAddress, userAgent string) error {
+	user, err := s.UserRepository.GetUserByID(parent.UserID)
+	if err != nil {
+		return err
 	}
 
-	session.SessionToken = token
-	session.RefreshToken = refreshToken
+	tokenClaims, err := s.JWTService.ExtractClaims(token)
+	if err != nil {
+		return err
+	}
+
+	// parent.Token is already hashed, as is the session's refresh token
+	session, err := s.UserSessionRepository.GetUserSessionByRefreshTokenAndUserID(user.UserID, parent.Token)
+	if err != nil || session == nil || !session.IsActive {
+		return errors.New("user session not found")
+	}
+
+	now := time.Now()
+	session.SessionToken = s.TokenHasher.Hash(token)
+	session.RefreshToken = s.TokenHasher.Hash(refreshToken)
+	session.TokensHashed = true
+	session.AccessUUID = tokenClaims.AccessUUID
 	session.IPAddress = ipAddress
 	session.UserAgent = userAgent
-	session.LoginTime = time.Now()
+	session.LastSeenAt = &now
 	session.ExpiresAt = time.Unix(tokenClaims.Exp, 0)
-	session.IsActive = true
-	session.LogoutTime = nil
 	session.UpdatedBy = user.ClientID
 
-	err = s.UserSessionRepository.UpdateSession(session)
-	if err != nil {
+	if err := s.UserSessionRepository.UpdateSession(session); err != nil {
 		return err
 	}
 
 	_ = s.Redis.SaveData(utils.UserSession, user.ClientID, session)
 
-	return nil
+	return s.RefreshTokenService.IssueInFamily(parent, refreshToken, ipAddress)
 }
 
 func (s usersSessionService) GetUserSessionByUserID(userID uint) (*models.UserSession, error) {
 	return s.UserSessionRepository.GetUserSessionByUserID(userID)
 }
 
-func (s usersSessionService) LogoutSession(userID uint) error {
-	currentTime := time.Now()
+// GetUserSessions lists the active sessions of the user; accessUUID identifies the caller's own session
+func (s usersSessionService) GetUserSessions(userID uint, accessUUID string) ([]out.UserSessionResponse, error) {
+	userSessions, err := s.UserSessionRepository.GetActiveUserSessionsByUserID(userID)
+	if err != nil {
+		return nil, errors.New("unable to get user sessions")
+	}
+
+	responses := make([]out.UserSessionResponse, 0, len(*userSessions))
+	for _, session := range *userSessions {
+		lastSeenAt := session.LastSeenAt
+		if seen, ok := s.SessionActivity.LastSeen(session.AccessUUID); ok && (lastSeenAt == nil || seen.After(*lastSeenAt)) {
+			lastSeenAt = &seen
+		}
+
+		responses = append(responses, out.UserSessionResponse{
+			SessionID:  session.UserSessionID,
+			IPAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+			LoginTime:  session.LoginTime,
+			LastSeenAt: lastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.AccessUUID == accessUUID,
+		})
+	}
+	return responses, nil
+}
+
+// LogoutSession ends the session the given access token belongs to
+func (s usersSessionService) LogoutSession(userID uint, accessUUID string) error {
 	user, err := s.UserRepository.GetUserByID(userID)
 	if err != nil {
 		return err
 	}
 
-	session, err := s.UserSessionRepository.GetUserSessionByUserID(userID)
-	if err != nil {
+	session, err := s.UserSessionRepository.GetUserSessionByAccessUUID(accessUUID)
+	if err != nil || session.UserID != userID {
 		return nil
 	}
+
+	return s.endSession(session, "logout", user.ClientID)
+}
+
+// RevokeSession ends one of the user's sessions, e.g. a lost device
+func (s usersSessionService) RevokeSession(userID, sessionID uint) error {
+	user, err := s.UserRepository.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	session, err := s.UserSessionRepository.GetUserSessionByIDAndUserID(sessionID, userID)
+	if err != nil || !session.IsActive {
+		return errors.New("session not found")
+	}
+
+	return s.endSession(session, "session_revoked", user.ClientID)
+}
+
+// RevokeOtherSessions ends every active session of the user except the caller's own
+func (s usersSessionService) RevokeOtherSessions(userID uint, accessUUID string) (int, error) {
+	user, err := s.UserRepository.GetUserByID(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	userSessions, err := s.UserSessionRepository.GetActiveUserSessionsByUserID(userID)
+	if err != nil {
+		return 0, errors.New("unable to get user sessions")
+	}
+
+	var revoked int
+	for i := range *userSessions {
+		session := &(*userSessions)[i]
+		if session.AccessUUID == accessUUID {
+			continue
+		}
+		if err := s.endSession(session, "session_revoked", user.ClientID); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// GetSessions lists sessions of every user for admins
+func (s usersSessionService) GetSessions(filter in.AdminSessionFilter, index, size int) ([]out.AdminSessionResponse, int64, error) {
+	userSessions, total, err := s.UserSessionRepository.GetUserSessionsByFilter(filter, index, size)
+	if err != nil {
+		return nil, 0, errors.New("unable to get user sessions")
+	}
+
+	users := make(map[uint]*models.Users)
+	responses := make([]out.AdminSessionResponse, 0, len(*userSessions))
+	for _, session := range *userSessions {
+		user, ok := users[session.UserID]
+		if !ok {
+			user, _ = s.UserRepository.GetUserByID(session.UserID)
+			users[session.UserID] = user
+		}
+
+		lastSeenAt := session.LastSeenAt
+		if session.IsActive {
+			if seen, ok := s.SessionActivity.LastSeen(session.AccessUUID); ok && (lastSeenAt == nil || seen.After(*lastSeenAt)) {
+				lastSeenAt = &seen
+			}
+		}
+
+		response := out.AdminSessionResponse{
+			SessionID:  session.UserSessionID,
+			UserID:     session.UserID,
+			IPAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+			LoginTime:  session.LoginTime,
+			LastSeenAt: lastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			LogoutTime: session.LogoutTime,
+			IsActive:   session.IsActive,
+		}
+		if user != nil {
+			response.ClientID = user.ClientID
+			response.Username = user.Username
+		}
+		responses = append(responses, response)
+	}
+	return responses, total, nil
+}
+
+// ForceLogout terminates every session of a (possibly compromised) account: all outstanding access
+// tokens are rejected, every refresh token family is revoked and the cached session data is cleared
+func (s usersSessionService) ForceLogout(userID uint, clientID string) (int, error) {
+	user, err := s.UserRepository.GetUserByID(userID)
+	if err != nil {
+		return 0, errors.New("user not found")
+	}
+
+	if err := s.TokenRevocation.RevokeUserTokens(user.UserID); err != nil {
+		return 0, errors.New("unable to revoke user tokens")
+	}
+
+	userSessions, err := s.UserSessionRepository.GetActiveUserSessionsByUserID(user.UserID)
+	if err != nil {
+		return 0, errors.New("unable to get user sessions")
+	}
+
+	var terminated int
+	for i := range *userSessions {
+		if err := s.endSession(&(*userSessions)[i], "admin_logout", clientID); err != nil {
+			return terminated, errors.New("unable to terminate user session")
+		}
+		terminated++
+	}
+
+	if err := s.RefreshTokenService.RevokeUserTokens(user.UserID, "admin_logout", clientID); err != nil {
+		return terminated, errors.New("unable to revoke refresh tokens")
+	}
+
+	if err := s.ApiKeyService.RevokeAllUserApiKeys(user.UserID, clientID); err != nil {
+		return terminated, err
+	}
+
+	if err := s.Redis.DeleteData(utils.UserSession, user.ClientID); err != nil {
+		logger.Error().Err(err).Msg("Failed to delete data from Redis")
+	}
+	if err := s.Redis.DeleteToken(user.ClientID); err != nil {
+		logger.Error().Err(err).Msg("Failed to delete token from Redis")
+	}
+	if err := s.Redis.DeleteData(utils.User, user.ClientID); err != nil {
+		logger.Error().Err(err).Msg("Failed to delete user from Redis")
+	}
+
+	logger.Info().Str("client_id", user.ClientID).Str("by", clientID).Int("sessions", terminated).Msg("User sessions terminated by admin")
+	return terminated, nil
+}
+
+// endSession closes the session, rejects its access token and revokes its refresh token family
+func (s usersSessionService) endSession(session *models.UserSession, reason, updatedBy string) error {
+	now := time.Now()
 	session.IsActive = false
-	session.LogoutTime = &currentTime
-	session.UpdatedBy = user.ClientID
+	session.LogoutTime = &now
+	session.UpdatedBy = updatedBy
+	if seen, ok := s.SessionActivity.LastSeen(session.AccessUUID); ok {
+		session.LastSeenAt = &seen
+	}
 
-	_ = s.Redis.SaveData(utils.UserSession, user.ClientID, session)
+	if err := s.TokenRevocation.RevokeToken(session.AccessUUID, session.ExpiresAt.Unix()); err != nil {
+		return err
+	}
+
+	if err := s.RefreshTokenService.RevokeFamilyByToken(session.RefreshToken, reason, updatedBy); err != nil {
+		return err
+	}
 
 	return s.UserSessionRepository.UpdateSession(session)
 }
@@ -108,35 +338,68 @@ func (s usersSessionService) LogoutSession(userID uint) error {
 func (s usersSessionService) CheckUser() {
 	userSession, err := s.UserSessionRepository.GetUserSessionExpired()
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to get user session")
+		logger.Error().Err(err).Msg("Failed to get user session")
 		return
 	}
 
 	for _, session := range *userSession {
 		go func(session models.UserSession) {
 			if time.Now().After(session.ExpiresAt) && session.IsActive {
+				// only this session ends; the per-client keys belong to whichever session logged in last
 				session.IsActive = false
 				session.UpdatedBy = "system"
-				_ = s.UserSessionRepository.UpdateSession(&session)
-				user, _ := s.UserRepository.GetUserByID(session.UserID)
-				if user != nil {
-					log.Info().Str("client_id", user.ClientID).Msg("User session expired")
-					err = s.Redis.DeleteData(utils.UserSession, user.ClientID)
-					if err != nil {
-						log.Error().Err(err).Msg("Failed to delete data from Redis")
-					}
-					err = s.Redis.DeleteToken(user.ClientID)
-					if err != nil {
-						log.Error().Err(err).Msg("Failed to delete token from Redis")
-					}
-					err = s.Redis.DeleteData(utils.User, user.ClientID)
-					if err != nil {
-						log.Error().Err(err).Msg("Failed to delete user from Redis")
-					}
-				} else {
-					log.Error().Err(err).Msg("Failed to get user by ID")
+				if seen, ok := s.SessionActivity.LastSeen(session.AccessUUID); ok {
+					session.LastSeenAt = &seen
+				}
+				if err := s.UserSessionRepository.UpdateSession(&session); err != nil {
+					logger.Error().Err(err).Uint("user_session_id", session.UserSessionID).Msg("Failed to expire user session")
+					return
+				}
+				if err := s.Redis.DeleteData(utils.SessionLastSeen, session.AccessUUID); err != nil {
+					logger.Error().Err(err).Msg("Failed to delete session activity from Redis")
 				}
+				logger.Info().Uint("user_id", session.UserID).Str("access_uuid", session.AccessUUID).Msg("User session expired")
 			}
 		}(session)
 	}
 }
+
+// HashLegacyTokens replaces session and refresh tokens stored in plaintext before hashing was introduced
+func (s usersSessionService) HashLegacyTokens() {
+	var hashed int
+	for {
+		userSessions, err := s.UserSessionRepository.GetUserSessionsWithRawTokens(utils.LegacyTokenBatchSize)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to get user sessions with raw tokens")
+			return
+		}
+
+		var updated int
+		for _, session := range *userSessions {
+			refreshToken := session.RefreshToken
+			if refreshToken != "" {
+				refreshToken = s.TokenHasher.Hash(refreshToken)
+			}
+			err := s.UserSessionRepository.UpdateSessionTokens(session.UserSessionID, s.TokenHasher.Hash(session.SessionToken), refreshToken)
+			if err != nil {
+				logger.Error().Err(err).Uint("user_session_id", session.UserSessionID).Msg("Failed to hash user session tokens")
+				continue
+			}
+			updated++
+		}
+
+		hashed += updated
+		if len(*userSessions) < utils.LegacyTokenBatchSize || updated == 0 {
+			break
+		}
+	}
+
+	refreshTokens, err := s.RefreshTokenService.HashLegacyTokens()
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to hash refresh tokens")
+	}
+
+	if hashed > 0 || refreshTokens > 0 {
+		logger.Info().Int("user_sessions", hashed).Int("refresh_tokens", refreshTokens).Msg("Hashed legacy tokens")
+	}
+}
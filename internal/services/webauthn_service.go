@@ -0,0 +1,344 @@
+package services
+
+import (
+	"authentication/internal/dto/in"
+	"authentication/internal/dto/out"
+	"authentication/internal/models"
+	"authentication/internal/repository"
+	"authentication/internal/utils"
+	"encoding/base64"
+	"errors"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"time"
+)
+
+type WebauthnService interface {
+	BeginRegistration(clientID string) (*protocol.CredentialCreation, error)
+	FinishRegistration(body []byte, name, clientID string) (out.WebauthnCredentialResponse, error)
+	BeginLogin(req *in.WebauthnLoginBeginRequest) (out.WebauthnLoginBeginResponse, error)
+	FinishLogin(body []byte, sessionID, deviceID string) (interface{}, error)
+	GetCredentials(clientID string) ([]out.WebauthnCredentialResponse, error)
+	DeleteCredential(id uint, clientID string) error
+}
+
+type webauthnService struct {
+	UserRepository               repository.UserRepository
+	WebauthnCredentialRepository repository.WebauthnCredentialRepository
+	RedisService                 utils.RedisService
+	AuthService                  AuthService
+	WebAuthn                     *webauthn.WebAuthn
+}
+
+func NewWebauthnService(
+	userRepo repository.UserRepository,
+	webauthnCredentialRepo repository.WebauthnCredentialRepository,
+	redis utils.RedisService,
+	authService AuthService,
+	webAuthn *webauthn.WebAuthn,
+) WebauthnService {
+	return webauthnService{
+		UserRepository:               userRepo,
+		WebauthnCredentialRepository: webauthnCredentialRepo,
+		RedisService:                 redis,
+		AuthService:                  authService,
+		WebAuthn:                     webAuthn,
+	}
+}
+
+// webauthnUser adapts a user and its stored credentials to the webauthn.User interface
+type webauthnUser struct {
+	user        *models.Users
+	credentials []webauthn.Credential
+}
+
+func (u webauthnUser) WebAuthnID() []byte {
+	return []byte(u.user.ClientID)
+}
+
+func (u webauthnUser) WebAuthnName() string {
+	return u.user.Username
+}
+
+func (u webauthnUser) WebAuthnDisplayName() string {
+	if u.user.FirstName == "" {
+		return u.user.Username
+	}
+	return u.user.FirstName + " " + u.user.LastName
+}
+
+func (u webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+func (s webauthnService) BeginRegistration(clientID string) (*protocol.CredentialCreation, error) {
+	user, err := s.loadUser(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	var exclusions []protocol.CredentialDescriptor
+	for _, credential := range user.credentials {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	creation, session, err := s.WebAuthn.BeginRegistration(user,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		return nil, errors.New("unable to start passkey registration")
+	}
+
+	if err := s.RedisService.SaveDataExpired(utils.WebauthnRegistration, clientID, utils.WebauthnSessionExpiry, session); err != nil {
+		return nil, errors.New("unable to start passkey registration")
+	}
+	return creation, nil
+}
+
+func (s webauthnService) FinishRegistration(body []byte, name, clientID string) (out.WebauthnCredentialResponse, error) {
+	var session webauthn.SessionData
+	if err := s.RedisService.GetData(utils.WebauthnRegistration, clientID, &session); err != nil {
+		return out.WebauthnCredentialResponse{}, errors.New("passkey registration is invalid or expired")
+	}
+	_ = s.RedisService.DeleteData(utils.WebauthnRegistration, clientID)
+
+	user, err := s.loadUser(clientID)
+	if err != nil {
+		return out.WebauthnCredentialResponse{}, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(body)
+	if err != nil {
+		return out.WebauthnCredentialResponse{}, errors.New("invalid passkey registration response")
+	}
+
+	credential, err := s.WebAuthn.CreateCredential(user, session, parsed)
+	if err != nil {
+		return out.WebauthnCredentialResponse{}, errors.New("passkey registration could not be verified")
+	}
+
+	if name == "" {
+		name = "Passkey"
+	}
+
+	var transports []string
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	record := &models.WebauthnCredential{
+		UserID:          user.user.UserID,
+		CredentialID:    base64.RawURLEncoding.EncodeToString(credential.ID),
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       int64(credential.Authenticator.SignCount),
+		Flags:           int(credential.Flags.ProtocolValue()),
+		Name:            name,
+		CreatedBy:       clientID,
+		UpdatedBy:       clientID,
+	}
+	if err := s.WebauthnCredentialRepository.AddCredential(record); err != nil {
+		return out.WebauthnCredentialResponse{}, errors.New("unable to save passkey")
+	}
+
+	return credentialResponse(record), nil
+}
+
+func (s webauthnService) BeginLogin(req *in.WebauthnLoginBeginRequest) (out.WebauthnLoginBeginResponse, error) {
+	var (
+		assertion *protocol.CredentialAssertion
+		session   *webauthn.SessionData
+	)
+
+	if req.Username == "" {
+		var err error
+		assertion, session, err = s.WebAuthn.BeginDiscoverableLogin()
+		if err != nil {
+			return out.WebauthnLoginBeginResponse{}, errors.New("unable to start passkey login")
+		}
+	} else {
+		users, err := s.UserRepository.GetUserByUsername(req.Username)
+		if err != nil {
+			return out.WebauthnLoginBeginResponse{}, errors.New("no passkey registered for this user")
+		}
+
+		user, err := s.loadUser(users.ClientID)
+		if err != nil || len(user.credentials) == 0 {
+			return out.WebauthnLoginBeginResponse{}, errors.New("no passkey registered for this user")
+		}
+
+		assertion, session, err = s.WebAuthn.BeginLogin(user)
+		if err != nil {
+			return out.WebauthnLoginBeginResponse{}, errors.New("unable to start passkey login")
+		}
+	}
+
+	sessionID := uuid.New().String()
+	if err := s.RedisService.SaveDataExpired(utils.WebauthnLogin, sessionID, utils.WebauthnSessionExpiry, session); err != nil {
+		return out.WebauthnLoginBeginResponse{}, errors.New("unable to start passkey login")
+	}
+
+	return out.WebauthnLoginBeginResponse{
+		SessionID: sessionID,
+		Options:   assertion,
+	}, nil
+}
+
+func (s webauthnService) FinishLogin(body []byte, sessionID, deviceID string) (interface{}, error) {
+	var session webauthn.SessionData
+	if err := s.RedisService.GetData(utils.WebauthnLogin, sessionID, &session); err != nil {
+		return nil, errors.New("passkey login is invalid or expired")
+	}
+	_ = s.RedisService.DeleteData(utils.WebauthnLogin, sessionID)
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(body)
+	if err != nil {
+		return nil, errors.New("invalid passkey login response")
+	}
+
+	var (
+		user       webauthn.User
+		credential *webauthn.Credential
+	)
+	if len(session.UserID) == 0 {
+		user, credential, err = s.WebAuthn.ValidatePasskeyLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+			return s.loadUser(string(userHandle))
+		}, session, parsed)
+	} else {
+		user, err = s.loadUser(string(session.UserID))
+		if err == nil {
+			credential, err = s.WebAuthn.ValidateLogin(user, session, parsed)
+		}
+	}
+	if err != nil {
+		return nil, errors.New("passkey could not be verified")
+	}
+
+	if credential.Authenticator.CloneWarning {
+		return nil, errors.New("passkey could not be verified")
+	}
+
+	record, err := s.WebauthnCredentialRepository.GetCredentialByCredentialID(base64.RawURLEncoding.EncodeToString(credential.ID))
+	if err != nil {
+		return nil, errors.New("passkey not found")
+	}
+
+	now := time.Now()
+	record.SignCount = int64(credential.Authenticator.SignCount)
+	record.Flags = int(credentialFlagsValue(credential.Flags))
+	record.LastUsedAt = &now
+	record.UpdatedBy = string(user.WebAuthnID())
+	if err := s.WebauthnCredentialRepository.UpdateCredential(record); err != nil {
+		return nil, errors.New("unable to update passkey")
+	}
+
+	return s.AuthService.CompleteLogin(user.(webauthnUser).user, deviceID)
+}
+
+func (s webauthnService) GetCredentials(clientID string) ([]out.WebauthnCredentialResponse, error) {
+	user, err := s.UserRepository.GetUserByClientID(clientID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	credentials, err := s.WebauthnCredentialRepository.GetCredentialsByUserID(user.UserID)
+	if err != nil {
+		return nil, errors.New("unable to get passkeys")
+	}
+
+	responses := make([]out.WebauthnCredentialResponse, 0, len(*credentials))
+	for i := range *credentials {
+		responses = append(responses, credentialResponse(&(*credentials)[i]))
+	}
+	return responses, nil
+}
+
+func (s webauthnService) DeleteCredential(id uint, clientID string) error {
+	user, err := s.UserRepository.GetUserByClientID(clientID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+
+	credential, err := s.WebauthnCredentialRepository.GetCredentialByIDAndUserID(id, user.UserID)
+	if err != nil {
+		return errors.New("passkey not found")
+	}
+
+	credential.DeletedBy = clientID
+	if err := s.WebauthnCredentialRepository.DeleteCredential(credential); err != nil {
+		return errors.New("unable to delete passkey")
+	}
+	return nil
+}
+
+// loadUser resolves the user behind a WebAuthn user handle together with its registered credentials
+func (s webauthnService) loadUser(clientID string) (webauthnUser, error) {
+	user, err := s.UserRepository.GetUserByClientID(clientID)
+	if err != nil || user.UserID == 0 {
+		return webauthnUser{}, errors.New("user not found")
+	}
+
+	records, err := s.WebauthnCredentialRepository.GetCredentialsByUserID(user.UserID)
+	if err != nil {
+		return webauthnUser{}, errors.New("unable to get passkeys")
+	}
+
+	credentials := make([]webauthn.Credential, 0, len(*records))
+	for _, record := range *records {
+		credentialID, err := base64.RawURLEncoding.DecodeString(record.CredentialID)
+		if err != nil {
+			continue
+		}
+
+		var transports []protocol.AuthenticatorTransport
+		for _, transport := range record.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+
+		credentials = append(credentials, webauthn.Credential{
+			ID:              credentialID,
+			PublicKey:       record.PublicKey,
+			AttestationType: record.AttestationType,
+			Transport:       transports,
+			Flags:           webauthn.NewCredentialFlags(protocol.AuthenticatorFlags(record.Flags)),
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    record.AAGUID,
+				SignCount: uint32(record.SignCount),
+			},
+		})
+	}
+
+	return webauthnUser{user: user, credentials: credentials}, nil
+}
+
+// credentialFlagsValue rebuilds the raw flag byte after a login has refreshed the individual flags
+func credentialFlagsValue(flags webauthn.CredentialFlags) protocol.AuthenticatorFlags {
+	var value protocol.AuthenticatorFlags
+	if flags.UserPresent {
+		value |= protocol.FlagUserPresent
+	}
+	if flags.UserVerified {
+		value |= protocol.FlagUserVerified
+	}
+	if flags.BackupEligible {
+		value |= protocol.FlagBackupEligible
+	}
+	if flags.BackupState {
+		value |= protocol.FlagBackupState
+	}
+	return value
+}
+
+func credentialResponse(credential *models.WebauthnCredential) out.WebauthnCredentialResponse {
+	return out.WebauthnCredentialResponse{
+		ID:         credential.ID,
+		Name:       credential.Name,
+		Transports: credential.Transports,
+		LastUsedAt: credential.LastUsedAt,
+		CreatedAt:  credential.CreatedAt,
+	}
+}
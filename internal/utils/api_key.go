@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ApiKeyScheme is the Authorization scheme API keys are sent with
+	ApiKeyScheme = "ApiKey "
+	// ApiKeyMarker starts every key so leaked keys are easy to spot
+	ApiKeyMarker = "ak_"
+
+	ApiKeyStatusActive  = "active"
+	ApiKeyStatusExpired = "expired"
+	ApiKeyStatusRevoked = "revoked"
+
+	// ApiKeyLastUsedInterval limits how often last_used_at is written for a busy key
+	ApiKeyLastUsedInterval = time.Minute
+)
+
+var (
+	ErrApiKeyInvalid = errors.New("API key is invalid")
+	ErrApiKeyRevoked = errors.New("API key has been revoked or has expired")
+)
+
+// GenerateApiKey returns a new key in the form ak_<prefix>_<secret> together with its prefix
+func GenerateApiKey() (string, string, error) {
+	prefixBytes := make([]byte, 6)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", err
+	}
+	secret, err := GenerateOAuthToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	prefix := ApiKeyMarker + hex.EncodeToString(prefixBytes)
+	return prefix + "_" + secret, prefix, nil
+}
+
+// ParseApiKeyPrefix returns the prefix of a key generated by GenerateApiKey
+func ParseApiKeyPrefix(key string) (string, bool) {
+	if !strings.HasPrefix(key, ApiKeyMarker) {
+		return "", false
+	}
+	separator := strings.Index(key[len(ApiKeyMarker):], "_")
+	if separator <= 0 {
+		return "", false
+	}
+	return key[:len(ApiKeyMarker)+separator], true
+}
+
+// ApiKeyAccessUUID identifies requests made with an API key wherever an access token has its access
+// UUID, e.g. as the jti on introspection or in the deny list
+func ApiKeyAccessUUID(id uint) string {
+	return "apikey:" + strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"os"
+	"strings"
+)
+
+// BloomFilter answers set membership in a fixed amount of memory. It never misses a value that
+// was added but may report a value that was not, at roughly the rate it was sized for.
+type BloomFilter struct {
+	bits   []uint64
+	size   uint64
+	hashes uint64
+}
+
+// NewBloomFilter sizes a filter for the expected number of values and false positive rate
+func NewBloomFilter(expected int, falsePositiveRate float64) *BloomFilter {
+	if expected < 1 {
+		expected = 1
+	}
+	size := uint64(math.Ceil(-float64(expected) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	hashes := uint64(math.Max(1, math.Round(float64(size)/float64(expected)*math.Ln2)))
+	return &BloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+// LoadBloomFilter builds a filter from a file holding one value per line
+func LoadBloomFilter(path string, falsePositiveRate float64) (*BloomFilter, error) {
+	count := 0
+	if err := scanLines(path, func(string) { count++ }); err != nil {
+		return nil, err
+	}
+
+	filter := NewBloomFilter(count, falsePositiveRate)
+	if err := scanLines(path, filter.Add); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+func (b *BloomFilter) Add(value string) {
+	h1, h2 := bloomHashes(value)
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.size
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (b *BloomFilter) Contains(value string) bool {
+	h1, h2 := bloomHashes(value)
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.size
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomHashes derives the two hashes combined into every bit position (Kirsch-Mitzenmacher)
+func bloomHashes(value string) (uint64, uint64) {
+	sum := sha256.Sum256([]byte(value))
+	return binary.BigEndian.Uint64(sum[0:8]), binary.BigEndian.Uint64(sum[8:16]) | 1
+}
+
+func scanLines(path string, fn func(string)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			fn(line)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBloomFilterContainsAddedValues(t *testing.T) {
+	filter := NewBloomFilter(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		filter.Add(fmt.Sprintf("password%d", i))
+	}
+
+	for i := 0; i < 1000; i++ {
+		if value := fmt.Sprintf("password%d", i); !filter.Contains(value) {
+			t.Fatalf("Contains(%q) = false for an added value", value)
+		}
+	}
+}
+
+func TestBloomFilterFalsePositiveRate(t *testing.T) {
+	tests := []struct {
+		expected int
+		rate     float64
+	}{
+		{1000, 0.01},
+		{5000, 0.001},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d values at %v", tt.expected, tt.rate), func(t *testing.T) {
+			filter := NewBloomFilter(tt.expected, tt.rate)
+			for i := 0; i < tt.expected; i++ {
+				filter.Add(fmt.Sprintf("member-%d", i))
+			}
+
+			const probes = 100000
+			var falsePositives int
+			for i := 0; i < probes; i++ {
+				if filter.Contains(fmt.Sprintf("stranger-%d", i)) {
+					falsePositives++
+				}
+			}
+
+			// allow some slack over the target, the filter only meets it on average
+			if got := float64(falsePositives) / probes; got > tt.rate*2 {
+				t.Errorf("false positive rate = %v, want about %v", got, tt.rate)
+			}
+		})
+	}
+}
+
+func TestNewBloomFilterSizing(t *testing.T) {
+	tests := []struct {
+		name       string
+		expected   int
+		rate       float64
+		wantSize   uint64
+		wantHashes uint64
+	}{
+		// m = -n ln p / (ln 2)^2, k = m/n ln 2
+		{"one percent", 1000, 0.01, 9586, 7},
+		{"one in a thousand", 1000, 0.001, 14378, 10},
+		{"no values", 0, 0.01, 10, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := NewBloomFilter(tt.expected, tt.rate)
+			if filter.size != tt.wantSize || filter.hashes != tt.wantHashes {
+				t.Errorf("NewBloomFilter() size = %d, hashes = %d, want %d, %d", filter.size, filter.hashes, tt.wantSize, tt.wantHashes)
+			}
+			if uint64(len(filter.bits))*64 < filter.size {
+				t.Errorf("NewBloomFilter() has %d words for %d bits", len(filter.bits), filter.size)
+			}
+		})
+	}
+}
+
+func TestLoadBloomFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte("123456\n  password  \n\nqwerty\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	filter, err := LoadBloomFilter(path, 0.001)
+	if err != nil {
+		t.Fatalf("LoadBloomFilter() error = %v", err)
+	}
+
+	for _, value := range []string{"123456", "password", "qwerty"} {
+		if !filter.Contains(value) {
+			t.Errorf("Contains(%q) = false for a listed value", value)
+		}
+	}
+	if filter.Contains("") {
+		t.Error("Contains(\"\") = true, blank lines must be skipped")
+	}
+
+	if _, err := LoadBloomFilter(filepath.Join(t.TempDir(), "missing.txt"), 0.001); err == nil {
+		t.Error("LoadBloomFilter() of a missing file succeeded")
+	}
+}
@@ -2,6 +2,7 @@ package cdn
 
 import (
 	"authentication/internal/dto/out"
+	"authentication/internal/utils/logger"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -31,7 +32,7 @@ func UploadImageToCDN(ipCdn string, file *multipart.FileHeader, clientID, authTo
 
 	defer func() {
 		if err := src.Close(); err != nil {
-			fmt.Println("Error closing file:", err)
+			logger.Error().Err(err).Msg("Error closing file")
 		}
 	}()
 
@@ -57,7 +58,7 @@ func UploadImageToCDN(ipCdn string, file *multipart.FileHeader, clientID, authTo
 
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
-			fmt.Println("Error closing response body:", err)
+			logger.Error().Err(err).Msg("Error closing response body")
 		}
 	}()
 
@@ -1,26 +1,110 @@
 package utils
 
 const (
-	User           = "user"
-	Token          = "token"
-	UserKey        = "user_key"
-	PinVerify      = "pin_verify"
-	DeviceVerify   = "device_verify"
-	ForgotPassword = "forgot_password"
-	UserSession    = "user_session"
-	CredentialKey  = "credential_key"
-	ClientID       = "client_id"
-	UserID         = "user_id"
-	RoleID         = "role_id"
-	PageIndex      = "page_index"
-	PageSize       = "page_size"
-)
-
-const (
-	TableUsersName        = "users"
-	TableUserKeysName     = "user_keys"
-	TableUserResourceName = "user_resources"
-	TableRolesName        = "roles"
-	TableUserRolesName    = "user_roles"
-	TableResourcesName    = "resources"
+	User                   = "user"
+	Token                  = "token"
+	UserKey                = "user_key"
+	PinVerify              = "pin_verify"
+	DeviceVerify           = "device_verify"
+	ForgotPassword         = "forgot_password"
+	UserSession            = "user_session"
+	CredentialKey          = "credential_key"
+	TwoFactorChallenge     = "two_factor_challenge"
+	TwoFactorFailures      = "two_factor_failures"
+	TwoFactorBlocked       = "two_factor_blocked"
+	WebauthnRegistration   = "webauthn_registration"
+	WebauthnLogin          = "webauthn_login"
+	OauthAuthorizationCode = "oauth_authorization_code"
+	OauthRefreshToken      = "oauth_refresh_token"
+	OauthBrowserSession    = "oauth_browser_session"
+	RevokedAccessToken     = "revoked_access_token"
+	RevokedBefore          = "revoked_before"
+	SessionLastSeen        = "session_last_seen"
+	LoginAttemptUser       = "login_attempt_user"
+	LoginAttemptIP         = "login_attempt_ip"
+	LoginBlockedUser       = "login_blocked_user"
+	LoginBlockedIP         = "login_blocked_ip"
+	RateLimit              = "rate_limit"
+	RequestID              = "request_id"
+	InternalToken          = "internal_token"
+	ClientID               = "client_id"
+	UserID                 = "user_id"
+	RoleID                 = "role_id"
+	PageIndex              = "page_index"
+	PageSize               = "page_size"
+)
+
+const (
+	RequestIDHeader    = "X-Request-ID"
+	RequestIDMaxLength = 128
+)
+
+const (
+	TableUsersName           = "users"
+	TableUserKeysName        = "user_keys"
+	TableUserResourceName    = "user_resources"
+	TableRolesName           = "roles"
+	TableUserRolesName       = "user_roles"
+	TableResourcesName       = "resources"
+	TablePasswordHistoryName = "password_history"
+)
+
+const (
+	LoginMethodPassword = "password"
+	LoginMethodPhone    = "phone"
+)
+
+const (
+	TwoFactorChallengeExpiry = 5 // minutes
+	TwoFactorMaxAttempts     = 5
+	TwoFactorMaxFailures     = 10 // per user across challenges, before the second factor is locked
+	TwoFactorFailureWindow   = 60 // minutes
+	TwoFactorLockout         = 15 // minutes
+)
+
+const (
+	WebauthnSessionExpiry = 5 // minutes
+)
+
+const (
+	OauthAuthorizationCodeExpiry = 5  // minutes
+	OauthBrowserSessionExpiry    = 30 // minutes
+	OauthSessionCookie           = "oauth_session"
+	OauthAuthorizePath           = "/oauth/authorize"
+	OauthConsentApprove          = "approve"
+)
+
+const (
+	SigningKeyRetireGrace = 25 // hours, the least a superseded key keeps verifying: longer than a user access token lives
+)
+
+const (
+	RefreshTokenActive  = "active"
+	RefreshTokenRotated = "rotated"
+	RefreshTokenRevoked = "revoked"
+	RefreshTokenExpiry  = 24 * 7 // hours
+	AccessTokenExpiry   = 24     // hours
+)
+
+const (
+	LegacyTokenBatchSize = 500
+)
+
+const (
+	EncryptedValuePrefix  = "gcm:"
+	ReencryptPIIBatchSize = 200
+)
+
+const (
+	SecurityEventSubject    = "security"
+	EventRefreshTokenReused = "refresh_token_reuse"
+	EventLoginLocked        = "login_locked"
+)
+
+const (
+	EventPinLocked = "pin_locked"
+)
+
+const (
+	LoginAttemptDelayMax = 30 // seconds, longest pause between failures before the lockout applies
 )
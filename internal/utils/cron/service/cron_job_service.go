@@ -2,9 +2,10 @@ package service
 
 import (
 	"authentication/internal/services"
+	"authentication/internal/utils"
 	"authentication/internal/utils/cron/model"
 	"authentication/internal/utils/cron/repository"
-	"log"
+	"authentication/internal/utils/logger"
 	"sync"
 	"time"
 
@@ -28,10 +29,12 @@ type cronService struct {
 	cronRepository repository.CronRepository
 	userSession    services.UsersSessionService
 	authService    services.AuthService
+	signingKey     services.SigningKeyService
+	jwtService     utils.JWTService
 }
 
 // NewCronService initializes and returns a CronService instance
-func NewCronService(db gorm.DB, cronRepository repository.CronRepository, userSession services.UsersSessionService, authService services.AuthService) CronService {
+func NewCronService(db gorm.DB, cronRepository repository.CronRepository, userSession services.UsersSessionService, authService services.AuthService, signingKey services.SigningKeyService, jwtService utils.JWTService) CronService {
 	return &cronService{
 		db:             db,
 		scheduler:      cron.New(), // Enables second-level precision
@@ -40,6 +43,8 @@ func NewCronService(db gorm.DB, cronRepository repository.CronRepository, userSe
 		cronRepository: cronRepository,
 		userSession:    userSession,
 		authService:    authService,
+		signingKey:     signingKey,
+		jwtService:     jwtService,
 	}
 }
 
@@ -57,7 +62,7 @@ func (cs *cronService) loadJobsFromDB() {
 
 	cronJobs, err := cs.cronRepository.GetCronJobs()
 	if err != nil {
-		log.Println("Error loading cron jobs from DB:", err)
+		logger.Error().Err(err).Msg("Error loading cron jobs from DB")
 		return
 	}
 
@@ -78,7 +83,7 @@ func (cs *cronService) scheduleJob(job model.CronJob) {
 		cs.executeJob(job)
 	})
 	if err != nil {
-		log.Println("Error scheduling job:", err)
+		logger.Error().Err(err).Str("job", job.Name).Msg("Error scheduling job")
 		return
 	}
 
@@ -92,7 +97,7 @@ func (cs *cronService) executeJob(job model.CronJob) {
 	if !job.LastExecutedAt.IsZero() {
 		expectedNextRun := job.LastExecutedAt.Add(cs.getJobInterval(job.Schedule))
 		if now.After(expectedNextRun) {
-			log.Printf("Job %s missed its scheduled run. Executing catch-up.\n", job.Name)
+			logger.Warn().Str("job", job.Name).Msg("Job missed its scheduled run. Executing catch-up.")
 			// Handle missed execution as needed
 		}
 	}
@@ -100,7 +105,7 @@ func (cs *cronService) executeJob(job model.CronJob) {
 	// Update the last executed time
 	job.LastExecutedAt = now
 	if err := cs.db.Save(&job).Error; err != nil {
-		log.Println("Error updating job last executed time:", err)
+		logger.Error().Err(err).Str("job", job.Name).Msg("Error updating job last executed time")
 	}
 
 	// Perform the actual job task
@@ -109,8 +114,18 @@ func (cs *cronService) executeJob(job model.CronJob) {
 		cs.userSession.CheckUser()
 	case "reset_pin_attempts":
 		cs.authService.ResetPinAttempts()
+	case "hash_legacy_tokens":
+		cs.userSession.HashLegacyTokens()
+	case "reencrypt_pii":
+		cs.authService.ReencryptPII()
+		cs.signingKey.ReencryptSigningKeys()
+	case "signing_key_rotation":
+		cs.signingKey.RotateSigningKeys()
+		if err := cs.jwtService.ReloadKeys(); err != nil {
+			logger.Error().Err(err).Msg("Error reloading signing keys")
+		}
 	default:
-		log.Printf("Unknown job: %s\n", job.Name)
+		logger.Warn().Str("job", job.Name).Msg("Unknown job")
 	}
 }
 
@@ -120,7 +135,7 @@ func (cs *cronService) getJobInterval(schedule string) time.Duration {
 
 func (cs *cronService) AddCronJob(job model.CronJob) {
 	if err := cs.cronRepository.Create(&job); err != nil {
-		log.Println("Error creating cron job:", err)
+		logger.Error().Interface("error", err).Str("job", job.Name).Msg("Error creating cron job")
 		return
 	}
 }
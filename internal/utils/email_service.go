@@ -1,7 +1,7 @@
 package utils
 
 import (
-	"fmt"
+	"authentication/internal/utils/logger"
 	"net/smtp"
 	"os"
 	"strings"
@@ -24,9 +24,9 @@ func SendEmail(to []string, subject string, body string) {
 
 	err := smtp.SendMail(smtpHost+":"+smtpPort, auth, senderEmail, to, message)
 	if err != nil {
-		fmt.Println("❌ Failed to send email:", err)
+		logger.Error().Err(err).Msg("❌ Failed to send email")
 		return
 	}
 
-	fmt.Println("✅ Email sent successfully!")
+	logger.Info().Msg("✅ Email sent successfully!")
 }
@@ -4,6 +4,7 @@ import (
 	"authentication/internal/models"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
@@ -13,61 +14,181 @@ import (
 	"errors"
 	"fmt"
 	"golang.org/x/crypto/argon2"
-	"golang.org/x/crypto/bcrypt"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
+// ErrNotCiphertext is returned when a stored value cannot be a ciphertext, such as a value that was
+// never encrypted
+var ErrNotCiphertext = errors.New("value is not a ciphertext")
+
 // Encryption interface defines the methods for encryption, decryption, and hashing
 type Encryption interface {
 	Encrypt(text string) (string, error)
 	Decrypt(encryptedText string) (string, error)
+	IsLegacy(encryptedText string) bool
+	NeedsReencrypt(encryptedText string) bool
+	KeyVersion() int
 	HashPhoneNumber(phone string) string
+	HashEmail(email string) string
 	HashPassword(password string) (*string, error)
 	CheckPassword(hash, password string) error
+	NeedsRehash(hash string) bool
 }
 
-// encryption struct contains AES key and IV
+// encryption struct contains the AES keyring, the IV of legacy ciphertexts and the blind index key
 type encryption struct {
-	key []byte
-	iv  []byte
+	keys          map[int][]byte
+	version       int
+	iv            []byte
+	blindIndexKey []byte
 }
 
-// NewEncryption initializes encryption with a 32-byte AES key and 16-byte IV
-func NewEncryption(key string, iv string) Encryption {
-	hashedKey := sha256.Sum256([]byte(key)) // Ensure 32-byte key for AES-256
-	hashedIV := sha256.Sum256([]byte(iv))   // Ensure IV is at least 16 bytes
-	return &encryption{
-		key: hashedKey[:],
-		iv:  hashedIV[:aes.BlockSize], // Use first 16 bytes of the hash
+// NewEncryption initializes encryption with a keyring of AES keys by version. New values are
+// encrypted with the highest version, older versions are kept to read what they encrypted and may
+// only be removed once the reencrypt_pii job has rewritten every value under the newest one. The IV
+// is only needed to read values written before AES-GCM, the blind index key keys the lookup hashes
+// of encrypted columns.
+func NewEncryption(keys map[int]string, iv string, blindIndexKey string) Encryption {
+	hashedIV := sha256.Sum256([]byte(iv)) // Ensure IV is at least 16 bytes
+	e := &encryption{
+		keys:          make(map[int][]byte, len(keys)),
+		iv:            hashedIV[:aes.BlockSize], // Use first 16 bytes of the hash
+		blindIndexKey: []byte(blindIndexKey),
+	}
+	for version, key := range keys {
+		hashedKey := sha256.Sum256([]byte(key)) // Ensure 32-byte key for AES-256
+		e.keys[version] = hashedKey[:]
+		if version > e.version {
+			e.version = version
+		}
 	}
+	return e
+}
+
+// KeyVersionPrefix is the prefix of values encrypted with the given key version
+func KeyVersionPrefix(version int) string {
+	return fmt.Sprintf("%sv%d:", EncryptedValuePrefix, version)
 }
 
-// Encrypt encrypts text using AES-256 with a fixed IV (Deterministic)
+// Encrypt encrypts text using AES-256-GCM under the newest key with a random nonce, so equal values
+// give different ciphertexts and tampering is detected
 func (a *encryption) Encrypt(text string) (string, error) {
-	block, err := aes.NewCipher(a.key)
+	gcm, err := a.gcm(a.version)
 	if err != nil {
-		return "", errors.New("failed to create AES cipher: " + err.Error())
+		return "", err
 	}
 
-	cipherText := make([]byte, len(text))
-
-	// Use fixed IV for deterministic encryption
-	stream := cipher.NewCFBEncrypter(block, a.iv)
-	stream.XORKeyStream(cipherText, []byte(text))
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.New("failed to generate nonce: " + err.Error())
+	}
 
-	// Encode as Base64 for safe storage
-	return base64.StdEncoding.EncodeToString(cipherText), nil
+	// Encode nonce and sealed text as Base64 for safe storage
+	return KeyVersionPrefix(a.version) + base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(text), nil)), nil
 }
 
-// Decrypt decrypts an AES-256 encrypted string using the fixed IV
+// Decrypt decrypts an AES-256-GCM value with the key version it carries, or a legacy AES-CFB one
+// written with the fixed IV
 func (a *encryption) Decrypt(encryptedText string) (string, error) {
-	cipherText, err := base64.StdEncoding.DecodeString(encryptedText)
+	if a.IsLegacy(encryptedText) {
+		return a.decryptLegacy(encryptedText)
+	}
+
+	version, payload := parseKeyVersion(encryptedText)
+	sealed, err := base64.StdEncoding.DecodeString(payload)
 	if err != nil {
 		return "", errors.New("failed to decode base64 data: " + err.Error())
 	}
 
-	block, err := aes.NewCipher(a.key)
+	gcm, err := a.gcm(version)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("encrypted value is too short")
+	}
+
+	plainText, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt data: " + err.Error())
+	}
+	return string(plainText), nil
+}
+
+// IsLegacy reports whether a value was written by the deterministic AES-CFB scheme
+func (a *encryption) IsLegacy(encryptedText string) bool {
+	return !strings.HasPrefix(encryptedText, EncryptedValuePrefix)
+}
+
+// NeedsReencrypt reports whether a value was not encrypted with AES-GCM under the newest key
+func (a *encryption) NeedsReencrypt(encryptedText string) bool {
+	if a.IsLegacy(encryptedText) {
+		return true
+	}
+	version, _ := parseKeyVersion(encryptedText)
+	return version != a.version
+}
+
+// KeyVersion returns the version of the key new values are encrypted with
+func (a *encryption) KeyVersion() int {
+	return a.version
+}
+
+// parseKeyVersion splits "gcm:v<version>:<payload>". Values written before the keyring carry no
+// version and were encrypted with key version 1.
+func parseKeyVersion(encryptedText string) (int, string) {
+	payload := strings.TrimPrefix(encryptedText, EncryptedValuePrefix)
+	if tag, rest, found := strings.Cut(payload, ":"); found && strings.HasPrefix(tag, "v") {
+		if version, err := strconv.Atoi(tag[1:]); err == nil {
+			return version, rest
+		}
+	}
+	return 1, payload
+}
+
+func (a *encryption) key(version int) ([]byte, error) {
+	key, ok := a.keys[version]
+	if !ok {
+		return nil, fmt.Errorf("encryption key version %d is not configured", version)
+	}
+	return key, nil
+}
+
+func (a *encryption) gcm(version int) (cipher.AEAD, error) {
+	key, err := a.key(version)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.New("failed to create AES cipher: " + err.Error())
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.New("failed to create AES-GCM: " + err.Error())
+	}
+	return gcm, nil
+}
+
+// decryptLegacy decrypts an AES-256-CFB value encrypted under key version 1 with the fixed IV. CFB
+// cannot detect a wrong key or a value that was never encrypted, so anything that is not base64 is
+// ErrNotCiphertext and anything that does not decrypt to text is an error, never a guess.
+func (a *encryption) decryptLegacy(encryptedText string) (string, error) {
+	cipherText, err := base64.StdEncoding.DecodeString(encryptedText)
+	if err != nil {
+		return "", ErrNotCiphertext
+	}
+
+	key, err := a.key(1)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", errors.New("failed to create AES cipher: " + err.Error())
 	}
@@ -76,28 +197,50 @@ func (a *encryption) Decrypt(encryptedText string) (string, error) {
 	stream := cipher.NewCFBDecrypter(block, a.iv)
 	stream.XORKeyStream(cipherText, cipherText)
 
-	return string(cipherText), nil
+	plainText := string(cipherText)
+	if !utf8.ValidString(plainText) || strings.IndexFunc(plainText, unicode.IsControl) >= 0 {
+		return "", errors.New("legacy value does not decrypt to text")
+	}
+	return plainText, nil
 }
 
-// HashPhoneNumber hashes a phone number using SHA-256 for deterministic lookups
+// HashPhoneNumber returns the blind index of a phone number: a keyed HMAC-SHA256 that allows
+// lookups on the encrypted column without revealing the number
 func (a *encryption) HashPhoneNumber(phone string) string {
-	hash := sha256.Sum256([]byte(phone))
-	return hex.EncodeToString(hash[:])
+	return a.blindIndex("phone_number", strings.TrimSpace(phone))
+}
+
+// HashEmail returns the blind index of an email address. Addresses are compared case-insensitively,
+// so the index is taken of the trimmed lower-case address.
+func (a *encryption) HashEmail(email string) string {
+	return a.blindIndex("email", strings.ToLower(strings.TrimSpace(email)))
 }
 
-// HashPassword securely hashes a password using bcrypt
+// blindIndex hashes a value with the blind index key, scoped to its column so equal values in
+// different columns do not share an index
+func (a *encryption) blindIndex(column, value string) string {
+	mac := hmac.New(sha256.New, a.blindIndexKey)
+	mac.Write([]byte(column + ":" + value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// HashPassword securely hashes a password with the configured password hash target
 func (a *encryption) HashPassword(password string) (*string, error) {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashed, err := HashPassword(password)
 	if err != nil {
 		return nil, errors.New("failed to hash password: " + err.Error())
 	}
-	hashedStr := string(hashed)
-	return &hashedStr, nil
+	return &hashed, nil
 }
 
-// CheckPassword verifies a bcrypt hashed password
+// CheckPassword verifies an argon2id or bcrypt hashed password
 func (a *encryption) CheckPassword(hash, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	return CheckPassword(hash, password)
+}
+
+// NeedsRehash reports whether a password hash is weaker than the configured password hash target
+func (a *encryption) NeedsRehash(hash string) bool {
+	return NeedsRehash(hash)
 }
 
 func ValidatePhoneNumber(phone string) error {
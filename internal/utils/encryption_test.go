@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const (
+	testKeyV1 = "first-encryption-key"
+	testKeyV2 = "second-encryption-key"
+	testIV    = "legacy-iv"
+)
+
+// encryptLegacy reproduces the AES-256-CFB scheme with a fixed IV used before AES-GCM
+func encryptLegacy(t *testing.T, key, iv, text string) string {
+	t.Helper()
+	hashedKey := sha256.Sum256([]byte(key))
+	hashedIV := sha256.Sum256([]byte(iv))
+
+	block, err := aes.NewCipher(hashedKey[:])
+	if err != nil {
+		t.Fatalf("aes.NewCipher() error = %v", err)
+	}
+	cipherText := make([]byte, len(text))
+	cipher.NewCFBEncrypter(block, hashedIV[:aes.BlockSize]).XORKeyStream(cipherText, []byte(text))
+	return base64.StdEncoding.EncodeToString(cipherText)
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	enc := NewEncryption(map[int]string{1: testKeyV1, 2: testKeyV2}, testIV, "blind-index-key")
+
+	encrypted, err := enc.Encrypt("user@example.com")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if !strings.HasPrefix(encrypted, KeyVersionPrefix(2)) {
+		t.Errorf("Encrypt() = %q, want it under the newest key version", encrypted)
+	}
+
+	again, err := enc.Encrypt("user@example.com")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if again == encrypted {
+		t.Error("Encrypt() gave the same ciphertext twice, the nonce must be random")
+	}
+
+	decrypted, err := enc.Decrypt(encrypted)
+	if err != nil || decrypted != "user@example.com" {
+		t.Errorf("Decrypt() = (%q, %v), want the original value", decrypted, err)
+	}
+}
+
+func TestEncryptionDecrypt(t *testing.T) {
+	oldKeyring := NewEncryption(map[int]string{1: testKeyV1}, testIV, "blind-index-key")
+	keyring := NewEncryption(map[int]string{1: testKeyV1, 2: testKeyV2}, testIV, "blind-index-key")
+	rotated := NewEncryption(map[int]string{2: testKeyV2}, testIV, "blind-index-key")
+
+	underV1, err := oldKeyring.Encrypt("+6281234567890")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	underV2, err := keyring.Encrypt("+6281234567890")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	// values written before the keyring carry no version and were encrypted with version 1
+	unversioned := EncryptedValuePrefix + strings.TrimPrefix(underV1, KeyVersionPrefix(1))
+
+	sealed, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(underV2, KeyVersionPrefix(2)))
+	sealed[len(sealed)-1] ^= 0xff
+	tampered := KeyVersionPrefix(2) + base64.StdEncoding.EncodeToString(sealed)
+
+	tests := []struct {
+		name    string
+		enc     Encryption
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"older key version", keyring, underV1, "+6281234567890", false},
+		{"newest key version", keyring, underV2, "+6281234567890", false},
+		{"unversioned AES-GCM", keyring, unversioned, "+6281234567890", false},
+		{"legacy AES-CFB", keyring, encryptLegacy(t, testKeyV1, testIV, "+6281234567890"), "+6281234567890", false},
+		{"key version not configured", rotated, underV1, "", true},
+		{"legacy without key version 1", rotated, encryptLegacy(t, testKeyV1, testIV, "+6281234567890"), "", true},
+		{"tampered ciphertext", keyring, tampered, "", true},
+		{"truncated ciphertext", keyring, KeyVersionPrefix(2) + "AAAA", "", true},
+		{"not base64", keyring, KeyVersionPrefix(2) + "!!!", "", true},
+		{"plaintext email is not legacy base64", keyring, "user@example.com", "", true},
+		{"legacy under another key", keyring, encryptLegacy(t, "another-key", testIV, "+6281234567890"), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.enc.Decrypt(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decrypt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Decrypt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncryptionDecryptNotCiphertext(t *testing.T) {
+	keyring := NewEncryption(map[int]string{1: testKeyV1}, testIV, "blind-index-key")
+
+	if _, err := keyring.Decrypt("user@example.com"); !errors.Is(err, ErrNotCiphertext) {
+		t.Errorf("Decrypt() of a plaintext email error = %v, want ErrNotCiphertext", err)
+	}
+	// base64 that does not decrypt to text may still be ciphertext, so it is not taken for plain text
+	if _, err := keyring.Decrypt(encryptLegacy(t, "another-key", testIV, "+6281234567890")); err == nil || errors.Is(err, ErrNotCiphertext) {
+		t.Errorf("Decrypt() of a legacy value under another key error = %v, want a decryption error", err)
+	}
+}
+
+func TestEncryptionNeedsReencrypt(t *testing.T) {
+	oldKeyring := NewEncryption(map[int]string{1: testKeyV1}, testIV, "blind-index-key")
+	keyring := NewEncryption(map[int]string{1: testKeyV1, 2: testKeyV2}, testIV, "blind-index-key")
+
+	underV1, _ := oldKeyring.Encrypt("value")
+	underV2, _ := keyring.Encrypt("value")
+
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{"legacy AES-CFB", encryptLegacy(t, testKeyV1, testIV, "value"), true},
+		{"older key version", underV1, true},
+		{"unversioned AES-GCM", EncryptedValuePrefix + strings.TrimPrefix(underV1, KeyVersionPrefix(1)), true},
+		{"newest key version", underV2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keyring.NeedsReencrypt(tt.value); got != tt.want {
+				t.Errorf("NeedsReencrypt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncryptionHashPhoneNumber(t *testing.T) {
+	enc := NewEncryption(map[int]string{1: testKeyV1}, testIV, "blind-index-key")
+	rotated := NewEncryption(map[int]string{1: testKeyV1, 2: testKeyV2}, testIV, "blind-index-key")
+	otherKey := NewEncryption(map[int]string{1: testKeyV1}, testIV, "another-blind-index-key")
+
+	if enc.HashPhoneNumber(" +6281234567890 ") != enc.HashPhoneNumber("+6281234567890") {
+		t.Error("HashPhoneNumber() must ignore surrounding spaces")
+	}
+	if enc.HashPhoneNumber("+6281234567890") != rotated.HashPhoneNumber("+6281234567890") {
+		t.Error("HashPhoneNumber() must not change when the AES key is rotated")
+	}
+	if enc.HashPhoneNumber("+6281234567890") == otherKey.HashPhoneNumber("+6281234567890") {
+		t.Error("HashPhoneNumber() must depend on the blind index key")
+	}
+}
+
+func TestEncryptionHashEmail(t *testing.T) {
+	enc := NewEncryption(map[int]string{1: testKeyV1}, testIV, "blind-index-key")
+	otherKey := NewEncryption(map[int]string{1: testKeyV1}, testIV, "another-blind-index-key")
+
+	tests := []struct {
+		name  string
+		a, b  string
+		equal bool
+	}{
+		{"same address", "user@example.com", "user@example.com", true},
+		{"different case", "User@Example.COM", "user@example.com", true},
+		{"surrounding spaces", " user@example.com ", "user@example.com", true},
+		{"different address", "user@example.com", "other@example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := enc.HashEmail(tt.a) == enc.HashEmail(tt.b); got != tt.equal {
+				t.Errorf("HashEmail(%q) == HashEmail(%q) is %v, want %v", tt.a, tt.b, got, tt.equal)
+			}
+		})
+	}
+
+	if enc.HashEmail("user@example.com") == otherKey.HashEmail("user@example.com") {
+		t.Error("HashEmail() must depend on the blind index key")
+	}
+	if enc.HashEmail("0812345678") == enc.HashPhoneNumber("0812345678") {
+		t.Error("blind indexes of different columns must differ for the same value")
+	}
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// Token exchange identifiers (RFC 8693 section 3); users are named by their user ID
+const (
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	TokenTypeAccessToken   = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeUserID        = "urn:authentication:params:oauth:token-type:user_id"
+)
+
+const (
+	RoleSuperAdmin = "Super Admin"
+
+	ImpersonationActionTokenExchange = "token_exchange"
+	ImpersonationActionWrite         = "write"
+
+	SessionStatusImpersonation = "impersonation"
+)
+
+// Actor is the RFC 8693 act claim naming the admin acting as the token's user
+type Actor struct {
+	Sub    string `json:"sub"`
+	UserID uint   `json:"user_id"`
+}
+
+// ExtractActor returns the admin behind an impersonated request
+func ExtractActor(c *gin.Context) (*Actor, bool) {
+	tokenClaims, exist := ExtractTokenClaims(c)
+	if !exist || tokenClaims.Actor == nil {
+		return nil, false
+	}
+	return tokenClaims.Actor, true
+}
+
+// IsWriteMethod reports whether a request with this method can change state
+func IsWriteMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
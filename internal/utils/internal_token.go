@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	InternalTokenSubject = "internal-communication"
+
+	InternalTokenStatusActive  = "active"
+	InternalTokenStatusExpired = "expired"
+	InternalTokenStatusRevoked = "revoked"
+)
+
+var (
+	ErrInternalTokenInvalid = errors.New("internal token is invalid")
+	ErrInternalTokenRevoked = errors.New("internal token has been revoked or has expired")
+)
+
+// ExtractInternalClaims extracts the claims of the service calling an internal route
+func ExtractInternalClaims(c *gin.Context) (*InternalClaims, bool) {
+	claimsData, exists := c.Get(InternalToken)
+	if !exists {
+		return nil, false
+	}
+
+	claims, ok := claimsData.(*InternalClaims)
+	if !ok || claims == nil {
+		return nil, false
+	}
+
+	return claims, true
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"authentication/internal/dto/out"
 	"authentication/internal/models"
 	"errors"
 	"fmt"
@@ -13,24 +14,110 @@ import (
 
 type JWTService interface {
 	GenerateToken(user models.Users, resourceName []string, roleName string) (models.TokenDetails, error)
+	GenerateImpersonationToken(user models.Users, resourceName []string, roleName string, actor Actor, ttl time.Duration) (models.TokenDetails, error)
+	GenerateDelegatedToken(user models.Users, resourceName []string, roleName, authorizedParty, scope string) (models.TokenDetails, error)
 	ValidateToken(tokenString string) (*jwt.MapClaims, error)
 	ValidateTokenAdmin(tokenString string) (*jwt.MapClaims, error)
 	ExtractClaims(tokenString string) (*TokenClaims, error)
-	GenerateInternalToken(serviceName string) (string, error)
+	GenerateInternalToken(serviceName, tokenID string, audience []string, expiresAt time.Time) (string, error)
+	GenerateServiceToken(clientID, serviceName, scope string, audience []string, ttl time.Duration) (string, int64, error)
 	ValidateInternalToken(tokenString string) (*InternalClaims, error)
+	ValidateServiceToken(tokenString string) (*InternalClaims, error)
+	PublicKeys() []out.JSONWebKey
+	Algorithm() string
+	ReloadKeys() error
 }
 
 type jwtService struct {
 	SecretKey         []byte
 	InternalSecretKey []byte
+	Keyring           *keyring
+	Issuer            string
 }
 
-// NewJWTService initializes the JWT service
-func NewJWTService(jwtSecret string) JWTService {
-	return jwtService{
+// NewJWTService initializes the JWT service. A nil keyStore keeps the legacy HS256 mode.
+func NewJWTService(jwtSecret string, keyStore KeyStore, issuer string) JWTService {
+	service := jwtService{
 		SecretKey:         []byte(jwtSecret),
 		InternalSecretKey: []byte(jwtSecret),
+		Issuer:            issuer,
 	}
+	if keyStore != nil {
+		service.Keyring = newKeyring(keyStore)
+		_ = service.Keyring.reload()
+	}
+	return service
+}
+
+// ReloadKeys refreshes the keyring from its store, e.g. right after a rotation
+func (j jwtService) ReloadKeys() error {
+	if j.Keyring == nil {
+		return nil
+	}
+	return j.Keyring.reload()
+}
+
+// sign signs the claims with the current active key, or with the shared secret in HS256 mode
+func (j jwtService) sign(claims jwt.Claims, secret []byte) (string, error) {
+	if j.Keyring == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	}
+
+	key := j.Keyring.signingKey()
+	if key == nil {
+		return "", errors.New("no active signing key")
+	}
+
+	token := jwt.NewWithClaims(key.Method(), claims)
+	token.Header["kid"] = key.KeyID
+	return token.SignedString(key.PrivateKey)
+}
+
+// keyFunc resolves the verification key by kid; any pending or active key is accepted
+func (j jwtService) keyFunc(secret []byte) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		if j.Keyring == nil {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return secret, nil
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		key, ok := j.Keyring.verificationKey(kid)
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+		if token.Method.Alg() != key.Method().Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.PublicKey, nil
+	}
+}
+
+// PublicKeys returns the keys published in the JWKS document; empty in HS256 mode
+func (j jwtService) PublicKeys() []out.JSONWebKey {
+	if j.Keyring == nil {
+		return []out.JSONWebKey{}
+	}
+
+	keys := j.Keyring.publicKeys()
+	jwks := make([]out.JSONWebKey, 0, len(keys))
+	for _, key := range keys {
+		jwks = append(jwks, key.JWK())
+	}
+	return jwks
+}
+
+// Algorithm returns the JWS algorithm used to sign tokens
+func (j jwtService) Algorithm() string {
+	if j.Keyring == nil {
+		return AlgorithmHS256
+	}
+	if key := j.Keyring.signingKey(); key != nil {
+		return key.Algorithm
+	}
+	return ""
 }
 
 // GenerateToken generates a new JWT token
@@ -38,9 +125,9 @@ func (j jwtService) GenerateToken(user models.Users, resourceName []string, role
 	var clientID string
 	clientID = GenerateClientID()
 	td := &models.TokenDetails{
-		AtExpires:   time.Now().Add(time.Hour * 24).Unix(),
+		AtExpires:   time.Now().Add(time.Hour * AccessTokenExpiry).Unix(),
 		AccessUUID:  uuid.New().String(),
-		RtExpires:   time.Now().Add(time.Hour * 24 * 7).Unix(),
+		RtExpires:   time.Now().Add(time.Hour * RefreshTokenExpiry).Unix(),
 		RefreshUUID: clientID,
 	}
 
@@ -52,12 +139,13 @@ func (j jwtService) GenerateToken(user models.Users, resourceName []string, role
 		"role_id":     user.RoleID,
 		"resource":    resourceName,
 		"role":        roleName,
+		"iss":         j.Issuer,
+		"iat":         time.Now().Unix(),
 		"exp":         td.AtExpires,
 	}
 
-	at := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	var err error
-	td.AccessToken, err = at.SignedString(j.SecretKey)
+	td.AccessToken, err = j.sign(claims, j.SecretKey)
 	if err != nil {
 		return models.TokenDetails{}, err
 	}
@@ -67,14 +155,77 @@ func (j jwtService) GenerateToken(user models.Users, resourceName []string, role
 	return *td, nil
 }
 
+// GenerateImpersonationToken generates a short-lived access token for user on behalf of actor. It carries
+// an act claim and no refresh token.
+func (j jwtService) GenerateImpersonationToken(user models.Users, resourceName []string, roleName string, actor Actor, ttl time.Duration) (models.TokenDetails, error) {
+	td := &models.TokenDetails{
+		AtExpires:  time.Now().Add(ttl).Unix(),
+		AccessUUID: uuid.New().String(),
+	}
+
+	claims := jwt.MapClaims{
+		"authorized":  true,
+		"access_uuid": td.AccessUUID,
+		"user_id":     user.UserID,
+		"client_id":   user.ClientID,
+		"role_id":     user.RoleID,
+		"resource":    resourceName,
+		"role":        roleName,
+		"act":         actor,
+		"iss":         j.Issuer,
+		"iat":         time.Now().Unix(),
+		"exp":         td.AtExpires,
+	}
+
+	var err error
+	td.AccessToken, err = j.sign(claims, j.SecretKey)
+	if err != nil {
+		return models.TokenDetails{}, err
+	}
+
+	return *td, nil
+}
+
+// GenerateDelegatedToken generates a token the user granted to an OAuth client. resourceName must already
+// be limited to the granted scope; the azp claim names the client and keeps the token out of admin routes.
+func (j jwtService) GenerateDelegatedToken(user models.Users, resourceName []string, roleName, authorizedParty, scope string) (models.TokenDetails, error) {
+	refreshToken := GenerateClientID()
+	td := &models.TokenDetails{
+		AtExpires:   time.Now().Add(time.Hour * AccessTokenExpiry).Unix(),
+		AccessUUID:  uuid.New().String(),
+		RtExpires:   time.Now().Add(time.Hour * RefreshTokenExpiry).Unix(),
+		RefreshUUID: refreshToken,
+	}
+
+	claims := jwt.MapClaims{
+		"authorized":  true,
+		"access_uuid": td.AccessUUID,
+		"user_id":     user.UserID,
+		"client_id":   user.ClientID,
+		"role_id":     user.RoleID,
+		"resource":    resourceName,
+		"role":        roleName,
+		"azp":         authorizedParty,
+		"scope":       scope,
+		"iss":         j.Issuer,
+		"iat":         time.Now().Unix(),
+		"exp":         td.AtExpires,
+	}
+
+	var err error
+	td.AccessToken, err = j.sign(claims, j.SecretKey)
+	if err != nil {
+		return models.TokenDetails{}, err
+	}
+
+	td.RefreshToken = refreshToken
+
+	return *td, nil
+}
+
 // ValidateToken validates a JWT token and extracts claims
 func (j jwtService) ValidateToken(tokenString string) (*jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return j.SecretKey, nil
-	})
+	token, err := jwt.Parse(tokenString, j.keyFunc(j.SecretKey))
 
 	if err != nil {
 		return nil, err
@@ -101,6 +252,16 @@ func (j jwtService) ValidateTokenAdmin(tokenString string) (*jwt.MapClaims, erro
 		return nil, err
 	}
 
+	// an impersonated token never carries admin rights, whatever its role claim says
+	if _, ok := (*claims)["act"]; ok {
+		return nil, errors.New("impersonated tokens cannot be used for admin access")
+	}
+
+	// neither is a token the user delegated to an OAuth client
+	if _, ok := (*claims)["azp"]; ok {
+		return nil, errors.New("tokens issued to OAuth clients cannot be used for admin access")
+	}
+
 	if role, ok := (*claims)["role"].(string); ok {
 		if strings.EqualFold(role, "Admin") || strings.EqualFold(role, "Super Admin") {
 			return claims, nil
@@ -132,6 +293,10 @@ func (j jwtService) ExtractClaims(tokenString string) (*TokenClaims, error) {
 		tc.Exp = int64(exp)
 	}
 
+	if iat, ok := (*claims)["iat"].(float64); ok {
+		tc.IssuedAt = int64(iat)
+	}
+
 	if userID, ok := (*claims)["user_id"].(float64); ok {
 		tc.UserID = uint(userID)
 	}
@@ -150,34 +315,97 @@ func (j jwtService) ExtractClaims(tokenString string) (*TokenClaims, error) {
 		}
 	}
 
+	if azp, ok := (*claims)["azp"].(string); ok {
+		tc.AuthorizedParty = azp
+	}
+
+	if scope, ok := (*claims)["scope"].(string); ok {
+		tc.Scope = scope
+	}
+
+	if act, ok := (*claims)["act"].(map[string]interface{}); ok {
+		tc.Actor = &Actor{}
+		if sub, ok := act["sub"].(string); ok {
+			tc.Actor.Sub = sub
+		}
+		if userID, ok := act["user_id"].(float64); ok {
+			tc.Actor.UserID = uint(userID)
+		}
+	}
+
 	return tc, nil
 }
 
-// GenerateInternalToken creates an internal JWT for service-to-service communication
-func (j jwtService) GenerateInternalToken(serviceName string) (string, error) {
+// GenerateInternalToken creates an internal JWT for service-to-service communication. The token ID
+// ties the token to its stored record, so it can be revoked before it expires.
+func (j jwtService) GenerateInternalToken(serviceName, tokenID string, audience []string, expiresAt time.Time) (string, error) {
+	claims := InternalClaims{
+		Service: serviceName,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Issuer:    j.Issuer,
+			Subject:   InternalTokenSubject,
+			Audience:  audience,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return j.sign(claims, j.InternalSecretKey)
+}
+
+// GenerateServiceToken creates a token for a service account. The subject is the account's client ID
+// and the scope lists the resources the token may be used for.
+func (j jwtService) GenerateServiceToken(clientID, serviceName, scope string, audience []string, ttl time.Duration) (string, int64, error) {
+	expiresAt := time.Now().Add(ttl)
 	claims := InternalClaims{
 		Service: serviceName,
+		Scope:   scope,
 		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    "auth-service",
-			Subject:   "internal-communication",
-			Audience:  []string{strings.ToLower(serviceName) + "-service"},
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			ID:        uuid.New().String(),
+			Issuer:    j.Issuer,
+			Subject:   clientID,
+			Audience:  audience,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.InternalSecretKey)
+	token, err := j.sign(claims, j.InternalSecretKey)
+	if err != nil {
+		return "", 0, err
+	}
+	return token, expiresAt.Unix(), nil
 }
 
 // ValidateInternalToken verifies an internal JWT token
 func (j jwtService) ValidateInternalToken(tokenString string) (*InternalClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &InternalClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, jwt.ErrSignatureInvalid
-		}
-		return j.InternalSecretKey, nil
-	})
+	claims, err := j.parseInternalClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Subject != InternalTokenSubject || claims.ID == "" {
+		return nil, ErrInternalTokenInvalid
+	}
+	return claims, nil
+}
+
+// ValidateServiceToken verifies a service account JWT token
+func (j jwtService) ValidateServiceToken(tokenString string) (*InternalClaims, error) {
+	claims, err := j.parseInternalClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsServiceAccountClientID(claims.Subject) || claims.ID == "" {
+		return nil, ErrInternalTokenInvalid
+	}
+	return claims, nil
+}
+
+func (j jwtService) parseInternalClaims(tokenString string) (*InternalClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &InternalClaims{}, j.keyFunc(j.InternalSecretKey))
 
 	if err != nil {
 		return nil, err
@@ -192,18 +420,24 @@ func (j jwtService) ValidateInternalToken(tokenString string) (*InternalClaims,
 
 // TokenClaims represents the claims extracted from a JWT token
 type TokenClaims struct {
-	Authorized bool     `json:"authorized"`
-	AccessUUID string   `json:"access_uuid"`
-	UserID     uint     `json:"user_id"`
-	ClientID   string   `json:"client_id"`
-	RoleID     uint     `json:"role_id"`
-	Resource   []string `json:"resource"`
-	Exp        int64    `json:"exp"`
+	Authorized      bool     `json:"authorized"`
+	AccessUUID      string   `json:"access_uuid"`
+	UserID          uint     `json:"user_id"`
+	ClientID        string   `json:"client_id"`
+	RoleID          uint     `json:"role_id"`
+	Resource        []string `json:"resource"`
+	Exp             int64    `json:"exp,omitempty"`
+	IssuedAt        int64    `json:"iat"`
+	ApiKeyID        uint     `json:"api_key_id,omitempty"`
+	Actor           *Actor   `json:"act,omitempty"`
+	AuthorizedParty string   `json:"azp,omitempty"`
+	Scope           string   `json:"scope,omitempty"`
 }
 
 // InternalClaims represents the claims used for service-to-service authentication
 type InternalClaims struct {
 	Service string `json:"service"`
+	Scope   string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
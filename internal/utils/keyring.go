@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	keyringRefreshInterval = 5 * time.Minute
+	keyringMissReloadDelay = 30 * time.Second
+)
+
+// keyring caches the non-retired signing keys loaded from a KeyStore
+type keyring struct {
+	mu        sync.RWMutex
+	store     KeyStore
+	keys      map[string]*SigningKey
+	signing   *SigningKey
+	checkedAt time.Time
+}
+
+func newKeyring(store KeyStore) *keyring {
+	return &keyring{store: store, keys: map[string]*SigningKey{}}
+}
+
+// reload replaces the cached keys; the most recently activated active key becomes the signing key
+func (k *keyring) reload() error {
+	keys, err := k.store.LoadSigningKeys()
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]*SigningKey, len(keys))
+	var signing *SigningKey
+	for _, key := range keys {
+		byID[key.KeyID] = key
+		if key.Status == SigningKeyActive && (signing == nil || key.ActivatedAt.After(signing.ActivatedAt)) {
+			signing = key
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = byID
+	k.signing = signing
+	k.checkedAt = time.Now()
+	return nil
+}
+
+// refreshIfOlder reloads when the cache is older than maxAge, so every instance picks up rotations.
+// The check time is claimed up front so concurrent callers and a failing store do not cause a reload storm.
+func (k *keyring) refreshIfOlder(maxAge time.Duration) {
+	k.mu.Lock()
+	stale := time.Since(k.checkedAt) > maxAge
+	if stale {
+		k.checkedAt = time.Now()
+	}
+	k.mu.Unlock()
+
+	if stale {
+		_ = k.reload()
+	}
+}
+
+func (k *keyring) signingKey() *SigningKey {
+	k.refreshIfOlder(keyringRefreshInterval)
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.signing
+}
+
+// verificationKey looks a key up by kid, reloading once if another instance may have rotated
+func (k *keyring) verificationKey(kid string) (*SigningKey, bool) {
+	k.refreshIfOlder(keyringRefreshInterval)
+
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	k.mu.RUnlock()
+	if ok {
+		return key, true
+	}
+
+	k.refreshIfOlder(keyringMissReloadDelay)
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok = k.keys[kid]
+	return key, ok
+}
+
+func (k *keyring) publicKeys() []*SigningKey {
+	k.refreshIfOlder(keyringRefreshInterval)
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	keys := make([]*SigningKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"context"
+	"github.com/rs/zerolog"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// log is the application logger. Every entry goes through the redacting writer, so sensitive
+// fields never reach the output whichever call site produced them.
+var log = New(os.Stdout, zerolog.InfoLevel)
+
+// New returns a JSON logger writing to out with sensitive values redacted
+func New(out io.Writer, level zerolog.Level) zerolog.Logger {
+	zerolog.TimeFieldFormat = time.RFC3339Nano
+	return zerolog.New(NewRedactingWriter(out)).Level(level).With().Timestamp().Logger()
+}
+
+// Init sets the level of the application logger, it is called once at startup
+func Init(level string) error {
+	parsed, err := zerolog.ParseLevel(strings.ToLower(strings.TrimSpace(level)))
+	if err != nil {
+		return err
+	}
+	log = log.Level(parsed)
+	return nil
+}
+
+// With starts a child logger with extra fields
+func With() zerolog.Context {
+	return log.With()
+}
+
+// Ctx returns the logger attached to a request context, or the application logger when there is none
+func Ctx(ctx context.Context) *zerolog.Logger {
+	if l := zerolog.Ctx(ctx); l.GetLevel() != zerolog.Disabled {
+		return l
+	}
+	return &log
+}
+
+func Debug() *zerolog.Event {
+	return log.Debug()
+}
+
+func Info() *zerolog.Event {
+	return log.Info()
+}
+
+func Warn() *zerolog.Event {
+	return log.Warn()
+}
+
+func Error() *zerolog.Event {
+	return log.Error()
+}
+
+// Fatal logs and exits the process
+func Fatal() *zerolog.Event {
+	return log.Fatal()
+}
@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/rs/zerolog"
+	"io"
+	"regexp"
+	"strings"
+)
+
+const (
+	// Redacted replaces the value of every sensitive field
+	Redacted = "[REDACTED]"
+	// RequestIDField is the field holding the ID of the request an entry was logged for
+	RequestIDField = "request_id"
+)
+
+// sensitiveFields are field names whose values are always redacted, at any depth
+var sensitiveFields = map[string]bool{
+	"password":      true,
+	"pin":           true,
+	"pin_code":      true,
+	"phone":         true,
+	"phone_number":  true,
+	"email":         true,
+	"token":         true,
+	"secret":        true,
+	"otp":           true,
+	"device_id":     true,
+	"device_token":  true,
+	"api_key":       true,
+	"authorization": true,
+	"cookie":        true,
+}
+
+// sensitiveSuffixes catch the variants of the fields above, such as new_password or refresh_token
+var sensitiveSuffixes = []string{"_password", "_pin", "_token", "_secret", "_email"}
+
+// plainFields are written by the logger itself and never hold sensitive values
+var plainFields = map[string]bool{
+	zerolog.TimestampFieldName: true,
+	zerolog.LevelFieldName:     true,
+	RequestIDField:             true,
+}
+
+// sensitivePatterns scrub values that end up inside free text, such as messages and errors
+var sensitivePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	regexp.MustCompile(`(?:\+62|\b62|\b0)8\d{7,11}\b`),
+	regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9._~+/-]+=*`),
+	regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+}
+
+// redactingWriter rewrites every JSON log entry with its sensitive values redacted before
+// passing it on
+type redactingWriter struct {
+	out io.Writer
+}
+
+// NewRedactingWriter wraps out so that sensitive fields and values never reach it
+func NewRedactingWriter(out io.Writer) io.Writer {
+	return redactingWriter{out: out}
+}
+
+func (w redactingWriter) Write(p []byte) (int, error) {
+	decoder := json.NewDecoder(bytes.NewReader(p))
+	decoder.UseNumber()
+
+	var entry map[string]interface{}
+	if err := decoder.Decode(&entry); err != nil {
+		// not an entry of ours, scrub it as text
+		if _, err := w.out.Write([]byte(RedactString(string(p)))); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	redacted, err := json.Marshal(redactValue(entry))
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.out.Write(append(redacted, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// IsSensitive reports whether the value of a field must be redacted
+func IsSensitive(field string) bool {
+	field = strings.ToLower(strings.ReplaceAll(field, "-", "_"))
+	if sensitiveFields[field] {
+		return true
+	}
+	for _, suffix := range sensitiveSuffixes {
+		if strings.HasSuffix(field, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactString scrubs email addresses, phone numbers and tokens out of free text
+func RedactString(value string) string {
+	for _, pattern := range sensitivePatterns {
+		value = pattern.ReplaceAllString(value, Redacted)
+	}
+	return value
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if IsSensitive(key) {
+				v[key] = Redacted
+			} else if !plainFields[key] {
+				v[key] = redactValue(field)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+		return v
+	case string:
+		return RedactString(v)
+	default:
+		return v
+	}
+}
@@ -9,6 +9,7 @@ import (
 type Service interface {
 	RequestNotification(subject string, notification models.Notification) error
 	PublishEmail(subject string, email models.Email) error
+	PublishSecurityEvent(subject string, event models.SecurityEvent) error
 }
 
 type natsService struct {
@@ -58,3 +59,22 @@ func (n *natsService) PublishEmail(subject string, email models.Email) error {
 
 	return nil
 }
+
+func (n *natsService) PublishSecurityEvent(subject string, event models.SecurityEvent) error {
+	conn, err := nats.Connect(n.nats)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if err := conn.Publish(subject, data); err != nil {
+		return err
+	}
+
+	return nil
+}
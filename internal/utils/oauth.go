@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+)
+
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+
+	CodeChallengeMethodS256 = "S256"
+
+	// token_type_hint values accepted at /v1/introspect (RFC 7662 section 2.1)
+	TokenTypeHintAccessToken = "access_token"
+	TokenTypeHintApiKey      = "api_key"
+
+	SessionStatusActive = "active"
+)
+
+// OAuth 2.0 error codes (RFC 6749 section 4.1.2.1 and 5.2)
+const (
+	OAuthErrInvalidRequest          = "invalid_request"
+	OAuthErrInvalidClient           = "invalid_client"
+	OAuthErrInvalidGrant            = "invalid_grant"
+	OAuthErrUnauthorizedClient      = "unauthorized_client"
+	OAuthErrUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthErrUnsupportedResponseType = "unsupported_response_type"
+	OAuthErrInvalidScope            = "invalid_scope"
+	OAuthErrAccessDenied            = "access_denied"
+	OAuthErrServerError             = "server_error"
+)
+
+// OAuthError carries an RFC 6749 error code together with a human-readable description
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func NewOAuthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// GenerateOAuthToken returns a URL-safe random value for authorization codes and client secrets
+func GenerateOAuthToken() (string, error) {
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(randomBytes), nil
+}
+
+// VerifyCodeChallenge checks a PKCE code_verifier against the S256 code_challenge (RFC 7636 section 4.6)
+func VerifyCodeChallenge(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// ResolveScope narrows a space-delimited scope request to the scopes a client is allowed;
+// an empty request grants every allowed scope
+func ResolveScope(requested string, allowed []string) (string, bool) {
+	if strings.TrimSpace(requested) == "" {
+		return strings.Join(allowed, " "), true
+	}
+
+	allowedSet := make(map[string]bool, len(allowed))
+	for _, scope := range allowed {
+		allowedSet[scope] = true
+	}
+
+	var granted []string
+	for _, scope := range strings.Fields(requested) {
+		if !allowedSet[scope] {
+			return "", false
+		}
+		granted = append(granted, scope)
+	}
+	return strings.Join(granted, " "), true
+}
+
+func ContainsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+const oauthLoginTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8" />
+  <meta name="viewport" content="width=device-width, initial-scale=1" />
+  <title>Sign in</title>
+</head>
+<body>
+  <h1>Sign in to continue</h1>
+  {{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+  <form method="post" action="/oauth/login">
+    <input type="hidden" name="return_to" value="{{.ReturnTo}}" />
+    <p><label>Username <input type="text" name="username" value="{{.Username}}" autocomplete="username" required /></label></p>
+    <p><label>Password <input type="password" name="password" autocomplete="current-password" required /></label></p>
+    <p><label>Two-factor code (if enabled) <input type="text" name="code" inputmode="numeric" autocomplete="one-time-code" /></label></p>
+    <p><button type="submit">Sign in</button></p>
+  </form>
+</body>
+</html>
+`
+
+const oauthConsentTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8" />
+  <meta name="viewport" content="width=device-width, initial-scale=1" />
+  <title>Authorize {{.ClientName}}</title>
+</head>
+<body>
+  <h1>{{.ClientName}} wants to access your account</h1>
+  <p>It is asking for access to:</p>
+  <ul>{{range .Scopes}}<li>{{.}}</li>{{else}}<li>no resources</li>{{end}}</ul>
+  <p>You will be sent back to {{.RedirectURI}}</p>
+  <form method="post" action="/oauth/authorize">
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}" />
+    {{range $name, $value := .Params}}<input type="hidden" name="{{$name}}" value="{{$value}}" />
+    {{end}}
+    <button type="submit" name="decision" value="approve">Allow</button>
+    <button type="submit" name="decision" value="deny">Deny</button>
+  </form>
+</body>
+</html>
+`
+
+var (
+	oauthLoginPage   = template.Must(template.New("oauth_login").Parse(oauthLoginTemplate))
+	oauthConsentPage = template.Must(template.New("oauth_consent").Parse(oauthConsentTemplate))
+)
+
+type OauthLoginData struct {
+	ReturnTo string
+	Username string
+	Error    string
+}
+
+type OauthConsentData struct {
+	ClientName  string
+	RedirectURI string
+	Scopes      []string
+	CSRFToken   string
+	Params      map[string]string // the authorization request, posted back with the decision
+}
+
+func RenderOauthLoginPage(c *gin.Context, status int, data OauthLoginData) {
+	renderOauthPage(c, status, oauthLoginPage, data)
+}
+
+func RenderOauthConsentPage(c *gin.Context, data OauthConsentData) {
+	renderOauthPage(c, http.StatusOK, oauthConsentPage, data)
+}
+
+// IsOauthAuthorizeURI reports whether returnTo is a local /oauth/authorize URL, so the login form cannot
+// be used as an open redirect
+func IsOauthAuthorizeURI(returnTo string) bool {
+	parsed, err := url.Parse(returnTo)
+	if err != nil {
+		return false
+	}
+	return parsed.Scheme == "" && parsed.Host == "" && parsed.User == nil && parsed.Path == OauthAuthorizePath
+}
+
+func renderOauthPage(c *gin.Context, status int, tmpl *template.Template, data interface{}) {
+	// the pages ask for credentials and consent, they must never be framed or cached
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Frame-Options", "DENY")
+	// no form-action: the consent form ends in a redirect to the client, which form-action would block
+	c.Header("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+	c.Status(status)
+	if err := tmpl.Execute(c.Writer, data); err != nil {
+		c.String(http.StatusInternalServerError, "Template execute error")
+	}
+}
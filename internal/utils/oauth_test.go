@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	// RFC 7636 appendix B
+	const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	const challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		want      bool
+	}{
+		{"RFC 7636 example", verifier, challenge, true},
+		{"wrong verifier", strings.Replace(verifier, "d", "e", 1), challenge, false},
+		{"padded challenge", verifier, challenge + "=", false},
+		{"plain method value", verifier, verifier, false},
+		{"empty challenge", verifier, "", false},
+		{"verifier too short", verifier[:42], challenge, false},
+		{"verifier too long", strings.Repeat("a", 129), challenge, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyCodeChallenge(tt.verifier, tt.challenge); got != tt.want {
+				t.Errorf("VerifyCodeChallenge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveScope(t *testing.T) {
+	allowed := []string{"auth", "profile", "orders"}
+
+	tests := []struct {
+		name      string
+		requested string
+		want      string
+		wantOK    bool
+	}{
+		{"empty grants everything", "", "auth profile orders", true},
+		{"blank grants everything", "   ", "auth profile orders", true},
+		{"subset", "profile auth", "profile auth", true},
+		{"extra spaces", " profile   orders ", "profile orders", true},
+		{"not allowed", "profile admin", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ResolveScope(tt.requested, allowed)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ResolveScope(%q) = (%q, %v), want (%q, %v)", tt.requested, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
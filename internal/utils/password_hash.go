@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+const (
+	PasswordHashArgon2id = "argon2id"
+	PasswordHashBcrypt   = "bcrypt"
+)
+
+const (
+	argon2idPrefix  = "$argon2id$"
+	argon2SaltSize  = 16
+	argon2KeyLength = 32
+)
+
+var ErrPasswordMismatch = errors.New("password does not match")
+
+// PasswordHashParams is the target every new password and PIN hash is created with. Hashes made
+// with a weaker algorithm or cost are upgraded the next time their owner proves the secret.
+type PasswordHashParams struct {
+	Algorithm     string
+	BcryptCost    int
+	Argon2Time    uint32
+	Argon2Memory  uint32 // KiB
+	Argon2Threads uint8
+}
+
+var passwordHashParams = PasswordHashParams{
+	Algorithm:     PasswordHashArgon2id,
+	BcryptCost:    bcrypt.DefaultCost,
+	Argon2Time:    2,
+	Argon2Memory:  19 * 1024,
+	Argon2Threads: 1,
+}
+
+// SetPasswordHashParams sets the hashing target, it is called once at startup
+func SetPasswordHashParams(params PasswordHashParams) error {
+	switch params.Algorithm {
+	case PasswordHashArgon2id:
+		if params.Argon2Time == 0 || params.Argon2Memory == 0 || params.Argon2Threads == 0 {
+			return errors.New("argon2id time, memory and threads must be positive")
+		}
+	case PasswordHashBcrypt:
+		if params.BcryptCost < bcrypt.MinCost || params.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		return errors.New("password hash algorithm must be argon2id or bcrypt")
+	}
+	passwordHashParams = params
+	return nil
+}
+
+// HashPassword hashes a password or PIN with the configured target. Argon2id hashes use the PHC
+// string format, so every hash carries its algorithm and parameters.
+func HashPassword(password string) (string, error) {
+	if passwordHashParams.Algorithm == PasswordHashBcrypt {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashParams.BcryptCost)
+		return string(hashed), err
+	}
+
+	salt := make([]byte, argon2SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, passwordHashParams.Argon2Time, passwordHashParams.Argon2Memory,
+		passwordHashParams.Argon2Threads, argon2KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		passwordHashParams.Argon2Memory, passwordHashParams.Argon2Time, passwordHashParams.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// CheckPassword verifies a password or PIN against an argon2id or bcrypt hash
+func CheckPassword(hash, password string) error {
+	if !strings.HasPrefix(hash, argon2idPrefix) {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	}
+
+	params, salt, key, err := parseArgon2idHash(hash)
+	if err != nil {
+		return err
+	}
+	candidate := argon2.IDKey([]byte(password), salt, params.Argon2Time, params.Argon2Memory, params.Argon2Threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(candidate, key) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+func CheckPasswordHash(password, hash string) bool {
+	return CheckPassword(hash, password) == nil
+}
+
+// NeedsRehash reports whether a hash is weaker than the configured target. A stronger algorithm
+// is never downgraded.
+func NeedsRehash(hash string) bool {
+	if strings.HasPrefix(hash, argon2idPrefix) {
+		if passwordHashParams.Algorithm != PasswordHashArgon2id {
+			return false
+		}
+		params, _, _, err := parseArgon2idHash(hash)
+		if err != nil {
+			return true
+		}
+		return params.Argon2Memory < passwordHashParams.Argon2Memory ||
+			params.Argon2Time < passwordHashParams.Argon2Time ||
+			params.Argon2Threads < passwordHashParams.Argon2Threads
+	}
+
+	if passwordHashParams.Algorithm == PasswordHashArgon2id {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost < passwordHashParams.BcryptCost
+}
+
+// parseArgon2idHash reads $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<key>
+func parseArgon2idHash(hash string) (PasswordHashParams, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return PasswordHashParams{}, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return PasswordHashParams{}, nil, nil, errors.New("unsupported argon2id version")
+	}
+
+	params := PasswordHashParams{Algorithm: PasswordHashArgon2id}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Argon2Memory, &params.Argon2Time, &params.Argon2Threads); err != nil {
+		return PasswordHashParams{}, nil, nil, errors.New("invalid argon2id parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return PasswordHashParams{}, nil, nil, errors.New("invalid argon2id salt")
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return PasswordHashParams{}, nil, nil, errors.New("invalid argon2id key")
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2Params keeps the tests fast, production uses far more memory
+var testArgon2Params = PasswordHashParams{
+	Algorithm:     PasswordHashArgon2id,
+	Argon2Time:    1,
+	Argon2Memory:  64,
+	Argon2Threads: 1,
+}
+
+func setTestPasswordHashParams(t *testing.T, params PasswordHashParams) {
+	t.Helper()
+	previous := passwordHashParams
+	if err := SetPasswordHashParams(params); err != nil {
+		t.Fatalf("SetPasswordHashParams() error = %v", err)
+	}
+	t.Cleanup(func() { passwordHashParams = previous })
+}
+
+func argon2idHash(memory, time, threads int) string {
+	return fmt.Sprintf("$argon2id$v=19$m=%d,t=%d,p=%d$c29tZXNhbHRzb21lc2FsdA$2KdPGfWhkInKxdXZhTO5h5/lB5uLRK4Ku7yTSK0EKlM", memory, time, threads)
+}
+
+func bcryptHash(t *testing.T, cost int) string {
+	t.Helper()
+	hashed, err := bcrypt.GenerateFromPassword([]byte("secret"), cost)
+	if err != nil {
+		t.Fatalf("bcrypt.GenerateFromPassword() error = %v", err)
+	}
+	return string(hashed)
+}
+
+func TestHashPasswordRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		params PasswordHashParams
+	}{
+		{"argon2id", testArgon2Params},
+		{"bcrypt", PasswordHashParams{Algorithm: PasswordHashBcrypt, BcryptCost: bcrypt.MinCost}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestPasswordHashParams(t, tt.params)
+
+			hashed, err := HashPassword("correct horse")
+			if err != nil {
+				t.Fatalf("HashPassword() error = %v", err)
+			}
+			if err := CheckPassword(hashed, "correct horse"); err != nil {
+				t.Errorf("CheckPassword() with the right password = %v", err)
+			}
+			if err := CheckPassword(hashed, "wrong horse"); err == nil {
+				t.Error("CheckPassword() with a wrong password succeeded")
+			}
+			if NeedsRehash(hashed) {
+				t.Error("NeedsRehash() of a fresh hash = true")
+			}
+		})
+	}
+}
+
+func TestCheckPasswordArgon2idMismatch(t *testing.T) {
+	setTestPasswordHashParams(t, testArgon2Params)
+
+	hashed, err := HashPassword("secret")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	if err := CheckPassword(hashed, "Secret"); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("CheckPassword() = %v, want ErrPasswordMismatch", err)
+	}
+}
+
+func TestParseArgon2idHash(t *testing.T) {
+	tests := []struct {
+		name    string
+		hash    string
+		want    PasswordHashParams
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			hash: argon2idHash(19456, 2, 1),
+			want: PasswordHashParams{Algorithm: PasswordHashArgon2id, Argon2Memory: 19456, Argon2Time: 2, Argon2Threads: 1},
+		},
+		{name: "missing key", hash: "$argon2id$v=19$m=19456,t=2,p=1$c29tZXNhbHQ", wantErr: true},
+		{name: "empty key", hash: "$argon2id$v=19$m=19456,t=2,p=1$c29tZXNhbHQ$", wantErr: true},
+		{name: "old version", hash: "$argon2id$v=16$m=19456,t=2,p=1$c29tZXNhbHQ$AAAA", wantErr: true},
+		{name: "parameters out of order", hash: "$argon2id$v=19$t=2,m=19456,p=1$c29tZXNhbHQ$AAAA", wantErr: true},
+		{name: "invalid salt", hash: "$argon2id$v=19$m=19456,t=2,p=1$!!!$AAAA", wantErr: true},
+		{name: "invalid key", hash: "$argon2id$v=19$m=19456,t=2,p=1$c29tZXNhbHQ$!!!", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, _, _, err := parseArgon2idHash(tt.hash)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseArgon2idHash() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && params != tt.want {
+				t.Errorf("parseArgon2idHash() = %+v, want %+v", params, tt.want)
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	argon2Target := PasswordHashParams{Algorithm: PasswordHashArgon2id, Argon2Memory: 19456, Argon2Time: 2, Argon2Threads: 1}
+	bcryptTarget := PasswordHashParams{Algorithm: PasswordHashBcrypt, BcryptCost: bcrypt.MinCost + 1}
+
+	tests := []struct {
+		name   string
+		target PasswordHashParams
+		hash   string
+		want   bool
+	}{
+		{"argon2id at target", argon2Target, argon2idHash(19456, 2, 1), false},
+		{"argon2id above target", argon2Target, argon2idHash(65536, 3, 2), false},
+		{"argon2id less memory", argon2Target, argon2idHash(8192, 2, 1), true},
+		{"argon2id fewer passes", argon2Target, argon2idHash(19456, 1, 1), true},
+		{"argon2id malformed", argon2Target, "$argon2id$v=19$broken", true},
+		{"bcrypt under argon2id target", argon2Target, bcryptHash(t, bcrypt.MinCost+1), true},
+		{"bcrypt at target cost", bcryptTarget, bcryptHash(t, bcrypt.MinCost+1), false},
+		{"bcrypt below target cost", bcryptTarget, bcryptHash(t, bcrypt.MinCost), true},
+		{"argon2id is not downgraded to bcrypt", bcryptTarget, argon2idHash(8192, 1, 1), false},
+		{"unknown hash under bcrypt target", bcryptTarget, "plaintext", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestPasswordHashParams(t, tt.target)
+			if got := NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetPasswordHashParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  PasswordHashParams
+		wantErr bool
+	}{
+		{"argon2id", testArgon2Params, false},
+		{"argon2id without memory", PasswordHashParams{Algorithm: PasswordHashArgon2id, Argon2Time: 1, Argon2Threads: 1}, true},
+		{"bcrypt", PasswordHashParams{Algorithm: PasswordHashBcrypt, BcryptCost: bcrypt.DefaultCost}, false},
+		{"bcrypt cost too low", PasswordHashParams{Algorithm: PasswordHashBcrypt, BcryptCost: bcrypt.MinCost - 1}, true},
+		{"unknown algorithm", PasswordHashParams{Algorithm: "scrypt"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := passwordHashParams
+			t.Cleanup(func() { passwordHashParams = previous })
+
+			if err := SetPasswordHashParams(tt.params); (err != nil) != tt.wantErr {
+				t.Errorf("SetPasswordHashParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
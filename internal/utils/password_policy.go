@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const DefaultPasswordPolicy = "default"
+
+// FieldError reports one rule a request field broke
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError lists every rule a password broke
+type PasswordPolicyError struct {
+	Errors []FieldError
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		messages = append(messages, fieldErr.Message)
+	}
+	return "password does not meet the policy: " + strings.Join(messages, "; ")
+}
+
+// PasswordSubject is what a password must not resemble
+type PasswordSubject struct {
+	Username string
+	Email    string
+}
+
+// PasswordRule is a single check of a password policy. It returns nil when the password passes,
+// otherwise the violation without its field.
+type PasswordRule interface {
+	Check(password string, subject PasswordSubject) *FieldError
+}
+
+// PasswordPolicy is the set of rules a password must pass
+type PasswordPolicy struct {
+	Rules []PasswordRule
+}
+
+// Validate runs every rule so the caller gets all violations at once
+func (p PasswordPolicy) Validate(field, password string, subject PasswordSubject) error {
+	var violations []FieldError
+	for _, rule := range p.Rules {
+		if violation := rule.Check(password, subject); violation != nil {
+			violation.Field = field
+			violations = append(violations, *violation)
+		}
+	}
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Errors: violations}
+	}
+	return nil
+}
+
+// MinLengthRule rejects passwords shorter than Length characters
+type MinLengthRule struct {
+	Length int
+}
+
+func (r MinLengthRule) Check(password string, _ PasswordSubject) *FieldError {
+	if utf8.RuneCountInString(password) < r.Length {
+		return &FieldError{Code: "min_length", Message: fmt.Sprintf("must be at least %d characters", r.Length)}
+	}
+	return nil
+}
+
+// CharacterClassRule requires at least one character of a class: lower, upper, digit or symbol
+type CharacterClassRule struct {
+	Class string
+}
+
+var characterClasses = map[string]struct {
+	matches     func(rune) bool
+	description string
+}{
+	"lower":  {unicode.IsLower, "a lowercase letter"},
+	"upper":  {unicode.IsUpper, "an uppercase letter"},
+	"digit":  {unicode.IsDigit, "a digit"},
+	"symbol": {func(c rune) bool { return unicode.IsPunct(c) || unicode.IsSymbol(c) }, "a symbol"},
+}
+
+func (r CharacterClassRule) Check(password string, _ PasswordSubject) *FieldError {
+	class := characterClasses[r.Class]
+	for _, c := range password {
+		if class.matches(c) {
+			return nil
+		}
+	}
+	return &FieldError{Code: "missing_" + r.Class, Message: "must contain " + class.description}
+}
+
+// SimilarityRule rejects passwords that contain the username or the local part of the email
+type SimilarityRule struct{}
+
+func (r SimilarityRule) Check(password string, subject PasswordSubject) *FieldError {
+	password = strings.ToLower(password)
+
+	username := strings.ToLower(strings.TrimSpace(subject.Username))
+	if len(username) >= 3 && strings.Contains(password, username) {
+		return &FieldError{Code: "similar_to_username", Message: "must not contain the username"}
+	}
+
+	local, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(subject.Email)), "@")
+	if len(local) >= 3 && strings.Contains(password, local) {
+		return &FieldError{Code: "similar_to_email", Message: "must not contain the email address"}
+	}
+	return nil
+}
+
+// BreachedPasswordRule rejects passwords found in a list of breached passwords
+type BreachedPasswordRule struct {
+	Filter *BloomFilter
+}
+
+func (r BreachedPasswordRule) Check(password string, _ PasswordSubject) *FieldError {
+	if r.Filter.Contains(password) || r.Filter.Contains(strings.ToLower(password)) {
+		return &FieldError{Code: "breached", Message: "has appeared in a data breach, choose another one"}
+	}
+	return nil
+}
+
+// ParsePasswordPolicy reads a policy written as "<min length>[/<class>+<class>...]", e.g.
+// "12/lower+upper+digit+symbol". Every policy also rejects passwords resembling the username or
+// email, and breached passwords when a breached list is loaded.
+func ParsePasswordPolicy(value string, breached *BloomFilter) (PasswordPolicy, error) {
+	lengthPart, classPart, _ := strings.Cut(strings.TrimSpace(value), "/")
+
+	length, err := strconv.Atoi(lengthPart)
+	if err != nil || length <= 0 {
+		return PasswordPolicy{}, errors.New("password minimum length must be a positive number")
+	}
+
+	rules := []PasswordRule{MinLengthRule{Length: length}}
+	if classPart != "" {
+		for _, class := range strings.Split(classPart, "+") {
+			if _, ok := characterClasses[class]; !ok {
+				return PasswordPolicy{}, errors.New("password character class must be lower, upper, digit or symbol")
+			}
+			rules = append(rules, CharacterClassRule{Class: class})
+		}
+	}
+
+	rules = append(rules, SimilarityRule{})
+	if breached != nil {
+		rules = append(rules, BreachedPasswordRule{Filter: breached})
+	}
+	return PasswordPolicy{Rules: rules}, nil
+}
+
+// PasswordValidator checks passwords against the policy of the role they belong to
+type PasswordValidator interface {
+	Validate(roleName, field, password string, subject PasswordSubject) error
+}
+
+type passwordValidator struct {
+	Policies map[string]PasswordPolicy
+}
+
+// NewPasswordValidator takes policies keyed by lower-case role name. Roles without a policy of
+// their own use the "default" one.
+func NewPasswordValidator(policies map[string]PasswordPolicy) PasswordValidator {
+	return passwordValidator{Policies: policies}
+}
+
+func (v passwordValidator) Validate(roleName, field, password string, subject PasswordSubject) error {
+	policy, ok := v.Policies[strings.ToLower(roleName)]
+	if !ok {
+		policy = v.Policies[DefaultPasswordPolicy]
+	}
+	return policy.Validate(field, password, subject)
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func violationCodes(err error) []string {
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return nil
+	}
+	codes := make([]string, 0, len(policyErr.Errors))
+	for _, fieldErr := range policyErr.Errors {
+		codes = append(codes, fieldErr.Code)
+	}
+	return codes
+}
+
+func TestPasswordRules(t *testing.T) {
+	subject := PasswordSubject{Username: "alice_w", Email: "Alice.Walker@example.com"}
+
+	tests := []struct {
+		name     string
+		rule     PasswordRule
+		password string
+		wantCode string
+	}{
+		{"long enough", MinLengthRule{Length: 8}, "abcdefgh", ""},
+		{"too short", MinLengthRule{Length: 8}, "abcdefg", "min_length"},
+		{"length counts characters, not bytes", MinLengthRule{Length: 4}, "äöüß", ""},
+		{"has lowercase", CharacterClassRule{Class: "lower"}, "ABCd", ""},
+		{"missing lowercase", CharacterClassRule{Class: "lower"}, "ABCD", "missing_lower"},
+		{"has uppercase", CharacterClassRule{Class: "upper"}, "abcD", ""},
+		{"missing uppercase", CharacterClassRule{Class: "upper"}, "abcd", "missing_upper"},
+		{"has digit", CharacterClassRule{Class: "digit"}, "abc1", ""},
+		{"missing digit", CharacterClassRule{Class: "digit"}, "abcd", "missing_digit"},
+		{"has punctuation", CharacterClassRule{Class: "symbol"}, "abc!", ""},
+		{"has symbol", CharacterClassRule{Class: "symbol"}, "abc+", ""},
+		{"missing symbol", CharacterClassRule{Class: "symbol"}, "abc1", "missing_symbol"},
+		{"unrelated to the user", SimilarityRule{}, "Tr0ub4dor&3", ""},
+		{"contains the username", SimilarityRule{}, "xxALICE_Wxx", "similar_to_username"},
+		{"contains the email local part", SimilarityRule{}, "alice.walker2024", "similar_to_email"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violation := tt.rule.Check(tt.password, subject)
+			var gotCode string
+			if violation != nil {
+				gotCode = violation.Code
+			}
+			if gotCode != tt.wantCode {
+				t.Errorf("Check(%q) = %q, want %q", tt.password, gotCode, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestSimilarityRuleIgnoresShortNames(t *testing.T) {
+	subject := PasswordSubject{Username: "al", Email: "jo@example.com"}
+	if violation := (SimilarityRule{}).Check("al-jo-password", subject); violation != nil {
+		t.Errorf("Check() = %q, names under three characters must not count", violation.Code)
+	}
+}
+
+func TestBreachedPasswordRule(t *testing.T) {
+	filter := NewBloomFilter(10, 0.001)
+	filter.Add("password1")
+
+	tests := []struct {
+		password string
+		wantCode string
+	}{
+		{"password1", "breached"},
+		{"Password1", "breached"},
+		{"correct-horse-battery", ""},
+	}
+
+	for _, tt := range tests {
+		violation := (BreachedPasswordRule{Filter: filter}).Check(tt.password, PasswordSubject{})
+		var gotCode string
+		if violation != nil {
+			gotCode = violation.Code
+		}
+		if gotCode != tt.wantCode {
+			t.Errorf("Check(%q) = %q, want %q", tt.password, gotCode, tt.wantCode)
+		}
+	}
+}
+
+func TestParsePasswordPolicy(t *testing.T) {
+	subject := PasswordSubject{Username: "alice_w", Email: "alice@example.com"}
+
+	tests := []struct {
+		name      string
+		value     string
+		password  string
+		wantCodes []string
+		wantErr   bool
+	}{
+		{"length only", "8", "abcdefgh", nil, false},
+		{"every violation is reported", "12/lower+upper+digit+symbol", "alice", []string{"min_length", "missing_upper", "missing_digit", "missing_symbol", "similar_to_email"}, false},
+		{"meets the policy", "12/lower+upper+digit+symbol", "Correct-Horse-42", nil, false},
+		{"surrounding spaces", " 10/digit ", "abcdefghij", []string{"missing_digit"}, false},
+		{"length is not a number", "twelve", "", nil, true},
+		{"length is zero", "0/lower", "", nil, true},
+		{"unknown class", "8/lower+emoji", "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := ParsePasswordPolicy(tt.value, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePasswordPolicy(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			err = policy.Validate("password", tt.password, subject)
+			if got := violationCodes(err); !reflect.DeepEqual(got, tt.wantCodes) {
+				t.Errorf("Validate(%q) codes = %v, want %v", tt.password, got, tt.wantCodes)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyValidateSetsField(t *testing.T) {
+	policy, err := ParsePasswordPolicy("8", nil)
+	if err != nil {
+		t.Fatalf("ParsePasswordPolicy() error = %v", err)
+	}
+
+	var policyErr *PasswordPolicyError
+	if !errors.As(policy.Validate("new_password", "short", PasswordSubject{}), &policyErr) {
+		t.Fatal("Validate() did not return a PasswordPolicyError")
+	}
+	if policyErr.Errors[0].Field != "new_password" {
+		t.Errorf("violation field = %q, want new_password", policyErr.Errors[0].Field)
+	}
+}
+
+func TestPasswordValidatorPolicyByRole(t *testing.T) {
+	defaultPolicy, _ := ParsePasswordPolicy("8", nil)
+	adminPolicy, _ := ParsePasswordPolicy("16/symbol", nil)
+	validator := NewPasswordValidator(map[string]PasswordPolicy{
+		DefaultPasswordPolicy: defaultPolicy,
+		"super admin":         adminPolicy,
+	})
+
+	tests := []struct {
+		name      string
+		role      string
+		wantCodes []string
+	}{
+		{"role with its own policy", "Super Admin", []string{"min_length", "missing_symbol"}},
+		{"role without a policy uses the default", "User", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.Validate(tt.role, "password", "abcdefghij", PasswordSubject{})
+			if got := violationCodes(err); !reflect.DeepEqual(got, tt.wantCodes) {
+				t.Errorf("Validate() codes = %v, want %v", got, tt.wantCodes)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrPinLocked = errors.New("pin Code is locked, please try again later")
+
+// PinLockPolicy decides when wrong PIN entries lock the PIN and for how long
+type PinLockPolicy struct {
+	MaxAttempts int
+	Durations   []time.Duration
+}
+
+func NewPinLockPolicy(maxAttempts int, durations []time.Duration) PinLockPolicy {
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	if len(durations) == 0 {
+		durations = []time.Duration{15 * time.Minute}
+	}
+	return PinLockPolicy{
+		MaxAttempts: maxAttempts,
+		Durations:   durations,
+	}
+}
+
+// LockDuration returns the window for a PIN that has already been locked lockCount times,
+// the last configured window applies to every lock after it
+func (p PinLockPolicy) LockDuration(lockCount int) time.Duration {
+	if lockCount >= len(p.Durations) {
+		return p.Durations[len(p.Durations)-1]
+	}
+	return p.Durations[lockCount]
+}
+
+// IsLocked reports whether a lock is still in force
+func (p PinLockPolicy) IsLocked(lockedUntil *time.Time) bool {
+	return lockedUntil != nil && lockedUntil.After(time.Now())
+}
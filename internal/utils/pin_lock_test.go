@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPinLockPolicyLockDuration(t *testing.T) {
+	policy := NewPinLockPolicy(3, []time.Duration{15 * time.Minute, time.Hour, 24 * time.Hour})
+
+	tests := []struct {
+		lockCount int
+		want      time.Duration
+	}{
+		{0, 15 * time.Minute},
+		{1, time.Hour},
+		{2, 24 * time.Hour},
+		{3, 24 * time.Hour},
+		{10, 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := policy.LockDuration(tt.lockCount); got != tt.want {
+			t.Errorf("LockDuration(%d) = %v, want %v", tt.lockCount, got, tt.want)
+		}
+	}
+}
+
+func TestNewPinLockPolicyDefaults(t *testing.T) {
+	policy := NewPinLockPolicy(0, nil)
+	if policy.MaxAttempts != 5 || policy.LockDuration(0) != 15*time.Minute {
+		t.Errorf("NewPinLockPolicy(0, nil) = %+v, want 5 attempts and a 15m lock", policy)
+	}
+}
+
+func TestPinLockPolicyIsLocked(t *testing.T) {
+	policy := NewPinLockPolicy(3, nil)
+	past := time.Now().Add(-time.Second)
+	future := time.Now().Add(time.Minute)
+
+	if policy.IsLocked(nil) {
+		t.Error("IsLocked(nil) = true, want false")
+	}
+	if policy.IsLocked(&past) {
+		t.Error("IsLocked(past) = true, an expired lock must not hold")
+	}
+	if !policy.IsLocked(&future) {
+		t.Error("IsLocked(future) = false, want true")
+	}
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate limit rule names used by the routes
+const (
+	RateLimitSensitive     = "sensitive"
+	RateLimitPublic        = "public"
+	RateLimitAuthenticated = "authenticated"
+)
+
+// What a rate limit is keyed by
+const (
+	RateLimitByIP       = "ip"
+	RateLimitByClientID = "client_id"
+	RateLimitByRoute    = "route"
+)
+
+// RateLimitRule allows Limit requests per Window for every key
+type RateLimitRule struct {
+	Limit  int
+	Window time.Duration
+	KeyBy  string
+}
+
+// ParseRateLimitRule reads a rule written as "<limit>/<window>[/<key by>]", e.g. "5/15m/ip".
+// Rules are keyed by IP unless stated otherwise.
+func ParseRateLimitRule(value string) (RateLimitRule, error) {
+	parts := strings.Split(strings.TrimSpace(value), "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return RateLimitRule{}, errors.New("rate limit rule must look like <limit>/<window>[/<key by>]")
+	}
+
+	limit, err := strconv.Atoi(parts[0])
+	if err != nil || limit <= 0 {
+		return RateLimitRule{}, errors.New("rate limit must be a positive number")
+	}
+
+	window, err := time.ParseDuration(parts[1])
+	if err != nil || window <= 0 {
+		return RateLimitRule{}, errors.New("rate limit window must be a positive duration")
+	}
+
+	keyBy := RateLimitByIP
+	if len(parts) == 3 {
+		keyBy = parts[2]
+	}
+	switch keyBy {
+	case RateLimitByIP, RateLimitByClientID, RateLimitByRoute:
+	default:
+		return RateLimitRule{}, errors.New("rate limit key must be ip, client_id or route")
+	}
+
+	return RateLimitRule{Limit: limit, Window: window, KeyBy: keyBy}, nil
+}
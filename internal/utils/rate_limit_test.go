@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRateLimitRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    RateLimitRule
+		wantErr bool
+	}{
+		{"keyed by IP by default", "5/15m", RateLimitRule{Limit: 5, Window: 15 * time.Minute, KeyBy: RateLimitByIP}, false},
+		{"keyed by client ID", "100/1m/client_id", RateLimitRule{Limit: 100, Window: time.Minute, KeyBy: RateLimitByClientID}, false},
+		{"keyed by route", " 1000/1s/route ", RateLimitRule{Limit: 1000, Window: time.Second, KeyBy: RateLimitByRoute}, false},
+		{"missing window", "5", RateLimitRule{}, true},
+		{"too many parts", "5/1m/ip/extra", RateLimitRule{}, true},
+		{"limit is zero", "0/1m", RateLimitRule{}, true},
+		{"limit is not a number", "five/1m", RateLimitRule{}, true},
+		{"window without unit", "5/60", RateLimitRule{}, true},
+		{"negative window", "5/-1m", RateLimitRule{}, true},
+		{"unknown key", "5/1m/user", RateLimitRule{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRateLimitRule(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRateLimitRule(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRateLimitRule(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
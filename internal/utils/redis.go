@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/redis/go-redis/v9"
+	"math/rand"
 	"time"
 )
 
@@ -14,8 +15,14 @@ import (
 type RedisService interface {
 	SaveData(key, clientID string, data interface{}) error
 	SaveDataExpired(key, clientID string, exp float32, data interface{}) error
+	SaveDataWithTTL(key, clientID string, ttl time.Duration, data interface{}) error
 	GetData(key, clientID string, target interface{}) error
+	GetAndDeleteData(key, clientID string, target interface{}) error
 	DeleteData(key, clientID string) error
+	Exists(key, clientID string) (bool, error)
+	Increment(key, clientID string, ttl time.Duration) (int64, error)
+	TTL(key, clientID string) (time.Duration, error)
+	SlidingWindow(key, clientID string, limit int, window time.Duration) (time.Duration, error)
 	GetToken(clientID string) (string, error)
 	DeleteToken(clientID string) error
 }
@@ -52,6 +59,15 @@ func (r redisService) SaveDataExpired(key, clientID string, exp float32, data in
 	return r.Client.Set(r.Ctx, key+":"+clientID, jsonData, time.Duration(exp)*time.Minute).Err()
 }
 
+// SaveDataWithTTL stores data in Redis with an exact time to live
+func (r redisService) SaveDataWithTTL(key, clientID string, ttl time.Duration, data interface{}) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal data: %v", err)
+	}
+	return r.Client.Set(r.Ctx, key+":"+clientID, jsonData, ttl).Err()
+}
+
 // GetData retrieves and unmarshals data from Redis
 func (r redisService) GetData(key, clientID string, target interface{}) error {
 	jsonData, err := r.Client.Get(r.Ctx, key+":"+clientID).Result()
@@ -63,11 +79,83 @@ func (r redisService) GetData(key, clientID string, target interface{}) error {
 	return json.Unmarshal([]byte(jsonData), target)
 }
 
+// GetAndDeleteData atomically retrieves and removes a key, so single-use values cannot be read twice
+func (r redisService) GetAndDeleteData(key, clientID string, target interface{}) error {
+	jsonData, err := r.Client.GetDel(r.Ctx, key+":"+clientID).Result()
+	if errors.Is(err, redis.Nil) {
+		return fmt.Errorf("no data found for key: %s", key+":"+clientID)
+	} else if err != nil {
+		return fmt.Errorf("failed to get data: %v", err)
+	}
+	return json.Unmarshal([]byte(jsonData), target)
+}
+
 // DeleteData removes a key from Redis
 func (r redisService) DeleteData(key, clientID string) error {
 	return r.Client.Del(r.Ctx, key+":"+clientID).Err()
 }
 
+// Exists reports whether a key is present in Redis
+func (r redisService) Exists(key, clientID string) (bool, error) {
+	count, err := r.Client.Exists(r.Ctx, key+":"+clientID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check key: %v", err)
+	}
+	return count > 0, nil
+}
+
+// Increment adds one to a counter. The time to live starts when the counter is created, so
+// the counter covers a fixed window.
+func (r redisService) Increment(key, clientID string, ttl time.Duration) (int64, error) {
+	count, err := r.Client.Incr(r.Ctx, key+":"+clientID).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment key: %v", err)
+	}
+	if count == 1 {
+		if err := r.Client.Expire(r.Ctx, key+":"+clientID, ttl).Err(); err != nil {
+			return count, fmt.Errorf("failed to expire key: %v", err)
+		}
+	}
+	return count, nil
+}
+
+// TTL returns the time a key has left to live, zero or negative when it has none
+func (r redisService) TTL(key, clientID string) (time.Duration, error) {
+	ttl, err := r.Client.TTL(r.Ctx, key+":"+clientID).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get key ttl: %v", err)
+	}
+	return ttl, nil
+}
+
+// slidingWindowScript keeps one sorted set member per request inside the window. It records the
+// request and returns 0 when there is room, otherwise the milliseconds until the oldest request leaves.
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], 0, now - window)
+if redis.call('ZCARD', KEYS[1]) < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	redis.call('PEXPIRE', KEYS[1], window)
+	return 0
+end
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return math.max(1, tonumber(oldest[2]) + window - now)
+`)
+
+// SlidingWindow counts a request against a limit over the trailing window. It returns zero when
+// the request is allowed, otherwise how long the caller has to wait.
+func (r redisService) SlidingWindow(key, clientID string, limit int, window time.Duration) (time.Duration, error) {
+	now := time.Now()
+	retryAfter, err := slidingWindowScript.Run(r.Ctx, &r.Client, []string{key + ":" + clientID},
+		now.UnixMilli(), window.Milliseconds(), limit, fmt.Sprintf("%d-%d", now.UnixNano(), rand.Int63())).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check rate limit: %v", err)
+	}
+	return time.Duration(retryAfter) * time.Millisecond, nil
+}
+
 // generateRedisKey creates a formatted key for token storage
 func generateRedisKey(clientID string) string {
 	return "token:" + clientID
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// testRedisService connects to the Redis at REDIS_TEST_ADDR. The sliding window is a Lua script, so
+// it is only tested against a real server; the test is skipped when none is configured.
+func testRedisService(t *testing.T) RedisService {
+	t.Helper()
+	addr := os.Getenv("REDIS_TEST_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_TEST_ADDR is not set")
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("unable to reach Redis at %s: %v", addr, err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return NewRedisService(*client)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	service := testRedisService(t)
+	id := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	t.Cleanup(func() { _ = service.DeleteData(RateLimit, id) })
+
+	const limit = 3
+	const window = time.Second
+
+	tests := []struct {
+		name      string
+		wait      time.Duration
+		wantAllow bool
+	}{
+		{"first request", 0, true},
+		{"second request", 0, true},
+		{"last request within the limit", 0, true},
+		{"over the limit", 0, false},
+		{"still over the limit", 0, false},
+		{"the window has moved past the first requests", window + 100*time.Millisecond, true},
+	}
+
+	for _, tt := range tests {
+		time.Sleep(tt.wait)
+		retryAfter, err := service.SlidingWindow(RateLimit, id, limit, window)
+		if err != nil {
+			t.Fatalf("%s: SlidingWindow() error = %v", tt.name, err)
+		}
+		if allowed := retryAfter == 0; allowed != tt.wantAllow {
+			t.Fatalf("%s: SlidingWindow() = %v, want allowed %v", tt.name, retryAfter, tt.wantAllow)
+		}
+		if retryAfter > window {
+			t.Errorf("%s: SlidingWindow() = %v, must not exceed the window", tt.name, retryAfter)
+		}
+	}
+}
+
+func TestSlidingWindowRejectedRequestsAreNotCounted(t *testing.T) {
+	service := testRedisService(t)
+	id := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	t.Cleanup(func() { _ = service.DeleteData(RateLimit, id) })
+
+	const window = 500 * time.Millisecond
+	if retryAfter, err := service.SlidingWindow(RateLimit, id, 1, window); err != nil || retryAfter != 0 {
+		t.Fatalf("first SlidingWindow() = (%v, %v), want allowed", retryAfter, err)
+	}
+
+	// hammering a full window must not push the retry time back
+	for i := 0; i < 10; i++ {
+		if retryAfter, err := service.SlidingWindow(RateLimit, id, 1, window); err != nil || retryAfter == 0 {
+			t.Fatalf("SlidingWindow() = (%v, %v), want rejected", retryAfter, err)
+		}
+	}
+
+	time.Sleep(window + 100*time.Millisecond)
+	if retryAfter, err := service.SlidingWindow(RateLimit, id, 1, window); err != nil || retryAfter != 0 {
+		t.Errorf("SlidingWindow() after the window = (%v, %v), want allowed", retryAfter, err)
+	}
+}
@@ -0,0 +1,179 @@
+// Package redistest provides an in-memory stand-in for utils.RedisService in tests.
+package redistest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Memory stores values as JSON under the same "key:id" names the Redis service uses, so tests can
+// check what a service cached or removed
+type Memory struct {
+	mu      sync.Mutex
+	values  map[string]memoryValue
+	windows map[string][]time.Time
+}
+
+type memoryValue struct {
+	data      []byte
+	expiresAt time.Time // zero when the value never expires
+}
+
+func NewMemory() *Memory {
+	return &Memory{values: make(map[string]memoryValue), windows: make(map[string][]time.Time)}
+}
+
+func (m *Memory) SaveData(key, clientID string, data interface{}) error {
+	return m.set(key+":"+clientID, data, 0)
+}
+
+func (m *Memory) SaveDataExpired(key, clientID string, exp float32, data interface{}) error {
+	return m.set(key+":"+clientID, data, time.Duration(exp)*time.Minute)
+}
+
+func (m *Memory) SaveDataWithTTL(key, clientID string, ttl time.Duration, data interface{}) error {
+	return m.set(key+":"+clientID, data, ttl)
+}
+
+func (m *Memory) GetData(key, clientID string, target interface{}) error {
+	data, ok := m.get(key + ":" + clientID)
+	if !ok {
+		return fmt.Errorf("no data found for key: %s", key+":"+clientID)
+	}
+	return json.Unmarshal(data, target)
+}
+
+func (m *Memory) GetAndDeleteData(key, clientID string, target interface{}) error {
+	if err := m.GetData(key, clientID, target); err != nil {
+		return err
+	}
+	return m.DeleteData(key, clientID)
+}
+
+func (m *Memory) DeleteData(key, clientID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key+":"+clientID)
+	delete(m.windows, key+":"+clientID)
+	return nil
+}
+
+func (m *Memory) Exists(key, clientID string) (bool, error) {
+	return m.Has(key, clientID), nil
+}
+
+func (m *Memory) Increment(key, clientID string, ttl time.Duration) (int64, error) {
+	var count int64
+	if data, ok := m.get(key + ":" + clientID); ok {
+		if err := json.Unmarshal(data, &count); err != nil {
+			return 0, fmt.Errorf("failed to increment key: %v", err)
+		}
+	} else {
+		// like INCR followed by EXPIRE on a new counter, the window starts with the first increment
+		if err := m.set(key+":"+clientID, 0, ttl); err != nil {
+			return 0, err
+		}
+	}
+	count++
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value := m.values[key+":"+clientID]
+	value.data, _ = json.Marshal(count)
+	m.values[key+":"+clientID] = value
+	return count, nil
+}
+
+// TTL follows the Redis convention of -2 for a missing key and -1 for a key without expiry
+func (m *Memory) TTL(key, clientID string) (time.Duration, error) {
+	if _, ok := m.get(key + ":" + clientID); !ok {
+		return -2, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value := m.values[key+":"+clientID]
+	if value.expiresAt.IsZero() {
+		return -1, nil
+	}
+	return time.Until(value.expiresAt), nil
+}
+
+// SlidingWindow keeps the requests of the trailing window like the Lua script does and returns
+// how long the caller has to wait once the limit is reached
+func (m *Memory) SlidingWindow(key, clientID string, limit int, window time.Duration) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var requests []time.Time
+	for _, at := range m.windows[key+":"+clientID] {
+		if now.Sub(at) < window {
+			requests = append(requests, at)
+		}
+	}
+
+	if len(requests) < limit {
+		m.windows[key+":"+clientID] = append(requests, now)
+		return 0, nil
+	}
+	m.windows[key+":"+clientID] = requests
+
+	retryAfter := requests[0].Add(window).Sub(now)
+	if retryAfter < time.Millisecond {
+		retryAfter = time.Millisecond
+	}
+	return retryAfter, nil
+}
+
+func (m *Memory) GetToken(clientID string) (string, error) {
+	data, _ := m.get("token:" + clientID)
+	return string(data), nil
+}
+
+func (m *Memory) DeleteToken(clientID string) error {
+	return m.DeleteData("token", clientID)
+}
+
+// Has reports whether a value is stored and has not expired
+func (m *Memory) Has(key, clientID string) bool {
+	_, ok := m.get(key + ":" + clientID)
+	return ok
+}
+
+// Expire drops a value as if its time to live had run out
+func (m *Memory) Expire(key, clientID string) {
+	_ = m.DeleteData(key, clientID)
+}
+
+func (m *Memory) set(name string, data interface{}, ttl time.Duration) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal data: %v", err)
+	}
+
+	value := memoryValue{data: jsonData}
+	if ttl > 0 {
+		value.expiresAt = time.Now().Add(ttl)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[name] = value
+	return nil
+}
+
+func (m *Memory) get(name string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.values[name]
+	if !ok {
+		return nil, false
+	}
+	if !value.expiresAt.IsZero() && !time.Now().Before(value.expiresAt) {
+		delete(m.values, name)
+		return nil, false
+	}
+	return value.data, true
+}
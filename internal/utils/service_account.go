@@ -0,0 +1,14 @@
+package utils
+
+import "strings"
+
+// ServiceAccountClientIDPrefix tells service accounts apart from OAuth clients at /oauth/token
+const ServiceAccountClientIDPrefix = "svc_"
+
+func GenerateServiceAccountClientID() string {
+	return ServiceAccountClientIDPrefix + GenerateClientID()
+}
+
+func IsServiceAccountClientID(clientID string) bool {
+	return strings.HasPrefix(clientID, ServiceAccountClientIDPrefix)
+}
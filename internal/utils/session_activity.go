@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"time"
+)
+
+// SessionActivity tracks when a session's access token was last used. It lives in Redis so
+// authenticated requests do not write to the database.
+type SessionActivity interface {
+	Touch(accessUUID string, expiresAt int64)
+	LastSeen(accessUUID string) (time.Time, bool)
+}
+
+type sessionActivity struct {
+	Redis RedisService
+}
+
+func NewSessionActivity(redis RedisService) SessionActivity {
+	return sessionActivity{Redis: redis}
+}
+
+// Touch records the current time as the session's last activity, kept until the token expires
+func (a sessionActivity) Touch(accessUUID string, expiresAt int64) {
+	ttl := time.Until(time.Unix(expiresAt, 0))
+	if accessUUID == "" || ttl <= 0 {
+		return
+	}
+	_ = a.Redis.SaveDataWithTTL(SessionLastSeen, accessUUID, ttl, time.Now().Unix())
+}
+
+func (a sessionActivity) LastSeen(accessUUID string) (time.Time, bool) {
+	var lastSeen int64
+	if accessUUID == "" || a.Redis.GetData(SessionLastSeen, accessUUID, &lastSeen) != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(lastSeen, 0), true
+}
@@ -0,0 +1,222 @@
+package utils
+
+import (
+	"authentication/internal/dto/out"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+const (
+	SigningKeyPending = "pending"
+	SigningKeyActive  = "active"
+	SigningKeyRetired = "retired"
+)
+
+// SigningKey is an asymmetric key pair used to sign and verify JWTs
+type SigningKey struct {
+	KeyID       string
+	Algorithm   string
+	Status      string
+	ActivatedAt time.Time
+	PrivateKey  crypto.Signer
+	PublicKey   crypto.PublicKey
+}
+
+// KeyStore supplies the persisted keyring; only pending and active keys are returned
+type KeyStore interface {
+	LoadSigningKeys() ([]*SigningKey, error)
+}
+
+// LoadSigningKey parses the operator-supplied private key from a PEM string or file.
+// It returns nil when no key is configured or the algorithm is HS256.
+func LoadSigningKey(algorithm, privateKeyPEM, privateKeyFile string) (*SigningKey, error) {
+	if algorithm == "" || algorithm == AlgorithmHS256 {
+		return nil, nil
+	}
+	if algorithm != AlgorithmRS256 && algorithm != AlgorithmEdDSA {
+		return nil, fmt.Errorf("unsupported JWT algorithm: %s", algorithm)
+	}
+
+	if privateKeyPEM == "" && privateKeyFile != "" {
+		data, err := os.ReadFile(privateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key file: %v", err)
+		}
+		privateKeyPEM = string(data)
+	}
+
+	if privateKeyPEM == "" {
+		return nil, nil
+	}
+
+	return ParseSigningKey(algorithm, strings.ReplaceAll(privateKeyPEM, `\n`, "\n"))
+}
+
+// GenerateSigningKey creates a fresh key pair for the algorithm
+func GenerateSigningKey(algorithm string) (*SigningKey, error) {
+	var signer crypto.Signer
+	switch algorithm {
+	case AlgorithmRS256:
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		signer = key
+	case AlgorithmEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		signer = key
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm: %s", algorithm)
+	}
+	return NewSigningKey(algorithm, signer)
+}
+
+// ParseSigningKey decodes a PKCS#8 (or PKCS#1 for RSA) PEM private key
+func ParseSigningKey(algorithm, privateKeyPEM string) (*SigningKey, error) {
+	block, _ := pem.Decode([]byte(privateKeyPEM))
+	if block == nil {
+		return nil, errors.New("invalid PEM private key")
+	}
+
+	var parsed interface{}
+	var err error
+	if block.Type == "RSA PRIVATE KEY" {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key cannot be used for signing")
+	}
+	return NewSigningKey(algorithm, signer)
+}
+
+// NewSigningKey checks the key type against the algorithm and derives its kid
+func NewSigningKey(algorithm string, signer crypto.Signer) (*SigningKey, error) {
+	switch signer.(type) {
+	case *rsa.PrivateKey:
+		if algorithm != AlgorithmRS256 {
+			return nil, errors.New("RSA keys can only be used with RS256")
+		}
+	case ed25519.PrivateKey:
+		if algorithm != AlgorithmEdDSA {
+			return nil, errors.New("Ed25519 keys can only be used with EdDSA")
+		}
+	default:
+		return nil, errors.New("unsupported private key type")
+	}
+
+	key := &SigningKey{
+		Algorithm:  algorithm,
+		PrivateKey: signer,
+		PublicKey:  signer.Public(),
+	}
+
+	kid, err := key.Thumbprint()
+	if err != nil {
+		return nil, err
+	}
+	key.KeyID = kid
+	return key, nil
+}
+
+// MarshalPrivateKey encodes the private key as a PKCS#8 PEM block
+func (k *SigningKey) MarshalPrivateKey() (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// Method returns the jwt signing method for the key
+func (k *SigningKey) Method() jwt.SigningMethod {
+	if k.Algorithm == AlgorithmEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+// JWK returns the public JSON Web Key
+func (k *SigningKey) JWK() out.JSONWebKey {
+	switch pub := k.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return out.JSONWebKey{
+			Kty: "RSA",
+			Use: "sig",
+			Kid: k.KeyID,
+			Alg: k.Algorithm,
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		return out.JSONWebKey{
+			Kty: "OKP",
+			Use: "sig",
+			Kid: k.KeyID,
+			Alg: k.Algorithm,
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}
+	}
+	return out.JSONWebKey{}
+}
+
+// Thumbprint computes the RFC 7638 JWK thumbprint, used as the kid
+func (k *SigningKey) Thumbprint() (string, error) {
+	jwk := k.JWK()
+
+	// members must be in lexicographic order with no whitespace
+	var canonical []byte
+	var err error
+	switch jwk.Kty {
+	case "RSA":
+		canonical, err = json.Marshal(struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N})
+	case "OKP":
+		canonical, err = json.Marshal(struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X})
+	default:
+		return "", errors.New("unsupported key type")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
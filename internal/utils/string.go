@@ -19,6 +19,8 @@ func ValidationTrimSpace(s string) string {
 var (
 	ErrUsernameLength  = errors.New("username must be between 3 and 20 characters")
 	ErrUsernameInvalid = errors.New("username can only contain alphanumeric characters and underscores")
+	ErrPinCodeLength   = errors.New("pin Code must be 6 digits")
+	ErrPinCodeInvalid  = errors.New("pin Code must be numeric")
 )
 
 // ValidateUsername checks if the username meets the criteria
@@ -37,6 +39,17 @@ func ValidateUsername(username string) error {
 	return nil
 }
 
+// ValidatePinCode checks that a PIN is exactly 6 digits
+func ValidatePinCode(pinCode string) error {
+	if len(pinCode) != 6 {
+		return ErrPinCodeLength
+	}
+	if !regexp.MustCompile(`^\d+$`).MatchString(pinCode) {
+		return ErrPinCodeInvalid
+	}
+	return nil
+}
+
 func DecryptOptionalString(value *string, encryption Encryption) *string {
 	if value == nil {
 		return nil
@@ -48,6 +61,15 @@ func DecryptOptionalString(value *string, encryption Encryption) *string {
 	return &decrypted
 }
 
+// DecryptString returns value decrypted, or unchanged when it is not ciphertext (rows not re-encrypted yet)
+func DecryptString(value string, encryption Encryption) string {
+	decrypted, err := encryption.Decrypt(value)
+	if err != nil {
+		return value
+	}
+	return decrypted
+}
+
 func ConvertToUint(input string) (uint, error) {
 	parsed, err := strconv.ParseUint(input, 10, 32)
 	if err != nil {
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"math"
+	"time"
+)
+
+// ThrottleError rejects a request that came too soon and tells the caller when to retry
+type ThrottleError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottleError) Error() string {
+	return "too many attempts, please try again later"
+}
+
+func NewThrottleError(retryAfter time.Duration) *ThrottleError {
+	return &ThrottleError{RetryAfter: retryAfter}
+}
+
+// RetryAfterSeconds rounds the wait up to whole seconds for the Retry-After header
+func (e *ThrottleError) RetryAfterSeconds() int {
+	seconds := int(math.Ceil(e.RetryAfter.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// TokenHasher derives the value stored in place of a bearer token, so a database dump
+// does not contain usable session or refresh tokens
+type TokenHasher interface {
+	Hash(token string) string
+}
+
+type tokenHasher struct {
+	Key []byte
+}
+
+func NewTokenHasher(key string) TokenHasher {
+	return tokenHasher{Key: []byte(key)}
+}
+
+// Hash returns the hex encoded HMAC-SHA256 of the token
+func (h tokenHasher) Hash(token string) string {
+	mac := hmac.New(sha256.New, h.Key)
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,15 @@
+package utils
+
+import "testing"
+
+func TestTokenHasher(t *testing.T) {
+	// RFC 4231 test case 2
+	hasher := NewTokenHasher("Jefe")
+	if got, want := hasher.Hash("what do ya want for nothing?"), "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"; got != want {
+		t.Errorf("Hash() = %s, want %s", got, want)
+	}
+
+	if hasher.Hash("token") == NewTokenHasher("another key").Hash("token") {
+		t.Error("Hash() must depend on the key")
+	}
+}
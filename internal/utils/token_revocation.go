@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"strconv"
+	"time"
+)
+
+// TokenRevocation is the server-side deny list for access tokens that have not expired yet
+type TokenRevocation interface {
+	RevokeToken(accessUUID string, expiresAt int64) error
+	RevokeUserTokens(userID uint) error
+	IsRevoked(claims *TokenClaims) (bool, error)
+}
+
+type tokenRevocation struct {
+	Redis RedisService
+}
+
+func NewTokenRevocation(redis RedisService) TokenRevocation {
+	return tokenRevocation{Redis: redis}
+}
+
+// RevokeToken rejects a single access token until it would have expired anyway
+func (t tokenRevocation) RevokeToken(accessUUID string, expiresAt int64) error {
+	if accessUUID == "" {
+		return nil
+	}
+
+	ttl := time.Until(time.Unix(expiresAt, 0))
+	if ttl <= 0 {
+		return nil
+	}
+	return t.Redis.SaveDataWithTTL(RevokedAccessToken, accessUUID, ttl, true)
+}
+
+// RevokeUserTokens rejects every access token issued to the user up to now. The cutoff only has
+// to outlive the longest access token lifetime.
+func (t tokenRevocation) RevokeUserTokens(userID uint) error {
+	return t.Redis.SaveDataWithTTL(RevokedBefore, strconv.FormatUint(uint64(userID), 10), time.Hour*AccessTokenExpiry, time.Now().Unix())
+}
+
+// IsRevoked reports whether the token was revoked on its own or issued before its user's cutoff.
+// iat only has second precision, so a token from the same second as the cutoff is revoked too.
+func (t tokenRevocation) IsRevoked(claims *TokenClaims) (bool, error) {
+	revoked, err := t.Redis.Exists(RevokedAccessToken, claims.AccessUUID)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	userID := strconv.FormatUint(uint64(claims.UserID), 10)
+	hasCutoff, err := t.Redis.Exists(RevokedBefore, userID)
+	if err != nil || !hasCutoff {
+		return false, err
+	}
+
+	var revokedBefore int64
+	if err := t.Redis.GetData(RevokedBefore, userID, &revokedBefore); err != nil {
+		return false, err
+	}
+	return claims.IssuedAt <= revokedBefore, nil
+}
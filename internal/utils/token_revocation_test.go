@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"authentication/internal/utils/redistest"
+	"testing"
+	"time"
+)
+
+func TestTokenRevocation(t *testing.T) {
+	now := time.Now()
+	hourAgo := now.Add(-time.Hour).Unix()
+
+	tests := []struct {
+		name   string
+		revoke func(TokenRevocation)
+		claims TokenClaims
+		want   bool
+	}{
+		{"nothing revoked", func(TokenRevocation) {}, TokenClaims{AccessUUID: "a", UserID: 1, IssuedAt: hourAgo}, false},
+		{"token on the deny list", func(r TokenRevocation) {
+			_ = r.RevokeToken("a", now.Add(time.Hour).Unix())
+		}, TokenClaims{AccessUUID: "a", UserID: 1, IssuedAt: hourAgo}, true},
+		{"another token of the user", func(r TokenRevocation) {
+			_ = r.RevokeToken("a", now.Add(time.Hour).Unix())
+		}, TokenClaims{AccessUUID: "b", UserID: 1, IssuedAt: hourAgo}, false},
+		{"already expired token is not listed", func(r TokenRevocation) {
+			_ = r.RevokeToken("a", now.Add(-time.Minute).Unix())
+		}, TokenClaims{AccessUUID: "a", UserID: 1, IssuedAt: hourAgo}, false},
+		{"issued before the user's cutoff", func(r TokenRevocation) {
+			_ = r.RevokeUserTokens(1)
+		}, TokenClaims{AccessUUID: "a", UserID: 1, IssuedAt: hourAgo}, true},
+		{"issued in the same second as the user's cutoff", func(r TokenRevocation) {
+			_ = r.RevokeUserTokens(1)
+		}, TokenClaims{AccessUUID: "a", UserID: 1, IssuedAt: time.Now().Unix()}, true},
+		{"issued after the user's cutoff", func(r TokenRevocation) {
+			_ = r.RevokeUserTokens(1)
+		}, TokenClaims{AccessUUID: "a", UserID: 1, IssuedAt: now.Add(time.Minute).Unix()}, false},
+		{"cutoff of another user", func(r TokenRevocation) {
+			_ = r.RevokeUserTokens(2)
+		}, TokenClaims{AccessUUID: "a", UserID: 1, IssuedAt: hourAgo}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revocation := NewTokenRevocation(redistest.NewMemory())
+			tt.revoke(revocation)
+
+			got, err := revocation.IsRevoked(&tt.claims)
+			if err != nil {
+				t.Fatalf("IsRevoked() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsRevoked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
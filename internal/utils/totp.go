@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238) compatible with common authenticator apps
+const (
+	TotpDigits     = 6
+	TotpPeriod     = 30
+	TotpSkew       = 1
+	TotpSecretSize = 20
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTotpSecret creates a random base32 encoded TOTP secret
+func GenerateTotpSecret() (string, error) {
+	secret := make([]byte, TotpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// GenerateTotpURI builds the otpauth:// URI used to provision authenticator apps
+func GenerateTotpURI(issuer, accountName, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprintf("%d", TotpDigits))
+	query.Set("period", fmt.Sprintf("%d", TotpPeriod))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(accountName)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// ValidateTotpCode checks a code against the secret within the allowed skew.
+// Codes from time steps at or before lastUsedStep are rejected to prevent replay.
+// It returns the matched time step so the caller can persist it.
+func ValidateTotpCode(secret, code string, now time.Time, lastUsedStep int64) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != TotpDigits {
+		return 0, false
+	}
+
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+
+	currentStep := now.Unix() / TotpPeriod
+	for offset := int64(-TotpSkew); offset <= TotpSkew; offset++ {
+		step := currentStep + offset
+		if step <= lastUsedStep {
+			continue
+		}
+		expected := generateTotpCode(key, step)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// generateTotpCode computes the HOTP value (RFC 4226) for the given counter
+func generateTotpCode(key []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < TotpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TotpDigits, value%mod)
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 seed of the RFC 6238 appendix B test vectors
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestGenerateTotpCodeRFC6238(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	// RFC 6238 lists 8 digit codes, a 6 digit code is their last six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		if got := generateTotpCode(key, tt.unix/TotpPeriod); got != tt.want {
+			t.Errorf("generateTotpCode(T=%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTotpCode(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / TotpPeriod
+
+	tests := []struct {
+		name         string
+		secret       string
+		code         string
+		lastUsedStep int64
+		wantStep     int64
+		wantValid    bool
+	}{
+		{"current step", rfc6238Secret, "050471", 0, step, true},
+		{"surrounding spaces", rfc6238Secret, " 050471 ", 0, step, true},
+		{"lowercase secret", strings.ToLower(rfc6238Secret), "050471", 0, step, true},
+		{"previous step within skew", rfc6238Secret, generateTotpCode([]byte("12345678901234567890"), step-1), 0, step - 1, true},
+		{"next step within skew", rfc6238Secret, generateTotpCode([]byte("12345678901234567890"), step+1), 0, step + 1, true},
+		{"outside skew", rfc6238Secret, generateTotpCode([]byte("12345678901234567890"), step-2), 0, 0, false},
+		{"replayed step", rfc6238Secret, "050471", step, 0, false},
+		{"wrong code", rfc6238Secret, "123456", 0, 0, false},
+		{"too short", rfc6238Secret, "05047", 0, 0, false},
+		{"eight digits", rfc6238Secret, "14050471", 0, 0, false},
+		{"invalid secret", "not base32!", "050471", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, gotValid := ValidateTotpCode(tt.secret, tt.code, now, tt.lastUsedStep)
+			if gotValid != tt.wantValid || gotStep != tt.wantStep {
+				t.Errorf("ValidateTotpCode() = (%d, %v), want (%d, %v)", gotStep, gotValid, tt.wantStep, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestGenerateTotpSecret(t *testing.T) {
+	secret, err := GenerateTotpSecret()
+	if err != nil {
+		t.Fatalf("GenerateTotpSecret() error = %v", err)
+	}
+
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q is not base32: %v", secret, err)
+	}
+	if len(key) != TotpSecretSize {
+		t.Errorf("secret decodes to %d bytes, want %d", len(key), TotpSecretSize)
+	}
+}
@@ -0,0 +1,4 @@
+-- PIN lockout: wrong PIN entries lock the PIN for a window that grows with every lock
+ALTER TABLE users
+    ADD COLUMN pin_lock_count INT DEFAULT 0 CHECK (pin_lock_count >= 0),
+    ADD COLUMN locked_until   TIMESTAMP NULL;
@@ -0,0 +1,17 @@
+-- Password history: recent password hashes per user, so a password cannot be set again
+CREATE TABLE password_history
+(
+    id         SERIAL PRIMARY KEY,
+    user_id    INT          NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    password   TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255)
+);
+
+CREATE INDEX idx_password_history_user_id ON password_history (user_id, created_at DESC);
+
+-- the current password of every existing user starts their history
+INSERT INTO password_history (user_id, password, created_by)
+SELECT user_id, password, 'system'
+FROM users
+WHERE deleted_at IS NULL;
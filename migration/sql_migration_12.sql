@@ -0,0 +1,12 @@
+-- PII encryption: phone numbers and device IDs are stored with AES-GCM, which gives a new
+-- ciphertext every time, so phone numbers are looked up and kept unique by a keyed blind index
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_phone_number_key,
+    ALTER COLUMN phone_number TYPE TEXT,
+    ALTER COLUMN device_id TYPE TEXT,
+    ADD COLUMN phone_number_index VARCHAR(64) NULL;
+
+CREATE UNIQUE INDEX idx_users_phone_number_index ON users (phone_number_index);
+
+INSERT INTO cron_jobs (name, schedule, is_active, description, created_by)
+VALUES ('encrypt_legacy_pii', '*/10 * * * *', true, 'Re-encrypt phone numbers and device IDs stored with the legacy scheme', 'system');
@@ -0,0 +1,9 @@
+-- Versioned encryption keys: device tokens are encrypted as well, and the re-encryption job moves
+-- phone numbers, device IDs and device tokens to the newest key
+ALTER TABLE users
+    ALTER COLUMN device_token TYPE TEXT;
+
+UPDATE cron_jobs
+SET name        = 'reencrypt_pii',
+    description = 'Re-encrypt phone numbers, device IDs and device tokens under the newest encryption key'
+WHERE name = 'encrypt_legacy_pii';
@@ -0,0 +1,13 @@
+-- Email encryption: emails are stored with AES-GCM like the other personal data, and looked up and
+-- kept unique by a keyed blind index of the lower-case address
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_email_key,
+    ALTER COLUMN email TYPE TEXT,
+    ADD COLUMN email_index VARCHAR(64) NULL;
+
+DROP INDEX IF EXISTS idx_users_email;
+CREATE UNIQUE INDEX idx_users_email_index ON users (email_index);
+
+UPDATE cron_jobs
+SET description = 'Re-encrypt emails, phone numbers, device IDs and device tokens under the newest encryption key'
+WHERE name = 'reencrypt_pii';
@@ -0,0 +1,13 @@
+-- Internal tokens: every token carries an ID checked on each call, an expiry and only its hash is stored
+ALTER TABLE internal_tokens
+    ADD COLUMN token_id   VARCHAR(64) NULL,
+    ADD COLUMN expires_at TIMESTAMP   NULL;
+
+CREATE UNIQUE INDEX idx_internal_tokens_token_id ON internal_tokens (token_id);
+
+-- tokens issued before carry no ID and cannot be checked, they are retired
+UPDATE internal_tokens
+SET expired    = TRUE,
+    token      = '',
+    updated_by = 'system'
+WHERE token_id IS NULL;
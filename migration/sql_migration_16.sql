@@ -0,0 +1,26 @@
+-- Service accounts: machine identities authenticating with the client_credentials grant
+CREATE TABLE service_accounts
+(
+    id                         SERIAL PRIMARY KEY,
+    client_id                  VARCHAR(255) NOT NULL UNIQUE,
+    client_secret              VARCHAR(255) NOT NULL,
+    previous_client_secret     VARCHAR(255),
+    previous_secret_expires_at TIMESTAMP NULL,
+    name                       VARCHAR(255) NOT NULL,
+    resources                  TEXT[]       NOT NULL DEFAULT '{}',
+    token_ttl                  INTEGER      NOT NULL,
+    is_active                  BOOLEAN      NOT NULL DEFAULT TRUE,
+    created_at                 TIMESTAMP             DEFAULT CURRENT_TIMESTAMP,
+    created_by                 VARCHAR(255),
+    updated_at                 TIMESTAMP             DEFAULT CURRENT_TIMESTAMP,
+    updated_by                 VARCHAR(255),
+    deleted_at                 TIMESTAMP NULL,
+    deleted_by                 VARCHAR(255)
+);
+
+CREATE INDEX idx_service_accounts_deleted_at ON service_accounts (deleted_at);
+
+CREATE TRIGGER set_updated_at_service_accounts
+    BEFORE UPDATE
+    ON service_accounts
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
@@ -0,0 +1,25 @@
+-- Personal API keys: only the key's prefix and its hash are stored, the key itself is shown once
+CREATE TABLE api_keys
+(
+    id           SERIAL PRIMARY KEY,
+    user_id      INT          NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    name         VARCHAR(255) NOT NULL,
+    prefix       VARCHAR(32)  NOT NULL UNIQUE,
+    key_hash     VARCHAR(64)  NOT NULL,
+    scopes       TEXT[]       NOT NULL DEFAULT '{}',
+    expires_at   TIMESTAMP NULL,
+    last_used_at TIMESTAMP NULL,
+    revoked_at   TIMESTAMP NULL,
+    revoked_by   VARCHAR(255),
+    created_at   TIMESTAMP             DEFAULT CURRENT_TIMESTAMP,
+    created_by   VARCHAR(255),
+    updated_at   TIMESTAMP             DEFAULT CURRENT_TIMESTAMP,
+    updated_by   VARCHAR(255)
+);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys (user_id);
+
+CREATE TRIGGER set_updated_at_api_keys
+    BEFORE UPDATE
+    ON api_keys
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
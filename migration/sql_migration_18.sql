@@ -0,0 +1,21 @@
+-- Impersonation audit trail: one row per token exchange and one per write made with the issued token
+CREATE TABLE impersonation_audits
+(
+    id              SERIAL PRIMARY KEY,
+    access_uuid     VARCHAR(64)  NOT NULL,
+    actor_user_id   INT          NOT NULL,
+    actor_client_id VARCHAR(255) NOT NULL,
+    subject_user_id INT          NOT NULL,
+    action          VARCHAR(32)  NOT NULL,
+    method          VARCHAR(16),
+    path            TEXT,
+    status          INT,
+    reason          TEXT,
+    ip_address      VARCHAR(64),
+    user_agent      TEXT,
+    created_at      TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_impersonation_audits_access_uuid ON impersonation_audits (access_uuid);
+CREATE INDEX idx_impersonation_audits_actor_user_id ON impersonation_audits (actor_user_id);
+CREATE INDEX idx_impersonation_audits_subject_user_id ON impersonation_audits (subject_user_id);
@@ -0,0 +1,18 @@
+-- TOTP two-factor authentication
+CREATE TABLE user_two_factors
+(
+    user_id        INT PRIMARY KEY REFERENCES users (user_id) ON DELETE CASCADE,
+    totp_secret    TEXT    NOT NULL,
+    is_enabled     BOOLEAN NOT NULL DEFAULT FALSE,
+    confirmed_at   TIMESTAMP NULL,
+    last_used_step BIGINT  NOT NULL DEFAULT 0,
+    created_at     TIMESTAMP        DEFAULT CURRENT_TIMESTAMP,
+    created_by     VARCHAR(255),
+    updated_at     TIMESTAMP        DEFAULT CURRENT_TIMESTAMP,
+    updated_by     VARCHAR(255)
+);
+
+CREATE TRIGGER set_updated_at_user_two_factors
+    BEFORE UPDATE
+    ON user_two_factors
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
@@ -0,0 +1,29 @@
+-- WebAuthn / passkey credentials
+CREATE TABLE webauthn_credentials
+(
+    id               SERIAL PRIMARY KEY,
+    user_id          INT          NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    credential_id    VARCHAR(1024) NOT NULL UNIQUE,
+    public_key       BYTEA        NOT NULL,
+    attestation_type VARCHAR(64),
+    transports       TEXT[],
+    aaguid           BYTEA,
+    sign_count       BIGINT       NOT NULL DEFAULT 0,
+    flags            INT          NOT NULL DEFAULT 0,
+    name             VARCHAR(255),
+    last_used_at     TIMESTAMP NULL,
+    created_at       TIMESTAMP             DEFAULT CURRENT_TIMESTAMP,
+    created_by       VARCHAR(255),
+    updated_at       TIMESTAMP             DEFAULT CURRENT_TIMESTAMP,
+    updated_by       VARCHAR(255),
+    deleted_at       TIMESTAMP NULL,
+    deleted_by       VARCHAR(255)
+);
+
+CREATE INDEX idx_webauthn_credentials_user_id ON webauthn_credentials (user_id);
+CREATE INDEX idx_webauthn_credentials_deleted_at ON webauthn_credentials (deleted_at);
+
+CREATE TRIGGER set_updated_at_webauthn_credentials
+    BEFORE UPDATE
+    ON webauthn_credentials
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
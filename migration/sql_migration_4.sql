@@ -0,0 +1,26 @@
+-- OAuth 2.0 registered clients
+CREATE TABLE oauth_clients
+(
+    id            SERIAL PRIMARY KEY,
+    client_id     VARCHAR(255) NOT NULL UNIQUE,
+    client_secret VARCHAR(255),
+    name          VARCHAR(255) NOT NULL,
+    redirect_uris TEXT[]       NOT NULL DEFAULT '{}',
+    grant_types   TEXT[]       NOT NULL DEFAULT '{}',
+    scopes        TEXT[]       NOT NULL DEFAULT '{}',
+    is_public     BOOLEAN      NOT NULL DEFAULT FALSE,
+    is_active     BOOLEAN      NOT NULL DEFAULT TRUE,
+    created_at    TIMESTAMP             DEFAULT CURRENT_TIMESTAMP,
+    created_by    VARCHAR(255),
+    updated_at    TIMESTAMP             DEFAULT CURRENT_TIMESTAMP,
+    updated_by    VARCHAR(255),
+    deleted_at    TIMESTAMP NULL,
+    deleted_by    VARCHAR(255)
+);
+
+CREATE INDEX idx_oauth_clients_deleted_at ON oauth_clients (deleted_at);
+
+CREATE TRIGGER set_updated_at_oauth_clients
+    BEFORE UPDATE
+    ON oauth_clients
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
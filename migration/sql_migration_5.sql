@@ -0,0 +1,26 @@
+-- JWT signing keyring
+CREATE TABLE signing_keys
+(
+    id           SERIAL PRIMARY KEY,
+    kid          VARCHAR(64)  NOT NULL UNIQUE,
+    algorithm    VARCHAR(16)  NOT NULL,
+    private_key  TEXT         NOT NULL,
+    status       VARCHAR(16)  NOT NULL DEFAULT 'pending',
+    activated_at TIMESTAMP NULL,
+    retired_at   TIMESTAMP NULL,
+    created_at   TIMESTAMP             DEFAULT CURRENT_TIMESTAMP,
+    created_by   VARCHAR(255),
+    updated_at   TIMESTAMP             DEFAULT CURRENT_TIMESTAMP,
+    updated_by   VARCHAR(255),
+    CONSTRAINT chk_signing_keys_status CHECK (status IN ('pending', 'active', 'retired'))
+);
+
+CREATE INDEX idx_signing_keys_status ON signing_keys (status);
+
+CREATE TRIGGER set_updated_at_signing_keys
+    BEFORE UPDATE
+    ON signing_keys
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+INSERT INTO cron_jobs (name, schedule, is_active, description, created_by)
+VALUES ('signing_key_rotation', '0 * * * *', true, 'Rotate JWT signing keys', 'system');
@@ -0,0 +1,28 @@
+-- Refresh token families with rotation and reuse detection
+CREATE TABLE refresh_tokens
+(
+    id             SERIAL PRIMARY KEY,
+    token          VARCHAR(255) NOT NULL UNIQUE,
+    family_id      VARCHAR(64)  NOT NULL,
+    parent_id      INT NULL REFERENCES refresh_tokens (id) ON DELETE SET NULL,
+    user_id        INT          NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    status         VARCHAR(16)  NOT NULL DEFAULT 'active',
+    expires_at     TIMESTAMP    NOT NULL,
+    rotated_at     TIMESTAMP NULL,
+    revoked_at     TIMESTAMP NULL,
+    revoked_reason VARCHAR(64),
+    ip_address     VARCHAR(64),
+    created_at     TIMESTAMP             DEFAULT CURRENT_TIMESTAMP,
+    created_by     VARCHAR(255),
+    updated_at     TIMESTAMP             DEFAULT CURRENT_TIMESTAMP,
+    updated_by     VARCHAR(255),
+    CONSTRAINT chk_refresh_tokens_status CHECK (status IN ('active', 'rotated', 'revoked'))
+);
+
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+
+CREATE TRIGGER set_updated_at_refresh_tokens
+    BEFORE UPDATE
+    ON refresh_tokens
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
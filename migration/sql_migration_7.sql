@@ -0,0 +1,14 @@
+-- Store session and refresh tokens as HMAC-SHA256 hashes.
+-- Existing rows keep their raw values until the hash_legacy_tokens job rewrites them with the
+-- application key; the job also runs once at startup.
+ALTER TABLE user_sessions
+    ADD COLUMN tokens_hashed BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE refresh_tokens
+    ADD COLUMN token_hashed BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX idx_user_sessions_tokens_hashed ON user_sessions (tokens_hashed) WHERE tokens_hashed = FALSE;
+CREATE INDEX idx_refresh_tokens_token_hashed ON refresh_tokens (token_hashed) WHERE token_hashed = FALSE;
+
+INSERT INTO cron_jobs (name, schedule, is_active, description, created_by)
+VALUES ('hash_legacy_tokens', '*/10 * * * *', true, 'Replace raw session and refresh tokens with their hashes', 'system');
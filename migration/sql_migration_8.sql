@@ -0,0 +1,5 @@
+-- Access token revocation: sessions remember the access_uuid of their current token
+ALTER TABLE user_sessions
+    ADD COLUMN access_uuid VARCHAR(64);
+
+CREATE INDEX idx_user_sessions_access_uuid ON user_sessions (access_uuid);
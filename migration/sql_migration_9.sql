@@ -0,0 +1,9 @@
+-- One session row per login instead of one per user
+ALTER TABLE user_sessions
+    ADD COLUMN last_seen_at TIMESTAMP NULL;
+
+UPDATE user_sessions
+SET last_seen_at = login_time
+WHERE last_seen_at IS NULL;
+
+CREATE INDEX idx_user_sessions_user_id_is_active ON user_sessions (user_id, is_active);